@@ -1,27 +1,41 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/alexs/golang_test/internal/config"
 	"github.com/alexs/golang_test/internal/handlers"
+	"github.com/alexs/golang_test/internal/logging"
+	"github.com/alexs/golang_test/internal/mail"
+	"github.com/alexs/golang_test/internal/metrics"
 	"github.com/alexs/golang_test/internal/repository"
 	"github.com/alexs/golang_test/internal/router"
 	"github.com/alexs/golang_test/internal/seed"
+	"github.com/alexs/golang_test/internal/tracing"
+	"github.com/alexs/golang_test/internal/utils"
 	"github.com/alexs/golang_test/internal/websocket"
 )
 
 func main() {
 	// Health check mode for Docker
 	if len(os.Args) > 1 && os.Args[1] == "healthcheck" {
+		cfg, err := config.Load()
+		if err != nil {
+			os.Exit(1)
+		}
 		client := http.Client{
 			Timeout: 2 * time.Second,
 		}
-		resp, err := client.Get("http://localhost:8080/health")
+		resp, err := client.Get(fmt.Sprintf("http://localhost:%d/health", cfg.Server.Port))
 		if err != nil {
 			os.Exit(1)
 		}
@@ -31,38 +45,216 @@ func main() {
 		os.Exit(0)
 	}
 
-	// 1. Load Configuration
-	config.LoadConfig()
+	// Schema migrations: api migrate up|down|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
+	// SIGTERM (docker stop) and Ctrl+C start a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// 1. Load Configuration from the optional CONFIG_FILE and the environment
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+	utils.ConfigureJWT(cfg.JWT)
+
+	// 1.0. JSON logs carrying the request and user IDs of each request
+	logging.Setup(os.Stdout, cfg.Logging.SlogLevel())
+	slog.Info("Configuration loaded", "log_level", cfg.Logging.Level)
+
+	// 1.1. OpenTelemetry traces, exported as configured by TRACING_EXPORTER
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, os.Stdout)
+	if err != nil {
+		fatal("Failed to set up tracing", err)
+	}
+
+	// 1.2. Prometheus metrics, served at /metrics on the metrics port only
+	m := metrics.New()
 
-	// 2. Connect to Database
-	repository.ConnectDB()
+	// 2. Connect to Database, timing and tracing its queries
+	repository.ConnectDB(cfg.Database)
+	if err := repository.DB.Use(m.GormPlugin()); err != nil {
+		fatal("Failed to install query metrics", err)
+	}
+	if err := repository.DB.Use(tracing.GormPlugin()); err != nil {
+		fatal("Failed to install query tracing", err)
+	}
+	if cfg.Database.MigrateOnStart {
+		if err := repository.MigrateDB(ctx); err != nil {
+			fatal("Failed to migrate database", err)
+		}
+	}
+	events := repository.NewGormEventRepository(repository.DB)
+	bookings := repository.NewGormBookingRepository(repository.DB)
+	users := repository.NewGormUserRepository(repository.DB)
+	holds := repository.NewGormHoldRepository(repository.DB)
+	waitlist := repository.NewGormWaitlistRepository(repository.DB)
+	tickets := repository.NewGormTicketRepository(repository.DB)
+	venues := repository.NewGormVenueRepository(repository.DB)
+	sales := repository.NewGormSalesRepository(repository.DB)
+	sessions := repository.NewGormSessionRepository(repository.DB)
+	accountTokens := repository.NewGormAccountTokenRepository(repository.DB)
+
+	// 2.1. Reject access tokens of revoked sessions
+	utils.SessionChecker = sessions.IsActive
+
+	// 2.2. Grant the admin role to the configured accounts
+	if promoted, err := users.PromoteAdmins(ctx, cfg.Accounts.AdminEmails); err != nil {
+		slog.Warn("Failed to promote admin accounts", "error", err)
+	} else if promoted > 0 {
+		slog.Info("Promoted accounts to admin", "accounts", promoted)
+	}
 
 	// 2.5. Seed Database (if enabled)
-	if os.Getenv("SEED_DATABASE") == "true" {
-		log.Println("Database seeding enabled, checking seed status...")
-		forceReseed := os.Getenv("FORCE_RESEED") == "true"
+	if cfg.Seed.Enabled {
+		slog.Info("Database seeding enabled, checking seed status")
 
-		if err := seed.Run(forceReseed); err != nil {
-			log.Printf("Warning: Database seeding failed: %v", err)
+		if err := seed.Run(cfg.Seed.Force); err != nil {
+			slog.Warn("Database seeding failed", "error", err)
 			// Don't fatal - allow app to start even if seeding fails
 		} else {
-			log.Println("Database seeding completed successfully")
+			slog.Info("Database seeding completed successfully")
 		}
 	}
 
 	// 3. Initialize WebSocket Hub
-	hub := websocket.NewHub()
+	// With the Postgres backplane every replica delivers every broadcast
+	var backplane websocket.Backplane
+	if cfg.WebSocket.Backplane == "postgres" {
+		pgBackplane, err := websocket.NewPostgresBackplane(context.Background(), cfg.Database.DSN(), websocket.BackplaneChannel)
+		if err != nil {
+			fatal("Failed to start hub backplane", err)
+		}
+		defer pgBackplane.Close()
+		backplane = pgBackplane
+	}
+
+	hub := websocket.NewHubWithBackplane(backplane)
+	hub.SetLimits(websocket.Limits{
+		MessageRate:           cfg.WebSocket.MessageRate,
+		MessageBurst:          cfg.WebSocket.MessageBurst,
+		MaxConnectionsPerUser: cfg.WebSocket.MaxConnectionsPerUser,
+	})
+	m.RegisterHub(hub)
 	go hub.Run()
 
-	// 4. Set WebSocket hub for handlers
-	handlers.SetWebSocketHub(hub)
+	// 4. Emails are written to a mailbox file in development. Without one they
+	// are dropped, and only their recipient and subject are logged.
+	var mailer mail.Sender = mail.NewLogSender()
+	if cfg.Mail.OutboxFile != "" {
+		mailer = mail.NewFileSender(cfg.Mail.OutboxFile)
+	} else {
+		slog.Warn("MAIL_OUTBOX_FILE is not set, so password reset and verification emails are not delivered")
+	}
+
+	// 4.1. Handlers with their repositories, WebSocket hub, mail sender, configuration and metrics
+	h := handlers.New(handlers.Dependencies{
+		Events:        events,
+		Bookings:      bookings,
+		Users:         users,
+		Holds:         holds,
+		Waitlist:      waitlist,
+		Tickets:       tickets,
+		Venues:        venues,
+		Sales:         sales,
+		Sessions:      sessions,
+		AccountTokens: accountTokens,
+		Hub:           hub,
+		Mailer:        mailer,
+		Config:        cfg,
+		Metrics:       m,
+	})
+
+	// 4.5. Start background reaper for expired ticket holds, and keep open
+	// sales dashboards' rates current between bookings
+	var workers sync.WaitGroup
+	workers.Add(2)
+	go func() {
+		defer workers.Done()
+		h.RunHoldReaper(ctx, cfg.Holds.ReaperInterval)
+	}()
+	go func() {
+		defer workers.Done()
+		h.RunSalesRefresher(ctx, cfg.WebSocket.SalesRefreshInterval)
+	}()
 
-	// 5. Setup Router with WebSocket hub
-	r := router.New(hub)
+	// 5. Setup Router with the handlers and WebSocket hub
+	r := router.New(h, hub, cfg.CORS.AllowedOrigins, m)
 
 	// 6. Start Server
-	fmt.Println("Server running on port 8080")
-	if err := http.ListenAndServe(":8080", r); err != nil {
-		fmt.Printf("Error starting server: %v\n", err)
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:           r,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+	}
+	go func() {
+		slog.Info("Server running", "port", cfg.Server.Port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("Error starting server", err)
+		}
+	}()
+
+	// 6.1. Prometheus scrapes a separate listener, so metrics aren't public
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("GET /metrics", m.Handler())
+	metricsServer := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Server.MetricsPort),
+		Handler:           metricsMux,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
 	}
+	go func() {
+		slog.Info("Metrics server running", "port", cfg.Server.MetricsPort)
+		if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("Error starting metrics server", err)
+		}
+	}()
+
+	// 7. Graceful shutdown
+	<-ctx.Done()
+	stop()
+	slog.Info("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	// Stream handlers only return once the hub closes their clients, so the hub
+	// shuts down alongside the server rather than after it
+	hubDone := make(chan error, 1)
+	go func() {
+		hubDone <- hub.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("HTTP server shutdown incomplete", "error", err)
+	}
+	if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Metrics server shutdown incomplete", "error", err)
+	}
+	if err := <-hubDone; err != nil {
+		slog.Warn("WebSocket hub shutdown incomplete", "error", err)
+	}
+
+	// The reaper finishes its current pass, which shutdown doesn't cancel,
+	// before the database goes away
+	workers.Wait()
+
+	if err := repository.CloseDB(); err != nil {
+		slog.Warn("Failed to close database", "error", err)
+	}
+
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Warn("Failed to flush traces", "error", err)
+	}
+
+	slog.Info("Server stopped")
+}
+
+// fatal logs err and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
\ No newline at end of file
@@ -1,28 +1,515 @@
 package config
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
+// Config is the application configuration. It starts from Default, is
+// overlaid by the YAML file named in CONFIG_FILE, if any, and then by
+// environment variables.
 type Config struct {
-	JWTSecret     string
-	JWTExpiration time.Duration
+	Server    ServerConfig    `yaml:"server"`
+	Database  DatabaseConfig  `yaml:"database"`
+	JWT       JWTConfig       `yaml:"jwt"`
+	Accounts  AccountsConfig  `yaml:"accounts"`
+	Mail      MailConfig      `yaml:"mail"`
+	CORS      CORSConfig      `yaml:"cors"`
+	WebSocket WebSocketConfig `yaml:"websocket"`
+	Holds     HoldsConfig     `yaml:"holds"`
+	Seed      SeedConfig      `yaml:"seed"`
+	Logging   LoggingConfig   `yaml:"logging"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+}
+
+// ServerConfig configures the HTTP server
+type ServerConfig struct {
+	Port              int           `yaml:"port"`
+	MetricsPort       int           `yaml:"metrics_port"` // serves /metrics on its own listener, kept off the public API
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"` // how long in-flight requests and connections get to finish on SIGTERM
+}
+
+// DatabaseConfig configures the Postgres connection and its pool
+type DatabaseConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+
+	MigrateOnStart     bool          `yaml:"migrate_on_start"`     // apply pending schema migrations before serving
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold"` // queries taking longer are logged as warnings
+}
+
+// JWTConfig configures access and refresh tokens. The secret also signs ticket codes.
+type JWTConfig struct {
+	Secret          string        `yaml:"secret"`
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl"`
+}
+
+// AccountsConfig configures account emails and roles
+type AccountsConfig struct {
+	AdminEmails          []string      `yaml:"admin_emails"` // existing accounts promoted to admin at startup
+	PasswordResetTTL     time.Duration `yaml:"password_reset_ttl"`
+	EmailVerificationTTL time.Duration `yaml:"email_verification_ttl"`
+}
+
+// MailConfig configures outgoing emails
+type MailConfig struct {
+	AppBaseURL string `yaml:"app_base_url"` // frontend URL used in emailed links
+	OutboxFile string `yaml:"outbox_file"`  // when set, emails are appended here; otherwise they are dropped
+}
+
+// CORSConfig configures cross-origin access
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins"` // browser origins allowed by CORS and the WebSocket handshake
+}
+
+// WebSocketConfig configures the live update hub
+type WebSocketConfig struct {
+	Backplane             string        `yaml:"backplane"`    // local (single replica) or postgres
+	MessageRate           float64       `yaml:"message_rate"` // inbound messages per second allowed on each WebSocket
+	MessageBurst          int           `yaml:"message_burst"`
+	MaxConnectionsPerUser int           `yaml:"max_connections_per_user"`
+	CoalesceWindow        time.Duration `yaml:"coalesce_window"`        // availability updates per event are merged within this window
+	SalesRefreshInterval  time.Duration `yaml:"sales_refresh_interval"` // open sales dashboards get fresh figures this often
+}
+
+// HoldsConfig configures ticket holds
+type HoldsConfig struct {
+	TTL            time.Duration `yaml:"ttl"`
+	ReaperInterval time.Duration `yaml:"reaper_interval"`
+}
+
+// SeedConfig configures demo data seeding at startup
+type SeedConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Force   bool `yaml:"force"` // wipe and reseed even if seed data exists
+}
+
+// LoggingConfig configures the JSON logs
+type LoggingConfig struct {
+	Level string `yaml:"level"` // debug, info, warn or error; debug also logs every SQL query
+}
+
+// TracingConfig configures OpenTelemetry tracing. The OTLP exporter reads its
+// endpoint, headers and protocol from the standard OTEL_EXPORTER_OTLP_*
+// environment variables.
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"` // none, stdout or otlp
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"` // share of new traces recorded; traces sampled upstream are always recorded
+}
+
+// SlogLevel returns the configured level. It is info if the level is invalid,
+// which Validate reports.
+func (c LoggingConfig) SlogLevel() slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// Default returns the configuration used for anything not set in the file or
+// environment. It has no JWT secret, which must always be provided.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:              8080,
+			MetricsPort:       9090,
+			ReadHeaderTimeout: 10 * time.Second,
+			ShutdownTimeout:   10 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:               "localhost",
+			Port:               5432,
+			SSLMode:            "disable",
+			MaxOpenConns:       25,
+			MaxIdleConns:       10,
+			ConnMaxLifetime:    30 * time.Minute,
+			ConnMaxIdleTime:    5 * time.Minute,
+			MigrateOnStart:     true,
+			SlowQueryThreshold: 200 * time.Millisecond,
+		},
+		JWT: JWTConfig{
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 30 * 24 * time.Hour,
+		},
+		Accounts: AccountsConfig{
+			PasswordResetTTL:     time.Hour,
+			EmailVerificationTTL: 48 * time.Hour,
+		},
+		Mail: MailConfig{
+			AppBaseURL: "http://localhost:3000",
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"http://localhost:3000"},
+		},
+		WebSocket: WebSocketConfig{
+			Backplane:             "local",
+			MessageRate:           10,
+			MessageBurst:          20,
+			MaxConnectionsPerUser: 5,
+			CoalesceWindow:        250 * time.Millisecond,
+			SalesRefreshInterval:  15 * time.Second,
+		},
+		Holds: HoldsConfig{
+			TTL:            10 * time.Minute,
+			ReaperInterval: 30 * time.Second,
+		},
+		Logging: LoggingConfig{
+			Level: "info",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "ticket-api",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// Load reads the configuration and validates it, reporting every problem at once
+func Load() (*Config, error) {
+	cfg := Default()
+	if err := readFile(cfg); err != nil {
+		return nil, err
+	}
+
+	env := &envReader{}
+	cfg.Server.fromEnv(env)
+	cfg.Database.fromEnv(env)
+	cfg.JWT.fromEnv(env)
+	cfg.Accounts.fromEnv(env)
+	cfg.Mail.fromEnv(env)
+	cfg.CORS.fromEnv(env)
+	cfg.WebSocket.fromEnv(env)
+	cfg.Holds.fromEnv(env)
+	cfg.Seed.fromEnv(env)
+	cfg.Logging.fromEnv(env)
+	cfg.Tracing.fromEnv(env)
+
+	if err := errors.Join(append(env.errs, cfg.Validate()...)...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadDatabase reads and validates only the database settings, for commands
+// such as migrations that need nothing else
+func LoadDatabase() (DatabaseConfig, error) {
+	cfg := Default()
+	if err := readFile(cfg); err != nil {
+		return DatabaseConfig{}, err
+	}
+
+	env := &envReader{}
+	cfg.Database.fromEnv(env)
+	if err := errors.Join(append(env.errs, cfg.Database.validate()...)...); err != nil {
+		return DatabaseConfig{}, err
+	}
+	return cfg.Database, nil
+}
+
+// readFile overlays cfg with the YAML file named in CONFIG_FILE. Unknown keys
+// are errors, so a misspelt setting doesn't go unnoticed.
+func readFile(cfg *Config) error {
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// DSN returns the Postgres connection string
+func (c DatabaseConfig) DSN() string {
+	return fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=UTC",
+		dsnValue(c.Host), dsnValue(c.User), dsnValue(c.Password), dsnValue(c.Name), c.Port, dsnValue(c.SSLMode),
+	)
+}
+
+// dsnValue quotes a connection string value, so empty values and values with
+// spaces or quotes survive parsing
+func dsnValue(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+func (c *ServerConfig) fromEnv(env *envReader) {
+	env.int("PORT", &c.Port)
+	env.int("METRICS_PORT", &c.MetricsPort)
+	env.duration("READ_HEADER_TIMEOUT", &c.ReadHeaderTimeout)
+	env.duration("SHUTDOWN_TIMEOUT", &c.ShutdownTimeout)
+}
+
+func (c *DatabaseConfig) fromEnv(env *envReader) {
+	env.string("DB_HOST", &c.Host)
+	env.int("DB_PORT", &c.Port)
+	env.string("DB_USER", &c.User)
+	env.string("DB_PASSWORD", &c.Password)
+	env.string("DB_NAME", &c.Name)
+	env.string("DB_SSLMODE", &c.SSLMode)
+	env.int("DB_MAX_OPEN_CONNS", &c.MaxOpenConns)
+	env.int("DB_MAX_IDLE_CONNS", &c.MaxIdleConns)
+	env.duration("DB_CONN_MAX_LIFETIME", &c.ConnMaxLifetime)
+	env.duration("DB_CONN_MAX_IDLE_TIME", &c.ConnMaxIdleTime)
+	env.bool("MIGRATE_ON_START", &c.MigrateOnStart)
+	env.duration("DB_SLOW_QUERY_THRESHOLD", &c.SlowQueryThreshold)
+}
+
+func (c *JWTConfig) fromEnv(env *envReader) {
+	env.string("JWT_SECRET", &c.Secret)
+	env.duration("ACCESS_TOKEN_TTL", &c.AccessTokenTTL)
+	env.duration("REFRESH_TOKEN_TTL", &c.RefreshTokenTTL)
+}
+
+func (c *AccountsConfig) fromEnv(env *envReader) {
+	env.list("ADMIN_EMAILS", &c.AdminEmails)
+	env.duration("PASSWORD_RESET_TTL", &c.PasswordResetTTL)
+	env.duration("EMAIL_VERIFICATION_TTL", &c.EmailVerificationTTL)
 }
 
-var AppConfig *Config
+func (c *MailConfig) fromEnv(env *envReader) {
+	env.string("APP_BASE_URL", &c.AppBaseURL)
+	env.string("MAIL_OUTBOX_FILE", &c.OutboxFile)
+}
+
+func (c *CORSConfig) fromEnv(env *envReader) {
+	env.list("ALLOWED_ORIGINS", &c.AllowedOrigins)
+}
+
+func (c *WebSocketConfig) fromEnv(env *envReader) {
+	env.string("HUB_BACKPLANE", &c.Backplane)
+	env.float("WS_MESSAGE_RATE", &c.MessageRate)
+	env.int("WS_MESSAGE_BURST", &c.MessageBurst)
+	env.int("WS_MAX_CONNECTIONS_PER_USER", &c.MaxConnectionsPerUser)
+	env.duration("WS_COALESCE_WINDOW", &c.CoalesceWindow)
+	env.duration("WS_SALES_REFRESH_INTERVAL", &c.SalesRefreshInterval)
+}
+
+func (c *HoldsConfig) fromEnv(env *envReader) {
+	env.duration("HOLD_TTL", &c.TTL)
+	env.duration("HOLD_REAPER_INTERVAL", &c.ReaperInterval)
+}
+
+func (c *SeedConfig) fromEnv(env *envReader) {
+	env.bool("SEED_DATABASE", &c.Enabled)
+	env.bool("FORCE_RESEED", &c.Force)
+}
+
+func (c *LoggingConfig) fromEnv(env *envReader) {
+	env.string("LOG_LEVEL", &c.Level)
+}
+
+func (c *TracingConfig) fromEnv(env *envReader) {
+	env.string("TRACING_EXPORTER", &c.Exporter)
+	env.string("OTEL_SERVICE_NAME", &c.ServiceName)
+	env.float("TRACING_SAMPLE_RATIO", &c.SampleRatio)
+}
 
-func LoadConfig() {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		log.Fatal("JWT_SECRET environment variable is required")
+// Validate checks the whole configuration and returns every problem found
+func (c *Config) Validate() []error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
 	}
 
-	AppConfig = &Config{
-		JWTSecret:     jwtSecret,
-		JWTExpiration: 24 * time.Hour, // Token expires in 24 hours
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "server port must be between 1 and 65535, got %d", c.Server.Port)
+	check(c.Server.MetricsPort > 0 && c.Server.MetricsPort <= 65535, "metrics port must be between 1 and 65535, got %d", c.Server.MetricsPort)
+	check(c.Server.MetricsPort != c.Server.Port, "metrics port must differ from the server port")
+	check(c.Server.ReadHeaderTimeout > 0, "server read header timeout must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server shutdown timeout must be positive")
+
+	errs = append(errs, c.Database.validate()...)
+
+	check(c.JWT.Secret != "", "JWT secret is required (JWT_SECRET)")
+	check(c.JWT.AccessTokenTTL > 0, "access token TTL must be positive")
+	check(c.JWT.RefreshTokenTTL > c.JWT.AccessTokenTTL, "refresh token TTL must be longer than the access token TTL")
+
+	check(c.Accounts.PasswordResetTTL > 0, "password reset TTL must be positive")
+	check(c.Accounts.EmailVerificationTTL > 0, "email verification TTL must be positive")
+
+	baseURL, err := url.Parse(c.Mail.AppBaseURL)
+	check(err == nil && baseURL.Scheme != "" && baseURL.Host != "", "app base URL must be an absolute URL, got %q", c.Mail.AppBaseURL)
+
+	check(len(c.CORS.AllowedOrigins) > 0, "at least one allowed origin is required")
+	for _, origin := range c.CORS.AllowedOrigins {
+		parsed, err := url.Parse(origin)
+		check(origin == "*" || (err == nil && parsed.Scheme != "" && parsed.Host != "" && parsed.Path == ""),
+			"allowed origin must be * or a scheme and host such as https://example.com, got %q", origin)
 	}
 
-	log.Println("Configuration loaded successfully")
+	check(c.WebSocket.Backplane == "local" || c.WebSocket.Backplane == "postgres",
+		"WebSocket backplane must be local or postgres, got %q", c.WebSocket.Backplane)
+	check(c.WebSocket.MessageRate > 0, "WebSocket message rate must be positive")
+	check(c.WebSocket.MessageBurst > 0, "WebSocket message burst must be positive")
+	check(c.WebSocket.MaxConnectionsPerUser > 0, "WebSocket connections per user must be positive")
+	check(c.WebSocket.CoalesceWindow > 0, "WebSocket coalesce window must be positive")
+	check(c.WebSocket.SalesRefreshInterval > 0, "WebSocket sales refresh interval must be positive")
+
+	check(c.Holds.TTL > 0, "hold TTL must be positive")
+	check(c.Holds.ReaperInterval > 0, "hold reaper interval must be positive")
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Logging.Level)) == nil, "log level must be debug, info, warn or error, got %q", c.Logging.Level)
+
+	check(c.Tracing.Exporter == "none" || c.Tracing.Exporter == "stdout" || c.Tracing.Exporter == "otlp",
+		"tracing exporter must be none, stdout or otlp, got %q", c.Tracing.Exporter)
+	check(c.Tracing.ServiceName != "", "tracing service name is required")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing sample ratio must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+
+	return errs
+}
+
+// validate checks the database settings
+func (c DatabaseConfig) validate() []error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Host != "", "database host is required (DB_HOST)")
+	check(c.Port > 0 && c.Port <= 65535, "database port must be between 1 and 65535, got %d", c.Port)
+	check(c.User != "", "database user is required (DB_USER)")
+	check(c.Name != "", "database name is required (DB_NAME)")
+	check(c.MaxOpenConns > 0, "database max open connections must be positive")
+	check(c.MaxIdleConns > 0 && c.MaxIdleConns <= c.MaxOpenConns,
+		"database max idle connections must be between 1 and max open connections (%d), got %d", c.MaxOpenConns, c.MaxIdleConns)
+	check(c.ConnMaxLifetime > 0, "database connection max lifetime must be positive")
+	check(c.ConnMaxIdleTime > 0, "database connection max idle time must be positive")
+	check(c.SlowQueryThreshold > 0, "database slow query threshold must be positive")
+
+	return errs
+}
+
+// envReader overlays settings with environment variables, collecting the
+// values that don't parse instead of stopping at the first
+type envReader struct {
+	errs []error
+}
+
+// lookup returns the variable's value, if it is set and not empty
+func (r *envReader) lookup(key string) (string, bool) {
+	value := strings.TrimSpace(os.Getenv(key))
+	return value, value != ""
+}
+
+// invalid records a value that doesn't parse
+func (r *envReader) invalid(key, value, expected string) {
+	r.errs = append(r.errs, fmt.Errorf("invalid %s value %q, expected %s", key, value, expected))
+}
+
+// string reads a string
+func (r *envReader) string(key string, dst *string) {
+	if value, ok := r.lookup(key); ok {
+		*dst = value
+	}
+}
+
+// duration reads a duration such as "10m"
+func (r *envReader) duration(key string, dst *time.Duration) {
+	value, ok := r.lookup(key)
+	if !ok {
+		return
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		r.invalid(key, value, "a duration such as 10m")
+		return
+	}
+	*dst = d
+}
+
+// int reads an integer
+func (r *envReader) int(key string, dst *int) {
+	value, ok := r.lookup(key)
+	if !ok {
+		return
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		r.invalid(key, value, "an integer")
+		return
+	}
+	*dst = n
+}
+
+// float reads a number
+func (r *envReader) float(key string, dst *float64) {
+	value, ok := r.lookup(key)
+	if !ok {
+		return
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		r.invalid(key, value, "a number")
+		return
+	}
+	*dst = f
+}
+
+// bool reads true or false
+func (r *envReader) bool(key string, dst *bool) {
+	value, ok := r.lookup(key)
+	if !ok {
+		return
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		r.invalid(key, value, "true or false")
+		return
+	}
+	*dst = b
+}
+
+// list reads a comma-separated list, skipping empty entries
+func (r *envReader) list(key string, dst *[]string) {
+	value, ok := r.lookup(key)
+	if !ok {
+		return
+	}
+
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	*dst = values
 }
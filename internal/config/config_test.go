@@ -0,0 +1,169 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// requiredEnv sets the settings that have no default
+func requiredEnv(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("JWT_SECRET", "test-secret-key-for-testing")
+	t.Setenv("DB_USER", "user")
+	t.Setenv("DB_NAME", "ticket_db")
+}
+
+// writeConfigFile writes a YAML config file and points CONFIG_FILE at it
+func writeConfigFile(t *testing.T, contents string) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+	t.Setenv("CONFIG_FILE", path)
+}
+
+// TestLoad_Defaults tests that only the required settings are needed
+func TestLoad_Defaults(t *testing.T) {
+	requiredEnv(t)
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, 8080, cfg.Server.Port)
+	assert.Equal(t, 9090, cfg.Server.MetricsPort)
+	assert.Equal(t, "test-secret-key-for-testing", cfg.JWT.Secret)
+	assert.Equal(t, 15*time.Minute, cfg.JWT.AccessTokenTTL)
+	assert.Equal(t, 25, cfg.Database.MaxOpenConns)
+	assert.True(t, cfg.Database.MigrateOnStart)
+	assert.Equal(t, []string{"http://localhost:3000"}, cfg.CORS.AllowedOrigins)
+	assert.Equal(t, "local", cfg.WebSocket.Backplane)
+	assert.False(t, cfg.Seed.Enabled)
+	assert.Equal(t, slog.LevelInfo, cfg.Logging.SlogLevel())
+	assert.Equal(t, "none", cfg.Tracing.Exporter)
+}
+
+// TestLoad_FileAndEnv tests that the file overrides defaults and the environment overrides the file
+func TestLoad_FileAndEnv(t *testing.T) {
+	requiredEnv(t)
+	writeConfigFile(t, `
+server:
+  port: 9090
+database:
+  max_open_conns: 50
+  max_idle_conns: 20
+cors:
+  allowed_origins: [https://tickets.example.com]
+websocket:
+  backplane: postgres
+  coalesce_window: 1s
+seed:
+  enabled: true
+`)
+	t.Setenv("PORT", "9191")
+	t.Setenv("ALLOWED_ORIGINS", "https://a.example.com, https://b.example.com")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, 9191, cfg.Server.Port, "Environment wins over the file")
+	assert.Equal(t, []string{"https://a.example.com", "https://b.example.com"}, cfg.CORS.AllowedOrigins)
+	assert.Equal(t, 50, cfg.Database.MaxOpenConns)
+	assert.Equal(t, 20, cfg.Database.MaxIdleConns)
+	assert.Equal(t, "postgres", cfg.WebSocket.Backplane)
+	assert.Equal(t, time.Second, cfg.WebSocket.CoalesceWindow)
+	assert.True(t, cfg.Seed.Enabled)
+	assert.Equal(t, 20, cfg.WebSocket.MessageBurst, "Settings missing from the file keep their default")
+}
+
+// TestLoad_Invalid tests that every problem is reported together
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name           string
+		env            map[string]string
+		file           string
+		expectedErrors []string
+	}{
+		{
+			name: "Invalid and missing values",
+			env: map[string]string{
+				"JWT_SECRET":        "",
+				"PORT":              "eighty",
+				"HOLD_TTL":          "ten minutes",
+				"SEED_DATABASE":     "yes please",
+				"HUB_BACKPLANE":     "redis",
+				"DB_MAX_IDLE_CONNS": "100",
+				"ALLOWED_ORIGINS":   "localhost:3000",
+				"LOG_LEVEL":         "verbose",
+				"TRACING_EXPORTER":  "jaeger",
+			},
+			expectedErrors: []string{
+				`invalid PORT value "eighty"`,
+				`invalid HOLD_TTL value "ten minutes"`,
+				`invalid SEED_DATABASE value "yes please"`,
+				"JWT secret is required",
+				`WebSocket backplane must be local or postgres, got "redis"`,
+				"database max idle connections must be between 1 and max open connections (25), got 100",
+				`allowed origin must be * or a scheme and host such as https://example.com, got "localhost:3000"`,
+				`log level must be debug, info, warn or error, got "verbose"`,
+				`tracing exporter must be none, stdout or otlp, got "jaeger"`,
+			},
+		},
+		{
+			name:           "Metrics on the server port",
+			env:            map[string]string{"METRICS_PORT": "8080"},
+			expectedErrors: []string{"metrics port must differ from the server port"},
+		},
+		{
+			name:           "Unknown file key",
+			file:           "websockets:\n  backplane: postgres\n",
+			expectedErrors: []string{"field websockets not found"},
+		},
+		{
+			name:           "Zero durations in file",
+			file:           "holds:\n  ttl: 0s\njwt:\n  access_token_ttl: 0s\n",
+			expectedErrors: []string{"hold TTL must be positive", "access token TTL must be positive"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requiredEnv(t)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			if tt.file != "" {
+				writeConfigFile(t, tt.file)
+			}
+
+			cfg, err := Load()
+
+			require.Error(t, err)
+			assert.Nil(t, cfg)
+			for _, expected := range tt.expectedErrors {
+				assert.Contains(t, err.Error(), expected)
+			}
+		})
+	}
+}
+
+// TestLoadDatabase tests that migrations can load the database settings without the rest
+func TestLoadDatabase(t *testing.T) {
+	requiredEnv(t)
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("DB_HOST", "db")
+	t.Setenv("DB_PORT", "6543")
+	t.Setenv("DB_PASSWORD", `it's secret`)
+
+	cfg, err := LoadDatabase()
+	require.NoError(t, err)
+	assert.Equal(t, `host='db' user='user' password='it\'s secret' dbname='ticket_db' port=6543 sslmode='disable' TimeZone=UTC`, cfg.DSN())
+
+	t.Setenv("DB_USER", "")
+	_, err = LoadDatabase()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "database user is required")
+}
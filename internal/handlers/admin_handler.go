@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/alexs/golang_test/internal/middleware"
+	"github.com/alexs/golang_test/internal/models"
+	"github.com/alexs/golang_test/internal/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+type UpdateUserRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// UpdateUserRole assigns a role to a user (admin only)
+func (h *Handler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	admin := middleware.GetUserFromContext(r)
+	if admin == nil {
+		utils.ErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req UpdateUserRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if !models.IsValidRole(req.Role) {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Role must be one of: user, organizer, admin")
+		return
+	}
+
+	// Keep at least the acting admin around so the system can't lock itself out
+	if uint(id) == admin.UserID && req.Role != models.RoleAdmin {
+		utils.ErrorResponse(w, http.StatusBadRequest, "You cannot remove your own admin role")
+		return
+	}
+
+	user, err := h.users.UpdateRole(r.Context(), uint(id), req.Role)
+	if err != nil {
+		if strings.Contains(err.Error(), "user not found") {
+			utils.ErrorResponse(w, http.StatusNotFound, "User not found")
+			return
+		}
+		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to update role")
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, user)
+}
+
+// GetWebSocketStats reports live connection counts and delivery counters (admin only)
+func (h *Handler) GetWebSocketStats(w http.ResponseWriter, r *http.Request) {
+	if h.hub == nil {
+		utils.ErrorResponse(w, http.StatusServiceUnavailable, "Live updates are unavailable")
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, h.hub.Stats())
+}
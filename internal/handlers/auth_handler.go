@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
 
+	"github.com/alexs/golang_test/internal/mail"
 	"github.com/alexs/golang_test/internal/middleware"
-	"github.com/alexs/golang_test/internal/repository"
+	"github.com/alexs/golang_test/internal/models"
 	"github.com/alexs/golang_test/internal/utils"
 )
 
@@ -21,8 +26,85 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// sendVerificationEmail mails a link that verifies the user's email address
+func (h *Handler) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	token, err := h.accountTokens.Create(ctx, user.ID, models.TokenPurposeEmailVerification, h.config.Accounts.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return h.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm your email address by opening this link:\n%s/verify-email?token=%s\n\nThe link expires in %s.",
+			user.Username, h.config.Mail.AppBaseURL, url.QueryEscape(token), h.config.Accounts.EmailVerificationTTL),
+	})
+}
+
+// sendPasswordResetEmail mails a link that lets the user choose a new password
+func (h *Handler) sendPasswordResetEmail(ctx context.Context, user *models.User) error {
+	token, err := h.accountTokens.Create(ctx, user.ID, models.TokenPurposePasswordReset, h.config.Accounts.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	return h.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nChoose a new password by opening this link:\n%s/reset-password?token=%s\n\nThe link expires in %s. If you didn't ask to reset your password, you can ignore this email.",
+			user.Username, h.config.Mail.AppBaseURL, url.QueryEscape(token), h.config.Accounts.PasswordResetTTL),
+	})
+}
+
+// newAuthResponse starts a session for the user and returns its access and refresh tokens
+func (h *Handler) newAuthResponse(ctx context.Context, user *models.User) (map[string]interface{}, error) {
+	session, refreshToken, err := h.sessions.Create(ctx, user.ID, h.config.JWT.RefreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.authResponse(user, session.ID, refreshToken)
+}
+
+// authResponse builds the token payload returned by signup, login and refresh
+func (h *Handler) authResponse(user *models.User, sessionID uint, refreshToken string) (map[string]interface{}, error) {
+	token, err := utils.GenerateJWT(user.ID, user.Username, user.Email, user.Role, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(h.config.JWT.AccessTokenTTL.Seconds()),
+		"user": map[string]interface{}{
+			"id":       user.ID,
+			"username": user.Username,
+			"email":    user.Email,
+			"role":     user.Role,
+		},
+	}, nil
+}
+
 // Signup handles user registration
-func Signup(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) Signup(w http.ResponseWriter, r *http.Request) {
 	var req SignupRequest
 
 	// Parse request body
@@ -50,7 +132,7 @@ func Signup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create user in database
-	user, err := repository.CreateUser(req.Username, req.Email, req.Password)
+	user, err := h.users.Create(r.Context(), req.Username, req.Email, req.Password)
 	if err != nil {
 		// Check for duplicate username/email
 		if strings.Contains(err.Error(), "duplicate key") || strings.Contains(err.Error(), "UNIQUE constraint") {
@@ -61,26 +143,24 @@ func Signup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateJWT(user.ID, user.Username, user.Email)
+	// A failed verification email shouldn't block signup
+	if err := h.sendVerificationEmail(r.Context(), user); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to send verification email", "user_id", user.ID, "error", err)
+	}
+
+	// Start a session and generate its tokens
+	response, err := h.newAuthResponse(r.Context(), user)
 	if err != nil {
 		utils.Error(w, http.StatusInternalServerError, "Failed to generate token")
 		return
 	}
 
-	// Return success response with token
-	utils.Success(w, "User created successfully", map[string]interface{}{
-		"token": token,
-		"user": map[string]interface{}{
-			"id":       user.ID,
-			"username": user.Username,
-			"email":    user.Email,
-		},
-	})
+	// Return success response with tokens
+	utils.Success(w, "User created successfully", response)
 }
 
 // Login handles user authentication
-func Login(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
 
 	// Parse request body
@@ -96,7 +176,7 @@ func Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Find user by email
-	user, err := repository.FindUserByEmail(req.Email)
+	user, err := h.users.FindByEmail(r.Context(), req.Email)
 	if err != nil {
 		utils.Error(w, http.StatusUnauthorized, "Invalid credentials")
 		return
@@ -108,26 +188,170 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateJWT(user.ID, user.Username, user.Email)
+	// Start a session and generate its tokens
+	response, err := h.newAuthResponse(r.Context(), user)
 	if err != nil {
 		utils.Error(w, http.StatusInternalServerError, "Failed to generate token")
 		return
 	}
 
-	// Return success response with token
-	utils.Success(w, "Login successful", map[string]interface{}{
-		"token": token,
-		"user": map[string]interface{}{
-			"id":       user.ID,
-			"username": user.Username,
-			"email":    user.Email,
-		},
+	// Return success response with tokens
+	utils.Success(w, "Login successful", response)
+}
+
+// RefreshToken rotates a refresh token and issues a new access token for the same session
+func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.RefreshToken == "" {
+		utils.Error(w, http.StatusBadRequest, "Refresh token is required")
+		return
+	}
+
+	session, refreshToken, err := h.sessions.Rotate(r.Context(), req.RefreshToken, h.config.JWT.RefreshTokenTTL)
+	if err != nil {
+		if strings.Contains(err.Error(), "reuse detected") {
+			utils.Error(w, http.StatusUnauthorized, "Refresh token has already been used. Session revoked, please log in again")
+			return
+		}
+		if strings.Contains(err.Error(), "invalid refresh token") ||
+			strings.Contains(err.Error(), "refresh token expired") ||
+			strings.Contains(err.Error(), "session revoked") {
+			utils.Error(w, http.StatusUnauthorized, "Invalid or expired refresh token")
+			return
+		}
+		utils.Error(w, http.StatusInternalServerError, "Failed to refresh token")
+		return
+	}
+
+	// Reload the user so role changes apply from the next refresh
+	user, err := h.users.FindByID(r.Context(), session.UserID)
+	if err != nil {
+		utils.Error(w, http.StatusUnauthorized, "Invalid or expired refresh token")
+		return
+	}
+
+	response, err := h.authResponse(user, session.ID, refreshToken)
+	if err != nil {
+		utils.Error(w, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+
+	utils.Success(w, "Token refreshed", response)
+}
+
+// Logout revokes the session of a refresh token, invalidating its access and refresh tokens
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.RefreshToken == "" {
+		utils.Error(w, http.StatusBadRequest, "Refresh token is required")
+		return
+	}
+
+	if err := h.sessions.RevokeByRefreshToken(r.Context(), req.RefreshToken); err != nil {
+		utils.Error(w, http.StatusInternalServerError, "Failed to log out")
+		return
+	}
+
+	utils.Success(w, "Logged out", nil)
+}
+
+// ForgotPassword emails a password reset link. It responds the same way whether or
+// not the account exists so it can't be used to discover registered emails.
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Email == "" {
+		utils.Error(w, http.StatusBadRequest, "Email is required")
+		return
+	}
+
+	user, err := h.users.FindByEmail(r.Context(), req.Email)
+	if err == nil {
+		if err := h.sendPasswordResetEmail(r.Context(), user); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to send password reset email", "user_id", user.ID, "error", err)
+		}
+	}
+
+	utils.Success(w, "If an account exists for that email, a password reset link has been sent", nil)
+}
+
+// ResetPassword sets a new password using a reset token and revokes all of the user's sessions
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Token == "" || req.Password == "" {
+		utils.Error(w, http.StatusBadRequest, "Token and password are required")
+		return
+	}
+
+	// Validate password length
+	if len(req.Password) < 6 {
+		utils.Error(w, http.StatusBadRequest, "Password must be at least 6 characters")
+		return
+	}
+
+	if err := h.accountTokens.ResetPassword(r.Context(), req.Token, req.Password); err != nil {
+		if strings.Contains(err.Error(), "invalid or expired token") {
+			utils.Error(w, http.StatusBadRequest, "Invalid or expired reset token")
+			return
+		}
+		utils.Error(w, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+
+	utils.Success(w, "Password has been reset. Please log in again", nil)
+}
+
+// VerifyEmail marks the user's email address verified using a verification token
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Token == "" {
+		utils.Error(w, http.StatusBadRequest, "Token is required")
+		return
+	}
+
+	user, err := h.accountTokens.VerifyEmail(r.Context(), req.Token)
+	if err != nil {
+		if strings.Contains(err.Error(), "invalid or expired token") {
+			utils.Error(w, http.StatusBadRequest, "Invalid or expired verification token")
+			return
+		}
+		utils.Error(w, http.StatusInternalServerError, "Failed to verify email")
+		return
+	}
+
+	utils.Success(w, "Email verified", map[string]interface{}{
+		"id":                user.ID,
+		"email":             user.Email,
+		"email_verified_at": user.EmailVerifiedAt,
 	})
 }
 
 // GetProfile returns the authenticated user's profile
-func GetProfile(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	// Get user from context (populated by middleware)
 	claims := middleware.GetUserFromContext(r)
 	if claims == nil {
@@ -136,7 +360,7 @@ func GetProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Optionally fetch full user from database
-	user, err := repository.FindUserByID(claims.UserID)
+	user, err := h.users.FindByID(r.Context(), claims.UserID)
 	if err != nil {
 		utils.Error(w, http.StatusNotFound, "User not found")
 		return
@@ -144,9 +368,11 @@ func GetProfile(w http.ResponseWriter, r *http.Request) {
 
 	// Return user profile
 	utils.Success(w, "Profile retrieved", map[string]interface{}{
-		"id":         user.ID,
-		"username":   user.Username,
-		"email":      user.Email,
-		"created_at": user.CreatedAt,
+		"id":             user.ID,
+		"username":       user.Username,
+		"email":          user.Email,
+		"email_verified": user.EmailVerifiedAt != nil,
+		"role":           user.Role,
+		"created_at":     user.CreatedAt,
 	})
 }
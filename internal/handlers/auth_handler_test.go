@@ -2,16 +2,23 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/alexs/golang_test/internal/config"
+	"github.com/alexs/golang_test/internal/middleware"
+	"github.com/alexs/golang_test/internal/utils"
 	"github.com/stretchr/testify/assert"
 )
 
 // TestSignup_Validation tests the signup endpoint validation logic without database
 func TestSignup_Validation(t *testing.T) {
+	h, _ := newTestHandler()
+
 	tests := []struct {
 		name           string
 		body           map[string]string
@@ -85,7 +92,7 @@ func TestSignup_Validation(t *testing.T) {
 			req.Header.Set("Content-Type", "application/json")
 
 			rr := httptest.NewRecorder()
-			handler := http.HandlerFunc(Signup)
+			handler := http.HandlerFunc(h.Signup)
 			handler.ServeHTTP(rr, req)
 
 			assert.Equal(t, tc.expectedStatus, rr.Code, "Status code mismatch")
@@ -96,12 +103,14 @@ func TestSignup_Validation(t *testing.T) {
 
 // TestSignup_InvalidJSON tests signup with malformed JSON
 func TestSignup_InvalidJSON(t *testing.T) {
+	h, _ := newTestHandler()
+
 	req, err := http.NewRequest("POST", "/signup", bytes.NewBufferString("{invalid json"))
 	assert.NoError(t, err)
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(Signup)
+	handler := http.HandlerFunc(h.Signup)
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusBadRequest, rr.Code)
@@ -110,6 +119,8 @@ func TestSignup_InvalidJSON(t *testing.T) {
 
 // TestLogin_Validation tests the login endpoint validation logic
 func TestLogin_Validation(t *testing.T) {
+	h, _ := newTestHandler()
+
 	tests := []struct {
 		name           string
 		body           map[string]string
@@ -160,7 +171,7 @@ func TestLogin_Validation(t *testing.T) {
 			req.Header.Set("Content-Type", "application/json")
 
 			rr := httptest.NewRecorder()
-			handler := http.HandlerFunc(Login)
+			handler := http.HandlerFunc(h.Login)
 			handler.ServeHTTP(rr, req)
 
 			assert.Equal(t, tc.expectedStatus, rr.Code, "Status code mismatch")
@@ -171,14 +182,199 @@ func TestLogin_Validation(t *testing.T) {
 
 // TestLogin_InvalidJSON tests login with malformed JSON
 func TestLogin_InvalidJSON(t *testing.T) {
+	h, _ := newTestHandler()
+
 	req, err := http.NewRequest("POST", "/login", bytes.NewBufferString("not json at all"))
 	assert.NoError(t, err)
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(Login)
+	handler := http.HandlerFunc(h.Login)
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusBadRequest, rr.Code)
 	assert.Contains(t, rr.Body.String(), "Invalid request body")
 }
+
+// TestRefreshAndLogout_Validation tests refresh token request validation without database
+func TestRefreshAndLogout_Validation(t *testing.T) {
+	h, _ := newTestHandler()
+
+	handlers := map[string]http.HandlerFunc{
+		"Refresh": h.RefreshToken,
+		"Logout":  h.Logout,
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Invalid JSON",
+			body:           `{not valid json`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid request body",
+		},
+		{
+			name:           "Missing Refresh Token",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Refresh token is required",
+		},
+	}
+
+	for handlerName, handler := range handlers {
+		for _, tc := range tests {
+			t.Run(handlerName+"/"+tc.name, func(t *testing.T) {
+				req, err := http.NewRequest("POST", "/auth/refresh", bytes.NewBufferString(tc.body))
+				assert.NoError(t, err)
+				req.Header.Set("Content-Type", "application/json")
+
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+
+				assert.Equal(t, tc.expectedStatus, rr.Code, "Status code mismatch")
+				assert.Contains(t, rr.Body.String(), tc.expectedBody, "Response body mismatch")
+			})
+		}
+	}
+}
+
+// TestRequireAuth_RevokedSession tests that access tokens stop working once their session is revoked
+func TestRequireAuth_RevokedSession(t *testing.T) {
+	// Configure token signing for tests
+	utils.ConfigureJWT(config.JWTConfig{Secret: "test-secret-key-for-testing", AccessTokenTTL: 24 * time.Hour})
+
+	// Session 1 is active, session 2 has been logged out
+	originalChecker := utils.SessionChecker
+	defer func() { utils.SessionChecker = originalChecker }()
+	utils.SessionChecker = func(ctx context.Context, sessionID uint) (bool, error) { return sessionID == 1, nil }
+
+	tests := []struct {
+		name           string
+		sessionID      uint
+		expectedStatus int
+	}{
+		{
+			name:           "Active Session",
+			sessionID:      1,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Revoked Session",
+			sessionID:      2,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Token Without Session",
+			sessionID:      0,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	protected := middleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := utils.GenerateJWT(1, "testuser", "test@example.com", "user", tc.sessionID)
+			assert.NoError(t, err)
+
+			req, err := http.NewRequest("GET", "/profile", nil)
+			assert.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			rr := httptest.NewRecorder()
+			protected.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Status code mismatch")
+		})
+	}
+}
+
+// TestAccountTokenFlows_Validation tests forgot/reset password and verify email validation without database
+func TestAccountTokenFlows_Validation(t *testing.T) {
+	h, _ := newTestHandler()
+
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Forgot Password Invalid JSON",
+			handler:        h.ForgotPassword,
+			body:           `{not valid json`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid request body",
+		},
+		{
+			name:           "Forgot Password Missing Email",
+			handler:        h.ForgotPassword,
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Email is required",
+		},
+		{
+			name:           "Reset Password Invalid JSON",
+			handler:        h.ResetPassword,
+			body:           `{not valid json`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid request body",
+		},
+		{
+			name:           "Reset Password Missing Token",
+			handler:        h.ResetPassword,
+			body:           `{"password": "newpassword"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Token and password are required",
+		},
+		{
+			name:           "Reset Password Missing Password",
+			handler:        h.ResetPassword,
+			body:           `{"token": "abc"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Token and password are required",
+		},
+		{
+			name:           "Reset Password Short Password",
+			handler:        h.ResetPassword,
+			body:           `{"token": "abc", "password": "12345"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Password must be at least 6 characters",
+		},
+		{
+			name:           "Verify Email Invalid JSON",
+			handler:        h.VerifyEmail,
+			body:           `{not valid json`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid request body",
+		},
+		{
+			name:           "Verify Email Missing Token",
+			handler:        h.VerifyEmail,
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Token is required",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/auth", bytes.NewBufferString(tc.body))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			tc.handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Status code mismatch")
+			assert.Contains(t, rr.Body.String(), tc.expectedBody, "Response body mismatch")
+		})
+	}
+}
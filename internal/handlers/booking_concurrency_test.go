@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alexs/golang_test/internal/middleware"
+	"github.com/alexs/golang_test/internal/migrations"
+	"github.com/alexs/golang_test/internal/models"
+	"github.com/alexs/golang_test/internal/repository"
+	"github.com/alexs/golang_test/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestBookTicket_ConcurrentNoOversell fires many parallel bookings at a small
+// event against a real Postgres database and asserts capacity is never exceeded.
+// Set TEST_DATABASE_DSN to run it.
+func TestBookTicket_ConcurrentNoOversell(t *testing.T) {
+	db := openTestDB(t)
+
+	h := New(Dependencies{
+		Events:   repository.NewGormEventRepository(db),
+		Bookings: repository.NewGormBookingRepository(db),
+		Users:    repository.NewGormUserRepository(db),
+	})
+
+	const (
+		capacity = 50
+		requests = 300
+	)
+
+	user, event := createTestEvent(t, db, capacity)
+
+	claims := &utils.Claims{UserID: user.ID, Username: user.Username, Email: user.Email}
+	body, _ := json.Marshal(BookTicketRequest{EventID: event.ID, Quantity: 1})
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+		soldOut   int
+		start     = make(chan struct{})
+	)
+
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			req := httptest.NewRequest("POST", "/bookings", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, claims))
+
+			// Release all requests at once to maximise contention
+			<-start
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(h.BookTicket).ServeHTTP(rr, req)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch rr.Code {
+			case http.StatusCreated:
+				succeeded++
+			case http.StatusBadRequest:
+				soldOut++
+			default:
+				t.Errorf("unexpected status %d: %s", rr.Code, rr.Body.String())
+			}
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	assert.Equal(t, capacity, succeeded, "Exactly capacity bookings should succeed")
+	assert.Equal(t, requests-capacity, soldOut, "Remaining requests should be rejected as sold out")
+
+	var totalBooked int64
+	err := db.Model(&models.Booking{}).
+		Where("event_id = ? AND status = ?", event.ID, "confirmed").
+		Select("COALESCE(SUM(quantity), 0)").
+		Scan(&totalBooked).Error
+	require.NoError(t, err)
+	assert.Equal(t, int64(capacity), totalBooked, "Confirmed tickets must never exceed capacity")
+}
+
+// TestCancelBooking_ConcurrentOnce fires parallel cancellations of one booking
+// against a real Postgres database and asserts only one of them frees its
+// tickets. Like TestBookTicket_ConcurrentNoOversell it needs TEST_DATABASE_DSN.
+func TestCancelBooking_ConcurrentOnce(t *testing.T) {
+	db := openTestDB(t)
+	bookings := repository.NewGormBookingRepository(db)
+
+	user, event := createTestEvent(t, db, 5)
+	booking := models.Booking{UserID: user.ID, EventID: event.ID, Quantity: 2}
+	require.NoError(t, bookings.Create(context.Background(), &booking))
+
+	const requests = 20
+	var (
+		wg        sync.WaitGroup
+		succeeded atomic.Int32
+		start     = make(chan struct{})
+	)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := bookings.Cancel(context.Background(), booking.ID, user.ID, time.Minute)
+			if err == nil {
+				succeeded.Add(1)
+			} else if err.Error() != "booking is already cancelled" {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), succeeded.Load(), "Exactly one cancellation should succeed")
+}
+
+// openTestDB connects to the database in TEST_DATABASE_DSN (e.g. "host=localhost
+// user=user password=password dbname=ticket_db port=5432 sslmode=disable") and
+// migrates it, skipping the test when it isn't set
+func openTestDB(t *testing.T) *gorm.DB {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN not set, skipping concurrency test against Postgres")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(20)
+
+	migrator, err := migrations.New(sqlDB)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	return db
+}
+
+// createTestEvent creates a user and a published event they organize, deleting
+// both with their bookings and tickets when the test ends
+func createTestEvent(t *testing.T, db *gorm.DB, capacity int) (models.User, models.Event) {
+	suffix := time.Now().UnixNano()
+	user := models.User{
+		Username: fmt.Sprintf("concurrency-%d", suffix),
+		Email:    fmt.Sprintf("concurrency-%d@example.com", suffix),
+		Password: "not-used",
+	}
+	require.NoError(t, db.Create(&user).Error)
+
+	event := models.Event{
+		Name:        "Concurrency Test Event",
+		Status:      "published",
+		OrganizerID: user.ID,
+		Date:        time.Now().Add(24 * time.Hour),
+		Price:       10,
+		Capacity:    capacity,
+	}
+	require.NoError(t, db.Create(&event).Error)
+
+	t.Cleanup(func() {
+		db.Unscoped().Where("event_id = ?", event.ID).Delete(&models.Ticket{})
+		db.Unscoped().Where("event_id = ?", event.ID).Delete(&models.Booking{})
+		db.Unscoped().Delete(&event)
+		db.Unscoped().Delete(&user)
+	})
+
+	return user, event
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strconv"
@@ -8,37 +9,110 @@ import (
 
 	"github.com/alexs/golang_test/internal/middleware"
 	"github.com/alexs/golang_test/internal/models"
-	"github.com/alexs/golang_test/internal/repository"
 	"github.com/alexs/golang_test/internal/utils"
 	"github.com/alexs/golang_test/internal/websocket"
 	"github.com/go-chi/chi/v5"
 )
 
-// WebSocket hub for broadcasting updates
-var wsHub *websocket.Hub
+// eventAvailability loads the remaining tickets of an event and each of its tiers
+func (h *Handler) eventAvailability(ctx context.Context, event *models.Event) (int, []websocket.TierAvailability) {
+	available, _ := h.events.AvailableTickets(ctx, event)
 
-// SetWebSocketHub sets the WebSocket hub for broadcasting
-func SetWebSocketHub(hub *websocket.Hub) {
-	wsHub = hub
+	var tierUpdates []websocket.TierAvailability
+	tiers, _ := h.events.GetTiersWithStats(ctx, []uint{event.ID})
+	for _, tier := range tiers {
+		tierUpdates = append(tierUpdates, websocket.TierAvailability{
+			TierID:           tier.ID,
+			Name:             tier.Name,
+			AvailableTickets: tier.AvailableTickets(),
+			Capacity:         tier.Capacity,
+		})
+	}
+
+	return available, tierUpdates
+}
+
+// liveEventInfo describes an event for matching WebSocket filter subscriptions
+func liveEventInfo(event *models.Event) websocket.EventInfo {
+	return websocket.EventInfo{
+		ID:          event.ID,
+		City:        event.City,
+		EventType:   event.EventType,
+		OrganizerID: event.OrganizerID,
+	}
+}
+
+// broadcastUpdate is a helper to send availability updates. The hub coalesces
+// bursts of bookings into one refreshAvailability per event and window.
+func (h *Handler) broadcastUpdate(ctx context.Context, eventID uint) {
+	if h.hub != nil {
+		h.hub.RequestAvailabilityUpdate(ctx, eventID)
+	}
+}
+
+// refreshAvailability reads an event's availability once and broadcasts it. It
+// runs on the hub's coalescing timer, outside of any request.
+func (h *Handler) refreshAvailability(ctx context.Context, eventID uint) {
+	event, err := h.events.GetByID(ctx, eventID)
+	if err == nil {
+		available, tierUpdates := h.eventAvailability(ctx, event)
+		h.hub.BroadcastAvailabilityUpdate(ctx, liveEventInfo(event), available, event.Capacity, tierUpdates)
+	}
+
+	// Every availability change is also a change on the organizer's sales dashboard
+	h.notifySales(ctx, eventID)
+}
+
+// notifyBooking tells the booking's owner, on all of their connections, that it changed
+func (h *Handler) notifyBooking(ctx context.Context, booking *models.Booking, msgType websocket.MessageType) {
+	if h.hub == nil {
+		return
+	}
+
+	h.hub.SendToUser(ctx, booking.UserID, &websocket.Message{
+		Type:    msgType,
+		EventID: &booking.EventID,
+		Data: websocket.BookingNotification{
+			BookingID:  booking.ID,
+			EventID:    booking.EventID,
+			EventName:  booking.Event.Name,
+			Quantity:   booking.Quantity,
+			TotalPrice: booking.TotalPrice,
+			Status:     booking.Status,
+		},
+	})
 }
 
-// broadcastUpdate is a helper to send availability updates
-func broadcastUpdate(eventID uint) {
-	if wsHub != nil {
-		event, err := repository.GetEventByID(eventID)
-		if err == nil {
-			available, _ := event.AvailableTickets(repository.DB)
-			wsHub.BroadcastAvailabilityUpdate(eventID, available, event.Capacity)
+// bookingErrorMessages maps ticket tier and seat selection errors from the
+// repository to client messages; all of them are bad requests
+var bookingErrorMessages = map[string]string{
+	"tier_id is required for this event":   "tier_id is required for this event",
+	"ticket tier not found":                "Ticket tier not found",
+	"ticket tier is not on sale":           "Ticket tier is not on sale",
+	"seat_ids are required for this event": "seat_ids are required for this event",
+	"event does not have reserved seating": "Event does not have reserved seating",
+	"event uses reserved seating":          "Event uses reserved seating, book specific seats instead",
+	"invalid seat selection":               "One or more seats do not belong to this event's venue",
+}
+
+// bookingErrorMessage returns the client message for a tier or seat error, if err is one
+func bookingErrorMessage(err error) (string, bool) {
+	for repoMsg, clientMsg := range bookingErrorMessages {
+		if strings.Contains(err.Error(), repoMsg) {
+			return clientMsg, true
 		}
 	}
+	return "", false
 }
 
 type BookTicketRequest struct {
-	EventID  uint `json:"event_id"`
-	Quantity int  `json:"quantity"`
+	EventID  uint   `json:"event_id"`
+	TierID   *uint  `json:"tier_id,omitempty"`
+	SeatIDs  []uint `json:"seat_ids,omitempty"` // required for reserved seating events
+	Quantity int    `json:"quantity"`
 }
 
-func BookTicket(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) BookTicket(w http.ResponseWriter, r *http.Request) {
 	// Get user from context
 	claims := middleware.GetUserFromContext(r)
 	if claims == nil {
@@ -52,6 +126,15 @@ func BookTicket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Seat selections imply the quantity
+	if len(req.SeatIDs) > 0 {
+		if req.Quantity != 0 && req.Quantity != len(req.SeatIDs) {
+			utils.ErrorResponse(w, http.StatusBadRequest, "Quantity must match the number of seats")
+			return
+		}
+		req.Quantity = len(req.SeatIDs)
+	}
+
 	// Validate input
 	if req.EventID == 0 || req.Quantity <= 0 {
 		utils.ErrorResponse(w, http.StatusBadRequest, "Valid event_id and quantity are required")
@@ -62,32 +145,55 @@ func BookTicket(w http.ResponseWriter, r *http.Request) {
 	booking := models.Booking{
 		UserID:   claims.UserID,
 		EventID:  req.EventID,
+		TierID:   req.TierID,
 		Quantity: req.Quantity,
 	}
 
-	if err := repository.CreateBooking(&booking); err != nil {
+	seen := make(map[uint]bool, len(req.SeatIDs))
+	for _, seatID := range req.SeatIDs {
+		if seen[seatID] {
+			utils.ErrorResponse(w, http.StatusBadRequest, "Duplicate seat IDs")
+			return
+		}
+		seen[seatID] = true
+		booking.Seats = append(booking.Seats, models.BookingSeat{SeatID: seatID})
+	}
+
+	if err := h.bookings.Create(r.Context(), &booking); err != nil {
 		if strings.Contains(err.Error(), "not enough tickets available") {
+			h.metrics.BookingRejected()
 			utils.ErrorResponse(w, http.StatusBadRequest, "Not enough tickets available")
 			return
 		}
+		if msg, ok := bookingErrorMessage(err); ok {
+			utils.ErrorResponse(w, http.StatusBadRequest, msg)
+			return
+		}
+		if strings.Contains(err.Error(), "seats already booked") || strings.Contains(err.Error(), "idx_booking_seats_event_seat") {
+			utils.ErrorResponse(w, http.StatusConflict, "One or more selected seats are already booked")
+			return
+		}
 		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to create booking: "+err.Error())
 		return
 	}
 
 	// Fetch complete booking with event details
-	completeBooking, err := repository.GetBookingByID(booking.ID)
+	completeBooking, err := h.bookings.GetByID(r.Context(), booking.ID)
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusInternalServerError, "Booking created but failed to fetch details")
 		return
 	}
+	h.metrics.BookingCreated(completeBooking.Event.EventType, completeBooking.Quantity)
 
-	// Broadcast availability update via WebSocket
-	broadcastUpdate(req.EventID)
+	// Broadcast availability and seat updates via WebSocket
+	h.broadcastUpdate(r.Context(), req.EventID)
+	h.broadcastSeatUpdate(r.Context(), &completeBooking.Event, completeBooking.Seats, "booked")
+	h.notifyBooking(r.Context(), completeBooking, websocket.MessageTypeBookingConfirmed)
 
 	utils.SuccessResponse(w, http.StatusCreated, completeBooking)
 }
 
-func GetMyBookings(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetMyBookings(w http.ResponseWriter, r *http.Request) {
 	// Get user from context
 	claims := middleware.GetUserFromContext(r)
 	if claims == nil {
@@ -95,7 +201,7 @@ func GetMyBookings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bookings, err := repository.GetUserBookings(claims.UserID)
+	bookings, err := h.bookings.GetByUser(r.Context(), claims.UserID)
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch bookings")
 		return
@@ -104,7 +210,7 @@ func GetMyBookings(w http.ResponseWriter, r *http.Request) {
 	utils.SuccessResponse(w, http.StatusOK, bookings)
 }
 
-func CancelBooking(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) CancelBooking(w http.ResponseWriter, r *http.Request) {
 	// Get user from context
 	claims := middleware.GetUserFromContext(r)
 	if claims == nil {
@@ -120,13 +226,14 @@ func CancelBooking(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get booking before cancellation to get event ID
-	booking, err := repository.GetBookingByID(uint(id))
+	booking, err := h.bookings.GetByID(r.Context(), uint(id))
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusNotFound, "Booking not found")
 		return
 	}
 
-	if err := repository.CancelBooking(uint(id), claims.UserID); err != nil {
+	offered, err := h.bookings.Cancel(r.Context(), uint(id), claims.UserID, h.config.Holds.TTL)
+	if err != nil {
 		if strings.Contains(err.Error(), "unauthorized to cancel this booking") {
 			utils.ErrorResponse(w, http.StatusForbidden, "You are not authorized to cancel this booking")
 			return
@@ -138,9 +245,17 @@ func CancelBooking(w http.ResponseWriter, r *http.Request) {
 		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to cancel booking")
 		return
 	}
+	h.metrics.BookingCancelled()
+
+	// Tell waitlisted users about the tickets now held for them
+	h.notifyWaitlistOffers(r.Context(), offered)
+
+	// Broadcast availability and seat updates via WebSocket
+	h.broadcastUpdate(r.Context(), booking.EventID)
+	h.broadcastSeatUpdate(r.Context(), &booking.Event, booking.Seats, "available")
 
-	// Broadcast availability update via WebSocket
-	broadcastUpdate(booking.EventID)
+	booking.Status = "cancelled"
+	h.notifyBooking(r.Context(), booking, websocket.MessageTypeBookingCancelled)
 
 	utils.SuccessResponse(w, http.StatusOK, map[string]string{"message": "Booking cancelled successfully"})
 }
@@ -6,16 +6,23 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/alexs/golang_test/internal/config"
 	"github.com/alexs/golang_test/internal/middleware"
+	"github.com/alexs/golang_test/internal/models"
 	"github.com/alexs/golang_test/internal/utils"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestBookTicket_Validation tests booking validation without database
 func TestBookTicket_Validation(t *testing.T) {
+	h, _ := newTestHandler()
+
 	tests := []struct {
 		name           string
 		body           map[string]interface{}
@@ -52,6 +59,27 @@ func TestBookTicket_Validation(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   "Valid event_id and quantity are required",
 		},
+		{
+			name: "Quantity Does Not Match Seats",
+			body: map[string]interface{}{
+				"event_id": 1,
+				"quantity": 3,
+				"seat_ids": []uint{10, 11},
+			},
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Quantity must match the number of seats",
+		},
+		{
+			name: "Duplicate Seat IDs",
+			body: map[string]interface{}{
+				"event_id": 1,
+				"seat_ids": []uint{10, 10},
+			},
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Duplicate seat IDs",
+		},
 		{
 			name: "No User Context",
 			body: map[string]interface{}{
@@ -83,7 +111,7 @@ func TestBookTicket_Validation(t *testing.T) {
 			}
 
 			rr := httptest.NewRecorder()
-			handler := http.HandlerFunc(BookTicket)
+			handler := http.HandlerFunc(h.BookTicket)
 			handler.ServeHTTP(rr, req)
 
 			assert.Equal(t, tc.expectedStatus, rr.Code, "Status code mismatch")
@@ -94,6 +122,8 @@ func TestBookTicket_Validation(t *testing.T) {
 
 // TestBookTicket_InvalidJSON tests booking with malformed JSON
 func TestBookTicket_InvalidJSON(t *testing.T) {
+	h, _ := newTestHandler()
+
 	req, err := http.NewRequest("POST", "/bookings", bytes.NewBufferString("{not valid json"))
 	assert.NoError(t, err)
 	req.Header.Set("Content-Type", "application/json")
@@ -108,7 +138,7 @@ func TestBookTicket_InvalidJSON(t *testing.T) {
 	req = req.WithContext(ctx)
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(BookTicket)
+	handler := http.HandlerFunc(h.BookTicket)
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusBadRequest, rr.Code)
@@ -117,11 +147,13 @@ func TestBookTicket_InvalidJSON(t *testing.T) {
 
 // TestGetMyBookings_NoAuth tests getting bookings without auth
 func TestGetMyBookings_NoAuth(t *testing.T) {
+	h, _ := newTestHandler()
+
 	req, err := http.NewRequest("GET", "/bookings", nil)
 	assert.NoError(t, err)
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(GetMyBookings)
+	handler := http.HandlerFunc(h.GetMyBookings)
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusUnauthorized, rr.Code)
@@ -130,6 +162,8 @@ func TestGetMyBookings_NoAuth(t *testing.T) {
 
 // TestCancelBooking_InvalidID tests canceling a booking with invalid ID
 func TestCancelBooking_InvalidID(t *testing.T) {
+	h, _ := newTestHandler()
+
 	tests := []struct {
 		name           string
 		id             string
@@ -182,7 +216,7 @@ func TestCancelBooking_InvalidID(t *testing.T) {
 			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 			rr := httptest.NewRecorder()
-			handler := http.HandlerFunc(CancelBooking)
+			handler := http.HandlerFunc(h.CancelBooking)
 			handler.ServeHTTP(rr, req)
 
 			assert.Equal(t, tc.expectedStatus, rr.Code, "Status code mismatch")
@@ -190,3 +224,100 @@ func TestCancelBooking_InvalidID(t *testing.T) {
 		})
 	}
 }
+
+// withUser adds the claims of an authenticated user to a request
+func withUser(req *http.Request, userID uint) *http.Request {
+	claims := &utils.Claims{
+		UserID:   userID,
+		Username: "testuser",
+		Email:    "test@example.com",
+	}
+	return req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, claims))
+}
+
+// TestBookTicket_Success tests booking tickets against the in-memory store
+func TestBookTicket_Success(t *testing.T) {
+	h, store := newTestHandler()
+	event := store.addEvent(models.Event{Name: "Jazz Night", EventType: "concert", Status: "published", Price: 25, Capacity: 10, Date: time.Now().Add(24 * time.Hour)})
+
+	book := func(quantity int) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(BookTicketRequest{EventID: event.ID, Quantity: quantity})
+		req := httptest.NewRequest("POST", "/bookings", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		rr := httptest.NewRecorder()
+		h.BookTicket(rr, withUser(req, 7))
+		return rr
+	}
+
+	rr := book(4)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	var response struct {
+		Data models.Booking `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, uint(7), response.Data.UserID)
+	assert.Equal(t, "confirmed", response.Data.Status)
+	assert.Equal(t, 100.0, response.Data.TotalPrice)
+	assert.Equal(t, "Jazz Night", response.Data.Event.Name)
+
+	available, err := h.events.AvailableTickets(context.Background(), &event)
+	require.NoError(t, err)
+	assert.Equal(t, 6, available)
+
+	// Only 6 tickets are left
+	rr = book(7)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Not enough tickets available")
+
+	// Both outcomes are counted
+	rr = httptest.NewRecorder()
+	h.metrics.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, rr.Body.String(), `bookings_total{result="created"} 1`)
+	assert.Contains(t, rr.Body.String(), `bookings_total{result="rejected_capacity"} 1`)
+	assert.Contains(t, rr.Body.String(), `tickets_sold_total{event_type="concert"} 4`)
+}
+
+// TestCancelBooking_Success tests cancelling a booking against the in-memory store
+func TestCancelBooking_Success(t *testing.T) {
+	utils.ConfigureJWT(config.JWTConfig{Secret: "test-secret-key-for-testing", AccessTokenTTL: 24 * time.Hour})
+
+	h, store := newTestHandler()
+	event := store.addEvent(models.Event{Name: "Jazz Night", Status: "published", Price: 25, Capacity: 10, Date: time.Now().Add(24 * time.Hour)})
+	booking := models.Booking{UserID: 7, EventID: event.ID, Quantity: 2}
+	require.NoError(t, h.bookings.Create(context.Background(), &booking))
+
+	cancel := func(userID uint) *httptest.ResponseRecorder {
+		id := strconv.FormatUint(uint64(booking.ID), 10)
+		req := withUser(httptest.NewRequest("DELETE", "/bookings/"+id, nil), userID)
+
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+		rr := httptest.NewRecorder()
+		h.CancelBooking(rr, req)
+		return rr
+	}
+
+	// Someone else's booking can't be cancelled
+	rr := cancel(8)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr = cancel(7)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), "Booking cancelled successfully")
+
+	stored, ok := store.booking(booking.ID)
+	require.True(t, ok)
+	assert.Equal(t, "cancelled", stored.Status)
+
+	available, err := h.events.AvailableTickets(context.Background(), &event)
+	require.NoError(t, err)
+	assert.Equal(t, 10, available, "Cancelled tickets are available again")
+
+	rr = cancel(7)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Booking is already cancelled")
+}
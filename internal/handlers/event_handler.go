@@ -1,22 +1,58 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/alexs/golang_test/internal/middleware"
 	"github.com/alexs/golang_test/internal/models"
 	"github.com/alexs/golang_test/internal/repository"
 	"github.com/alexs/golang_test/internal/utils"
+	"github.com/alexs/golang_test/internal/websocket"
 	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
 )
 
 func parseDate(dateStr string) (time.Time, error) {
 	return time.Parse(time.RFC3339, dateStr)
 }
 
+// notifyEventCancelled tells every user holding tickets for the event that it won't take place
+func (h *Handler) notifyEventCancelled(ctx context.Context, event *models.Event) {
+	if h.hub == nil {
+		return
+	}
+
+	holderIDs, err := h.bookings.GetEventTicketHolderIDs(ctx, event.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load ticket holders", "event_id", event.ID, "error", err)
+		return
+	}
+
+	for _, userID := range holderIDs {
+		h.hub.SendToUser(ctx, userID, &websocket.Message{
+			Type:    websocket.MessageTypeEventCancelled,
+			EventID: &event.ID,
+			Data: websocket.EventCancelled{
+				EventID:   event.ID,
+				EventName: event.Name,
+				Date:      event.Date.Format(time.RFC3339),
+			},
+		})
+	}
+}
+
+// canManageEvent reports whether the user owns the event or is an admin moderating it
+func canManageEvent(user *utils.Claims, event *models.Event) bool {
+	return event.OrganizerID == user.UserID || user.Role == models.RoleAdmin
+}
+
 // parseEventFilters extracts filter parameters from the HTTP request query string
 func parseEventFilters(r *http.Request) repository.EventFilters {
 	query := r.URL.Query()
@@ -47,14 +83,118 @@ type CreateEventRequest struct {
 	Price       float64 `json:"price"`
 	Capacity    int     `json:"capacity"`
 	ImageURL    string  `json:"image_url"`
+
+	// Optional seat map; when set, capacity is the venue's seat count
+	VenueID *uint `json:"venue_id"`
+
+	// Optional ticket tiers; their capacities must fit within the event capacity
+	Tiers []CreateTierRequest `json:"tiers"`
+}
+
+type CreateTierRequest struct {
+	Name       string  `json:"name"`
+	Price      float64 `json:"price"`
+	Capacity   int     `json:"capacity"`
+	SalesStart string  `json:"sales_start"` // RFC3339 format, optional
+	SalesEnd   string  `json:"sales_end"`   // RFC3339 format, optional
+}
+
+// UpdateEventRequest holds a partial event update; nil fields are left unchanged
+type UpdateEventRequest struct {
+	Name        *string  `json:"name"`
+	Description *string  `json:"description"`
+	VenueName   *string  `json:"venue_name"`
+	City        *string  `json:"city"`
+	Address     *string  `json:"address"`
+	Date        *string  `json:"date"` // RFC3339 format
+	Price       *float64 `json:"price"`
+	Capacity    *int     `json:"capacity"`
+	ImageURL    *string  `json:"image_url"`
+	VenueID     *uint    `json:"venue_id"` // attaches a seat map; capacity follows its seat count
+	Status      *string  `json:"status"`   // draft, published, cancelled
 }
 
 type EventResponse struct {
 	models.Event
-	AvailableTickets int `json:"available_tickets"`
+	AvailableTickets int            `json:"available_tickets"`
+	Tiers            []TierResponse `json:"tiers,omitempty"`
+}
+
+type TierResponse struct {
+	models.TicketTier
+	AvailableTickets int  `json:"available_tickets"`
+	OnSale           bool `json:"on_sale"`
+}
+
+// parseTiers validates tier requests and converts them to models
+func parseTiers(reqs []CreateTierRequest, eventCapacity int) ([]models.TicketTier, string) {
+	tiers := make([]models.TicketTier, 0, len(reqs))
+	totalCapacity := 0
+
+	for _, req := range reqs {
+		if req.Name == "" || req.Capacity <= 0 {
+			return nil, "Each tier requires a name and capacity"
+		}
+		if req.Price < 0 {
+			return nil, "Tier price cannot be negative"
+		}
+
+		tier := models.TicketTier{
+			Name:     req.Name,
+			Price:    req.Price,
+			Capacity: req.Capacity,
+		}
+
+		if req.SalesStart != "" {
+			start, err := parseDate(req.SalesStart)
+			if err != nil {
+				return nil, "Invalid tier sales window. Use RFC3339 format"
+			}
+			tier.SalesStart = &start
+		}
+		if req.SalesEnd != "" {
+			end, err := parseDate(req.SalesEnd)
+			if err != nil {
+				return nil, "Invalid tier sales window. Use RFC3339 format"
+			}
+			tier.SalesEnd = &end
+		}
+		if tier.SalesStart != nil && tier.SalesEnd != nil && !tier.SalesEnd.After(*tier.SalesStart) {
+			return nil, "Tier sales end must be after sales start"
+		}
+
+		totalCapacity += req.Capacity
+		tiers = append(tiers, tier)
+	}
+
+	if totalCapacity > eventCapacity {
+		return nil, "Tier capacities cannot exceed event capacity"
+	}
+
+	return tiers, ""
 }
 
-func CreateEvent(w http.ResponseWriter, r *http.Request) {
+// tierResponsesByEvent loads per-tier availability for the given events
+func (h *Handler) tierResponsesByEvent(ctx context.Context, eventIDs []uint) map[uint][]TierResponse {
+	result := make(map[uint][]TierResponse)
+
+	tiers, err := h.events.GetTiersWithStats(ctx, eventIDs)
+	if err != nil {
+		return result
+	}
+
+	now := time.Now()
+	for _, tier := range tiers {
+		result[tier.EventID] = append(result[tier.EventID], TierResponse{
+			TicketTier:       tier.TicketTier,
+			AvailableTickets: tier.AvailableTickets(),
+			OnSale:           tier.OnSale(now),
+		})
+	}
+	return result
+}
+
+func (h *Handler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 	var req CreateEventRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
@@ -62,7 +202,7 @@ func CreateEvent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate required fields
-	if req.Name == "" || req.Date == "" || req.Capacity <= 0 {
+	if req.Name == "" || req.Date == "" || (req.Capacity <= 0 && req.VenueID == nil) {
 		utils.ErrorResponse(w, http.StatusBadRequest, "Name, date, and capacity are required")
 		return
 	}
@@ -74,12 +214,36 @@ func CreateEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Reserved seating events hold exactly one ticket per seat
+	if req.VenueID != nil {
+		seatCount, err := h.venues.CountSeats(r.Context(), *req.VenueID)
+		if err != nil || seatCount == 0 {
+			utils.ErrorResponse(w, http.StatusBadRequest, "Venue not found or has no seats")
+			return
+		}
+		req.Capacity = seatCount
+	}
+
+	tiers, errMsg := parseTiers(req.Tiers, req.Capacity)
+	if errMsg != "" {
+		utils.ErrorResponse(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
 	user := middleware.GetUserFromContext(r)
 	if user == nil {
 		utils.ErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
+	// Tiered events advertise their cheapest tier as the event price
+	price := req.Price
+	for i, tier := range tiers {
+		if i == 0 || tier.Price < price {
+			price = tier.Price
+		}
+	}
+
 	event := models.Event{
 		Name:        req.Name,
 		Description: req.Description,
@@ -90,12 +254,14 @@ func CreateEvent(w http.ResponseWriter, r *http.Request) {
 		City:        req.City,
 		Address:     req.Address,
 		Date:        date,
-		Price:       req.Price,
+		Price:       price,
 		Capacity:    req.Capacity,
 		ImageURL:    req.ImageURL,
+		VenueID:     req.VenueID,
+		Tiers:       tiers,
 	}
 
-	if err := repository.CreateEvent(&event); err != nil {
+	if err := h.events.Create(r.Context(), &event); err != nil {
 		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to create event")
 		return
 	}
@@ -103,7 +269,7 @@ func CreateEvent(w http.ResponseWriter, r *http.Request) {
 	utils.SuccessResponse(w, http.StatusCreated, event)
 }
 
-func GetEvents(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetEvents(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
 	filters := parseEventFilters(r)
 
@@ -152,18 +318,26 @@ func GetEvents(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get events with filters
-	result, err := repository.GetEventsWithFilters(filters)
+	result, err := h.events.GetWithFilters(r.Context(), filters)
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch events")
 		return
 	}
 
+	// Load per-tier availability for the whole page at once
+	eventIDs := make([]uint, len(result.Events))
+	for i, event := range result.Events {
+		eventIDs[i] = event.ID
+	}
+	tiersByEvent := h.tierResponsesByEvent(r.Context(), eventIDs)
+
 	// Transform to EventResponse with available tickets
 	eventResponses := make([]EventResponse, len(result.Events))
 	for i, event := range result.Events {
 		eventResponses[i] = EventResponse{
 			Event:            event.Event,
-			AvailableTickets: event.Capacity - int(event.TotalBooked),
+			AvailableTickets: event.Capacity - int(event.TotalBooked) - int(event.TotalHeld),
+			Tiers:            tiersByEvent[event.ID],
 		}
 	}
 
@@ -181,7 +355,7 @@ func GetEvents(w http.ResponseWriter, r *http.Request) {
 	utils.SuccessResponse(w, http.StatusOK, response)
 }
 
-func GetEvent(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetEvent(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
@@ -189,23 +363,24 @@ func GetEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	event, err := repository.GetEventByID(uint(id))
+	event, err := h.events.GetByID(r.Context(), uint(id))
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusNotFound, "Event not found")
 		return
 	}
 
 	// Add available tickets count
-	available, _ := event.AvailableTickets(repository.DB)
+	available, _ := h.events.AvailableTickets(r.Context(), event)
 	eventResponse := EventResponse{
 		Event:            *event,
 		AvailableTickets: available,
+		Tiers:            h.tierResponsesByEvent(r.Context(), []uint{event.ID})[event.ID],
 	}
 
 	utils.SuccessResponse(w, http.StatusOK, eventResponse)
 }
 
-func DeleteEvent(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
@@ -219,21 +394,164 @@ func DeleteEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	event, err := repository.GetEventByID(uint(id))
+	event, err := h.events.GetByID(r.Context(), uint(id))
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusNotFound, "Event not found")
 		return
 	}
 
-	if event.OrganizerID != user.UserID {
+	if !canManageEvent(user, event) {
 		utils.ErrorResponse(w, http.StatusForbidden, "You are not authorized to delete this event")
 		return
 	}
 
-	if err := repository.DeleteEvent(uint(id)); err != nil {
+	if err := h.events.Delete(r.Context(), uint(id)); err != nil {
 		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to delete event")
 		return
 	}
 
+	// Ticket holders of an already cancelled event were notified at cancellation
+	if event.Status != "cancelled" {
+		h.notifyEventCancelled(r.Context(), event)
+	}
+
 	utils.SuccessResponse(w, http.StatusOK, map[string]string{"message": "Event deleted successfully"})
 }
+
+// UpdateEvent applies a partial update to an event owned by the authenticated user
+func (h *Handler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	user := middleware.GetUserFromContext(r)
+	if user == nil {
+		utils.ErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req UpdateEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Validate provided fields
+	if req.Name != nil && *req.Name == "" {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Name cannot be empty")
+		return
+	}
+	if req.Capacity != nil && *req.Capacity <= 0 {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Capacity must be greater than zero")
+		return
+	}
+	if req.Price != nil && *req.Price < 0 {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Price cannot be negative")
+		return
+	}
+	if req.Capacity != nil && req.VenueID != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Capacity is set by the venue's seat map")
+		return
+	}
+	if req.Status != nil && *req.Status != "draft" && *req.Status != "published" && *req.Status != "cancelled" {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Status must be one of: draft, published, cancelled")
+		return
+	}
+
+	var date time.Time
+	if req.Date != nil {
+		date, err = parseDate(*req.Date)
+		if err != nil {
+			utils.ErrorResponse(w, http.StatusBadRequest, "Invalid date format. Use RFC3339 format")
+			return
+		}
+	}
+
+	event, err := h.events.GetByID(r.Context(), uint(id))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	if !canManageEvent(user, event) {
+		utils.ErrorResponse(w, http.StatusForbidden, "You are not authorized to update this event")
+		return
+	}
+
+	// Only the fields that were sent change
+	changes := repository.EventChanges{
+		Name:        req.Name,
+		Description: req.Description,
+		VenueName:   req.VenueName,
+		City:        req.City,
+		Address:     req.Address,
+		Price:       req.Price,
+		Capacity:    req.Capacity,
+		ImageURL:    req.ImageURL,
+		Status:      req.Status,
+	}
+	if req.Date != nil {
+		changes.Date = &date
+	}
+
+	// Attaching a seat map sets capacity to its seat count
+	if req.VenueID != nil {
+		seatCount, err := h.venues.CountSeats(r.Context(), *req.VenueID)
+		if err != nil || seatCount == 0 {
+			utils.ErrorResponse(w, http.StatusBadRequest, "Venue not found or has no seats")
+			return
+		}
+		changes.VenueID = req.VenueID
+		changes.Capacity = &seatCount
+	}
+
+	updated, err := h.events.Update(r.Context(), event.ID, changes)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(w, http.StatusNotFound, "Event not found")
+			return
+		}
+		if strings.Contains(err.Error(), "capacity is set by the venue's seat map") {
+			utils.ErrorResponse(w, http.StatusBadRequest, "Capacity is set by the venue's seat map")
+			return
+		}
+		if strings.Contains(err.Error(), "capacity cannot be lower than tickets already booked or held") {
+			utils.ErrorResponse(w, http.StatusConflict, "Capacity cannot be lower than tickets already booked or held")
+			return
+		}
+		if strings.Contains(err.Error(), "capacity cannot be lower than the combined capacity of its tiers") {
+			utils.ErrorResponse(w, http.StatusConflict, "Capacity cannot be lower than the combined capacity of the event's tiers")
+			return
+		}
+		if strings.Contains(err.Error(), "cannot change the seat map of an event with bookings") {
+			utils.ErrorResponse(w, http.StatusConflict, "Cannot change the seat map of an event with bookings")
+			return
+		}
+		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to update event")
+		return
+	}
+
+	capacityChanged := updated.Capacity != event.Capacity
+	cancelled := updated.Status == "cancelled" && event.Status != "cancelled"
+	event = updated
+
+	// Broadcast availability update via WebSocket
+	if capacityChanged {
+		h.broadcastUpdate(r.Context(), event.ID)
+	}
+
+	// Let ticket holders know their event is off
+	if cancelled {
+		h.notifyEventCancelled(r.Context(), event)
+	}
+
+	available, _ := h.events.AvailableTickets(r.Context(), event)
+	utils.SuccessResponse(w, http.StatusOK, EventResponse{
+		Event:            *event,
+		AvailableTickets: available,
+		Tiers:            h.tierResponsesByEvent(r.Context(), []uint{event.ID})[event.ID],
+	})
+}
@@ -4,16 +4,25 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/alexs/golang_test/internal/middleware"
+	"github.com/alexs/golang_test/internal/models"
+	"github.com/alexs/golang_test/internal/utils"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestCreateEvent_Validation tests event creation validation without database
 func TestCreateEvent_Validation(t *testing.T) {
+	h, _ := newTestHandler()
+
 	tests := []struct {
 		name           string
 		body           map[string]interface{}
@@ -88,6 +97,59 @@ func TestCreateEvent_Validation(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   "Name, date, and capacity are required",
 		},
+		{
+			name: "Tier Without Name",
+			body: map[string]interface{}{
+				"name":     "Test Event",
+				"date":     "2025-12-25T18:00:00Z",
+				"capacity": 100,
+				"tiers": []map[string]interface{}{
+					{"price": 50, "capacity": 50},
+				},
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Each tier requires a name and capacity",
+		},
+		{
+			name: "Tier Negative Price",
+			body: map[string]interface{}{
+				"name":     "Test Event",
+				"date":     "2025-12-25T18:00:00Z",
+				"capacity": 100,
+				"tiers": []map[string]interface{}{
+					{"name": "VIP", "price": -5, "capacity": 10},
+				},
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Tier price cannot be negative",
+		},
+		{
+			name: "Tier Invalid Sales Window",
+			body: map[string]interface{}{
+				"name":     "Test Event",
+				"date":     "2025-12-25T18:00:00Z",
+				"capacity": 100,
+				"tiers": []map[string]interface{}{
+					{"name": "Early Bird", "price": 20, "capacity": 10, "sales_end": "2025-12-01"},
+				},
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid tier sales window",
+		},
+		{
+			name: "Tier Capacities Exceed Event Capacity",
+			body: map[string]interface{}{
+				"name":     "Test Event",
+				"date":     "2025-12-25T18:00:00Z",
+				"capacity": 100,
+				"tiers": []map[string]interface{}{
+					{"name": "General Admission", "price": 40, "capacity": 80},
+					{"name": "VIP", "price": 120, "capacity": 30},
+				},
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Tier capacities cannot exceed event capacity",
+		},
 	}
 
 	for _, tc := range tests {
@@ -98,7 +160,7 @@ func TestCreateEvent_Validation(t *testing.T) {
 			req.Header.Set("Content-Type", "application/json")
 
 			rr := httptest.NewRecorder()
-			handler := http.HandlerFunc(CreateEvent)
+			handler := http.HandlerFunc(h.CreateEvent)
 			handler.ServeHTTP(rr, req)
 
 			assert.Equal(t, tc.expectedStatus, rr.Code, "Status code mismatch")
@@ -109,12 +171,14 @@ func TestCreateEvent_Validation(t *testing.T) {
 
 // TestCreateEvent_InvalidJSON tests event creation with malformed JSON
 func TestCreateEvent_InvalidJSON(t *testing.T) {
+	h, _ := newTestHandler()
+
 	req, err := http.NewRequest("POST", "/events", bytes.NewBufferString("{invalid json}"))
 	assert.NoError(t, err)
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(CreateEvent)
+	handler := http.HandlerFunc(h.CreateEvent)
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusBadRequest, rr.Code)
@@ -169,6 +233,8 @@ func TestParseDate(t *testing.T) {
 
 // TestGetEvent_InvalidID tests getting an event with invalid ID
 func TestGetEvent_InvalidID(t *testing.T) {
+	h, _ := newTestHandler()
+
 	tests := []struct {
 		name           string
 		id             string
@@ -207,7 +273,7 @@ func TestGetEvent_InvalidID(t *testing.T) {
 			rctx.URLParams.Add("id", tc.id)
 			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
-			handler := http.HandlerFunc(GetEvent)
+			handler := http.HandlerFunc(h.GetEvent)
 			handler.ServeHTTP(rr, req)
 
 			assert.Equal(t, tc.expectedStatus, rr.Code, "Status code mismatch")
@@ -218,6 +284,8 @@ func TestGetEvent_InvalidID(t *testing.T) {
 
 // TestDeleteEvent_InvalidID tests deleting an event with invalid ID
 func TestDeleteEvent_InvalidID(t *testing.T) {
+	h, _ := newTestHandler()
+
 	tests := []struct {
 		name           string
 		id             string
@@ -250,7 +318,7 @@ func TestDeleteEvent_InvalidID(t *testing.T) {
 			rctx.URLParams.Add("id", tc.id)
 			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
-			handler := http.HandlerFunc(DeleteEvent)
+			handler := http.HandlerFunc(h.DeleteEvent)
 			handler.ServeHTTP(rr, req)
 
 			assert.Equal(t, tc.expectedStatus, rr.Code, "Status code mismatch")
@@ -258,3 +326,237 @@ func TestDeleteEvent_InvalidID(t *testing.T) {
 		})
 	}
 }
+
+// TestUpdateEvent_Validation tests partial event update validation without database
+func TestUpdateEvent_Validation(t *testing.T) {
+	h, _ := newTestHandler()
+
+	tests := []struct {
+		name           string
+		id             string
+		body           string
+		withAuth       bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Non-numeric ID",
+			id:             "abc",
+			body:           `{"name": "New Name"}`,
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid event ID",
+		},
+		{
+			name:           "No Auth",
+			id:             "1",
+			body:           `{"name": "New Name"}`,
+			withAuth:       false,
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "Unauthorized",
+		},
+		{
+			name:           "Invalid JSON",
+			id:             "1",
+			body:           `{invalid json}`,
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid request body",
+		},
+		{
+			name:           "Empty Name",
+			id:             "1",
+			body:           `{"name": ""}`,
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Name cannot be empty",
+		},
+		{
+			name:           "Zero Capacity",
+			id:             "1",
+			body:           `{"capacity": 0}`,
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Capacity must be greater than zero",
+		},
+		{
+			name:           "Negative Price",
+			id:             "1",
+			body:           `{"price": -1}`,
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Price cannot be negative",
+		},
+		{
+			name:           "Unknown Status",
+			id:             "1",
+			body:           `{"status": "archived"}`,
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Status must be one of: draft, published, cancelled",
+		},
+		{
+			name:           "Invalid Date Format",
+			id:             "1",
+			body:           `{"date": "2025-12-25"}`,
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid date format",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("PATCH", "/events/"+tc.id, bytes.NewBufferString(tc.body))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			// Add user claims to context if needed
+			if tc.withAuth {
+				claims := &utils.Claims{
+					UserID:   1,
+					Username: "testuser",
+					Email:    "test@example.com",
+				}
+				ctx := context.WithValue(req.Context(), middleware.UserContextKey, claims)
+				req = req.WithContext(ctx)
+			}
+
+			// Simulate chi URL params
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tc.id)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(h.UpdateEvent)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Status code mismatch")
+			assert.Contains(t, rr.Body.String(), tc.expectedBody, "Response body mismatch")
+		})
+	}
+}
+
+// TestReservedSeating_Capacity tests that events on a seat map take their capacity
+// from its seat count, against the in-memory store
+func TestReservedSeating_Capacity(t *testing.T) {
+	h, store := newTestHandler()
+	venue := store.addVenue("Concert Hall", 12)
+	emptyVenue := store.addVenue("Empty Hall", 0)
+	organizer := &utils.Claims{UserID: 5, Username: "organizer", Role: models.RoleOrganizer}
+
+	send := func(handler http.HandlerFunc, method, id, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/events/"+id, bytes.NewBufferString(body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		ctx := context.WithValue(req.Context(), middleware.UserContextKey, organizer)
+		req = req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	withVenue := func(id uint) string {
+		body, _ := json.Marshal(map[string]interface{}{
+			"name":     "Symphony",
+			"date":     "2030-12-25T18:00:00Z",
+			"capacity": 500,
+			"venue_id": id,
+		})
+		return string(body)
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		rr := send(h.CreateEvent, "POST", "", withVenue(venue.ID))
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+		var response struct {
+			Data models.Event `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		assert.Equal(t, 12, response.Data.Capacity)
+	})
+
+	t.Run("Create Without Seats", func(t *testing.T) {
+		for _, id := range []uint{emptyVenue.ID, 9999} {
+			rr := send(h.CreateEvent, "POST", "", withVenue(id))
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Contains(t, rr.Body.String(), "Venue not found or has no seats")
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		event := store.addEvent(models.Event{Name: "Recital", OrganizerID: organizer.UserID, Status: "published", Capacity: 300, Date: time.Now().Add(24 * time.Hour)})
+		id := strconv.FormatUint(uint64(event.ID), 10)
+
+		rr := send(h.UpdateEvent, "PATCH", id, fmt.Sprintf(`{"venue_id": %d}`, venue.ID))
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		updated, err := h.events.GetByID(context.Background(), event.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 12, updated.Capacity)
+		require.NotNil(t, updated.VenueID)
+		assert.Equal(t, venue.ID, *updated.VenueID)
+
+		rr = send(h.UpdateEvent, "PATCH", id, `{"capacity": 50, "name": "Renamed"}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Capacity is set by the venue's seat map")
+		unchanged, err := h.events.GetByID(context.Background(), event.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Recital", unchanged.Name, "A rejected update changes nothing")
+
+		rr = send(h.UpdateEvent, "PATCH", id, fmt.Sprintf(`{"venue_id": %d}`, emptyVenue.ID))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Venue not found or has no seats")
+	})
+}
+
+// TestGetEvents_Success tests listing, filtering and paging events against the in-memory store
+func TestGetEvents_Success(t *testing.T) {
+	h, store := newTestHandler()
+
+	now := time.Now()
+	jazz := store.addEvent(models.Event{Name: "Jazz Night", EventType: "concert", City: "Copenhagen", Status: "published", Price: 25, Capacity: 100, Date: now.Add(48 * time.Hour)})
+	store.addEvent(models.Event{Name: "Rock Fest", EventType: "concert", City: "Aarhus", Status: "published", Price: 60, Capacity: 500, Date: now.Add(24 * time.Hour)})
+	store.addEvent(models.Event{Name: "Stand-up Special", EventType: "standup", City: "Copenhagen", Status: "published", Price: 15, Capacity: 80, Date: now.Add(72 * time.Hour)})
+	store.addEvent(models.Event{Name: "Secret Show", EventType: "concert", City: "Copenhagen", Status: "draft", Price: 10, Capacity: 50, Date: now.Add(96 * time.Hour)})
+
+	booking := models.Booking{UserID: 7, EventID: jazz.ID, Quantity: 3}
+	require.NoError(t, h.bookings.Create(context.Background(), &booking))
+
+	type page struct {
+		Data struct {
+			Events     []EventResponse `json:"events"`
+			Total      int64           `json:"total"`
+			TotalPages int             `json:"total_pages"`
+			HasNext    bool            `json:"has_next"`
+		} `json:"data"`
+	}
+	list := func(query string) page {
+		rr := httptest.NewRecorder()
+		h.GetEvents(rr, httptest.NewRequest("GET", "/events"+query, nil))
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		var response page
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		return response
+	}
+
+	// Published events only, soonest first
+	all := list("")
+	assert.Equal(t, int64(3), all.Data.Total)
+	require.Len(t, all.Data.Events, 3)
+	assert.Equal(t, "Rock Fest", all.Data.Events[0].Name)
+	assert.Equal(t, "Jazz Night", all.Data.Events[1].Name)
+	assert.Equal(t, 97, all.Data.Events[1].AvailableTickets)
+
+	concerts := list("?type=concert&city=copenhagen")
+	require.Len(t, concerts.Data.Events, 1)
+	assert.Equal(t, "Jazz Night", concerts.Data.Events[0].Name)
+
+	cheapest := list("?sort=price&limit=2")
+	assert.Equal(t, 2, cheapest.Data.TotalPages)
+	assert.True(t, cheapest.Data.HasNext)
+	require.Len(t, cheapest.Data.Events, 2)
+	assert.Equal(t, "Stand-up Special", cheapest.Data.Events[0].Name)
+}
@@ -0,0 +1,626 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexs/golang_test/internal/models"
+	"github.com/alexs/golang_test/internal/repository"
+	"github.com/alexs/golang_test/internal/utils"
+	"gorm.io/gorm"
+)
+
+// memoryStore keeps events, bookings, holds, tickets, users and venues in
+// memory for handler tests. Its bookings are general admission only: no tiers,
+// seats or waitlist.
+type memoryStore struct {
+	mutex    sync.Mutex
+	events   map[uint]models.Event
+	bookings map[uint]models.Booking
+	holds    map[uint]models.Hold
+	users    map[uint]models.User
+	venues   map[uint]models.Venue
+	tickets  map[uint]models.Ticket
+	nextID   uint
+}
+
+// newMemoryStore creates an empty store
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		events:   make(map[uint]models.Event),
+		bookings: make(map[uint]models.Booking),
+		holds:    make(map[uint]models.Hold),
+		users:    make(map[uint]models.User),
+		venues:   make(map[uint]models.Venue),
+		tickets:  make(map[uint]models.Ticket),
+	}
+}
+
+// newTestHandler creates a Handler backed by an empty in-memory store and no hub
+func newTestHandler() (*Handler, *memoryStore) {
+	store := newMemoryStore()
+	h := New(Dependencies{
+		Events:   fakeEventRepository{store},
+		Bookings: fakeBookingRepository{store},
+		Holds:    fakeHoldRepository{store},
+		Users:    fakeUserRepository{store},
+		Venues:   fakeVenueRepository{store},
+		Tickets:  fakeTicketRepository{store},
+	})
+	return h, store
+}
+
+// id hands out the next ID; the caller holds the lock
+func (s *memoryStore) id() uint {
+	s.nextID++
+	return s.nextID
+}
+
+// bookedLocked sums the confirmed tickets of an event; the caller holds the lock
+func (s *memoryStore) bookedLocked(eventID uint) int {
+	booked := 0
+	for _, booking := range s.bookings {
+		if booking.EventID == eventID && booking.Status == "confirmed" {
+			booked += booking.Quantity
+		}
+	}
+	return booked
+}
+
+// addEvent stores an event as is, for arranging test data
+func (s *memoryStore) addEvent(event models.Event) models.Event {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	event.ID = s.id()
+	s.events[event.ID] = event
+	return event
+}
+
+// addVenue stores a venue with the given number of seats in a single section
+func (s *memoryStore) addVenue(name string, seats int) models.Venue {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	venue := models.Venue{Name: name, Sections: []models.Section{{Name: "Main"}}}
+	venue.ID = s.id()
+	venue.Sections[0].ID = s.id()
+	venue.Sections[0].VenueID = venue.ID
+	for i := 1; i <= seats; i++ {
+		seat := models.Seat{SectionID: venue.Sections[0].ID, Row: "A", Number: i}
+		seat.ID = s.id()
+		venue.Sections[0].Seats = append(venue.Sections[0].Seats, seat)
+	}
+	s.venues[venue.ID] = venue
+	return venue
+}
+
+// addHold stores a hold as is, for arranging test data
+func (s *memoryStore) addHold(hold models.Hold) models.Hold {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	hold.ID = s.id()
+	s.holds[hold.ID] = hold
+	return hold
+}
+
+// addTicket stores a ticket as is, for arranging test data
+func (s *memoryStore) addTicket(ticket models.Ticket) models.Ticket {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ticket.ID = s.id()
+	s.tickets[ticket.ID] = ticket
+	return ticket
+}
+
+// booking returns a stored booking, for asserting on test results
+func (s *memoryStore) booking(id uint) (models.Booking, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	booking, ok := s.bookings[id]
+	return booking, ok
+}
+
+// fakeEventRepository is an in-memory repository.EventRepository
+type fakeEventRepository struct{ *memoryStore }
+
+func (r fakeEventRepository) Create(ctx context.Context, event *models.Event) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	event.ID = r.id()
+	event.CreatedAt = time.Now()
+	r.events[event.ID] = *event
+	return nil
+}
+
+func (r fakeEventRepository) GetByID(ctx context.Context, id uint) (*models.Event, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	event, ok := r.events[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &event, nil
+}
+
+// Update applies changes under the store lock, checking capacity against
+// confirmed bookings only since the store has no holds or tiers
+func (r fakeEventRepository) Update(ctx context.Context, id uint, changes repository.EventChanges) (*models.Event, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	event, ok := r.events[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if changes.Capacity != nil && changes.VenueID == nil && event.VenueID != nil {
+		return nil, errors.New("capacity is set by the venue's seat map")
+	}
+	if changes.Capacity != nil && *changes.Capacity < r.bookedLocked(id) {
+		return nil, errors.New("capacity cannot be lower than tickets already booked or held")
+	}
+
+	assign := func(field *string, value *string) {
+		if value != nil {
+			*field = *value
+		}
+	}
+	assign(&event.Name, changes.Name)
+	assign(&event.Description, changes.Description)
+	assign(&event.VenueName, changes.VenueName)
+	assign(&event.City, changes.City)
+	assign(&event.Address, changes.Address)
+	assign(&event.ImageURL, changes.ImageURL)
+	assign(&event.Status, changes.Status)
+	if changes.Date != nil {
+		event.Date = *changes.Date
+	}
+	if changes.Price != nil {
+		event.Price = *changes.Price
+	}
+	if changes.Capacity != nil {
+		event.Capacity = *changes.Capacity
+	}
+	if changes.VenueID != nil {
+		event.VenueID = changes.VenueID
+	}
+
+	r.events[id] = event
+	return &event, nil
+}
+
+func (r fakeEventRepository) Delete(ctx context.Context, id uint) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.events, id)
+	return nil
+}
+
+// GetWithFilters supports the status, type, city, search and price filters,
+// sorting by date or price
+func (r fakeEventRepository) GetWithFilters(ctx context.Context, filters repository.EventFilters) (*repository.PaginatedEventsResponse, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if filters.Page < 1 {
+		filters.Page = 1
+	}
+	if filters.Limit < 1 || filters.Limit > 100 {
+		filters.Limit = 20
+	}
+	if filters.Status == "" {
+		filters.Status = "published"
+	}
+
+	var matches []repository.EventWithStats
+	for _, event := range r.events {
+		if event.Status != filters.Status ||
+			(filters.EventType != "" && event.EventType != filters.EventType) ||
+			(filters.City != "" && !strings.EqualFold(event.City, filters.City)) ||
+			(filters.Search != "" && !strings.Contains(strings.ToLower(event.Name), strings.ToLower(filters.Search))) ||
+			(filters.PriceMin != nil && event.Price < *filters.PriceMin) ||
+			(filters.PriceMax != nil && event.Price > *filters.PriceMax) {
+			continue
+		}
+		matches = append(matches, repository.EventWithStats{
+			Event:       event,
+			TotalBooked: int64(r.bookedLocked(event.ID)),
+		})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		less := matches[i].Date.Before(matches[j].Date)
+		if filters.Sort == "price" {
+			less = matches[i].Price < matches[j].Price
+		}
+		if filters.Order == "desc" {
+			return !less
+		}
+		return less
+	})
+
+	total := len(matches)
+	start := min((filters.Page-1)*filters.Limit, total)
+	end := min(start+filters.Limit, total)
+	totalPages := int(math.Ceil(float64(total) / float64(filters.Limit)))
+
+	return &repository.PaginatedEventsResponse{
+		Events:      matches[start:end],
+		Total:       int64(total),
+		Page:        filters.Page,
+		Limit:       filters.Limit,
+		TotalPages:  totalPages,
+		HasNext:     filters.Page < totalPages,
+		HasPrevious: filters.Page > 1,
+	}, nil
+}
+
+func (r fakeEventRepository) AvailableTickets(ctx context.Context, event *models.Event) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return event.Capacity - r.bookedLocked(event.ID), nil
+}
+
+func (r fakeEventRepository) GetTiersWithStats(ctx context.Context, eventIDs []uint) ([]repository.TierWithStats, error) {
+	return nil, nil
+}
+
+// fakeBookingRepository is an in-memory repository.BookingRepository
+type fakeBookingRepository struct{ *memoryStore }
+
+func (r fakeBookingRepository) Create(ctx context.Context, booking *models.Booking) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	event, ok := r.events[booking.EventID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	if event.Capacity-r.bookedLocked(event.ID) < booking.Quantity {
+		return errors.New("not enough tickets available")
+	}
+	if booking.TierID != nil {
+		return errors.New("ticket tier not found")
+	}
+	if len(booking.Seats) > 0 {
+		return errors.New("event does not have reserved seating")
+	}
+
+	booking.ID = r.id()
+	booking.CreatedAt = time.Now()
+	booking.PricePerTicket = event.Price
+	booking.TotalPrice = event.Price * float64(booking.Quantity)
+	booking.Status = "confirmed"
+	r.bookings[booking.ID] = *booking
+	return nil
+}
+
+func (r fakeBookingRepository) GetByID(ctx context.Context, id uint) (*models.Booking, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	booking, ok := r.bookings[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	booking.Event = r.events[booking.EventID]
+	booking.User = r.users[booking.UserID]
+	return &booking, nil
+}
+
+func (r fakeBookingRepository) GetByUser(ctx context.Context, userID uint) ([]models.Booking, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var bookings []models.Booking
+	for _, booking := range r.bookings {
+		if booking.UserID == userID {
+			booking.Event = r.events[booking.EventID]
+			bookings = append(bookings, booking)
+		}
+	}
+	sort.Slice(bookings, func(i, j int) bool { return bookings[i].ID > bookings[j].ID })
+	return bookings, nil
+}
+
+// Cancel frees the tickets; the store has no waitlist, so nobody is offered them
+func (r fakeBookingRepository) Cancel(ctx context.Context, bookingID uint, userID uint, offerTTL time.Duration) ([]models.WaitlistEntry, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	booking, ok := r.bookings[bookingID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if booking.UserID != userID {
+		return nil, errors.New("unauthorized to cancel this booking")
+	}
+	if booking.Status == "cancelled" {
+		return nil, errors.New("booking is already cancelled")
+	}
+
+	booking.Status = "cancelled"
+	r.bookings[bookingID] = booking
+	return nil, nil
+}
+
+func (r fakeBookingRepository) GetEventTicketHolderIDs(ctx context.Context, eventID uint) ([]uint, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	seen := make(map[uint]bool)
+	var userIDs []uint
+	for _, booking := range r.bookings {
+		if booking.EventID == eventID && booking.Status == "confirmed" && !seen[booking.UserID] {
+			seen[booking.UserID] = true
+			userIDs = append(userIDs, booking.UserID)
+		}
+	}
+	return userIDs, nil
+}
+
+// fakeHoldRepository is an in-memory repository.HoldRepository
+type fakeHoldRepository struct{ *memoryStore }
+
+func (r fakeHoldRepository) Create(ctx context.Context, hold *models.Hold, ttl time.Duration) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.events[hold.EventID]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+
+	hold.ID = r.id()
+	hold.Status = "active"
+	hold.ExpiresAt = time.Now().Add(ttl)
+	r.holds[hold.ID] = *hold
+	return nil
+}
+
+func (r fakeHoldRepository) Confirm(ctx context.Context, holdID uint, userID uint) (*models.Booking, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	hold, ok := r.holds[holdID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if hold.UserID != userID {
+		return nil, errors.New("unauthorized to confirm this hold")
+	}
+	if hold.Status != "active" || !hold.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("hold is no longer active")
+	}
+	event, ok := r.events[hold.EventID]
+	if !ok || event.Status == "cancelled" {
+		return nil, errors.New("event is no longer available")
+	}
+
+	booking := models.Booking{
+		UserID:         hold.UserID,
+		EventID:        hold.EventID,
+		Quantity:       hold.Quantity,
+		PricePerTicket: event.Price,
+		TotalPrice:     event.Price * float64(hold.Quantity),
+		Status:         "confirmed",
+	}
+	booking.ID = r.id()
+	booking.CreatedAt = time.Now()
+	r.bookings[booking.ID] = booking
+
+	hold.Status = "confirmed"
+	hold.BookingID = &booking.ID
+	r.holds[hold.ID] = hold
+	return &booking, nil
+}
+
+func (r fakeHoldRepository) Expire(ctx context.Context) ([]uint, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var eventIDs []uint
+	for id, hold := range r.holds {
+		if hold.Status == "active" && !hold.ExpiresAt.After(time.Now()) {
+			hold.Status = "expired"
+			r.holds[id] = hold
+			eventIDs = append(eventIDs, hold.EventID)
+		}
+	}
+	return eventIDs, nil
+}
+
+// fakeUserRepository is an in-memory repository.UserRepository
+type fakeUserRepository struct{ *memoryStore }
+
+func (r fakeUserRepository) Create(ctx context.Context, username, email, password string) (*models.User, error) {
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, user := range r.users {
+		if user.Username == username || user.Email == email {
+			return nil, errors.New("duplicate key value violates unique constraint")
+		}
+	}
+
+	user := models.User{
+		Username: username,
+		Email:    email,
+		Password: hashedPassword,
+		Role:     models.RoleUser,
+	}
+	user.ID = r.id()
+	r.users[user.ID] = user
+	return &user, nil
+}
+
+// findUser returns the first user matching; not finding one is an error, as in the GORM repository
+func (r fakeUserRepository) findUser(match func(models.User) bool) (*models.User, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, user := range r.users {
+		if match(user) {
+			return &user, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (r fakeUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findUser(func(user models.User) bool { return user.Email == email })
+}
+
+func (r fakeUserRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.findUser(func(user models.User) bool { return user.Username == username })
+}
+
+func (r fakeUserRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	return r.findUser(func(user models.User) bool { return user.ID == id })
+}
+
+func (r fakeUserRepository) UpdateRole(ctx context.Context, id uint, role string) (*models.User, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	user.Role = role
+	r.users[id] = user
+	return &user, nil
+}
+
+func (r fakeUserRepository) PromoteAdmins(ctx context.Context, emails []string) (int64, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var promoted int64
+	for id, user := range r.users {
+		for _, email := range emails {
+			if user.Email == email && user.Role != models.RoleAdmin {
+				user.Role = models.RoleAdmin
+				r.users[id] = user
+				promoted++
+			}
+		}
+	}
+	return promoted, nil
+}
+
+// fakeVenueRepository is an in-memory repository.VenueRepository
+type fakeVenueRepository struct{ *memoryStore }
+
+func (r fakeVenueRepository) Create(ctx context.Context, venue *models.Venue) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	venue.ID = r.id()
+	r.venues[venue.ID] = *venue
+	return nil
+}
+
+func (r fakeVenueRepository) GetByID(ctx context.Context, id uint) (*models.Venue, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	venue, ok := r.venues[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &venue, nil
+}
+
+func (r fakeVenueRepository) CountSeats(ctx context.Context, venueID uint) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	count := 0
+	for _, section := range r.venues[venueID].Sections {
+		count += len(section.Seats)
+	}
+	return count, nil
+}
+
+// GetBookedSeatIDs finds no booked seats, since the store's bookings are general admission
+func (r fakeVenueRepository) GetBookedSeatIDs(ctx context.Context, eventID uint) ([]uint, error) {
+	return nil, nil
+}
+
+// fakeTicketRepository is an in-memory repository.TicketRepository
+type fakeTicketRepository struct{ *memoryStore }
+
+func (r fakeTicketRepository) GetByID(ctx context.Context, id uint) (*models.Ticket, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	ticket, ok := r.tickets[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &ticket, nil
+}
+
+func (r fakeTicketRepository) GetForBooking(ctx context.Context, bookingID uint) ([]models.Ticket, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var tickets []models.Ticket
+	for _, ticket := range r.tickets {
+		if ticket.BookingID == bookingID {
+			tickets = append(tickets, ticket)
+		}
+	}
+	sort.Slice(tickets, func(i, j int) bool { return tickets[i].ID < tickets[j].ID })
+	return tickets, nil
+}
+
+func (r fakeTicketRepository) CheckIn(ctx context.Context, code string, eventID uint, scannedBy uint) (*models.Ticket, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for id, ticket := range r.tickets {
+		if ticket.Code != code || ticket.EventID != eventID {
+			continue
+		}
+		if booking, ok := r.bookings[ticket.BookingID]; ok && booking.Status == "cancelled" {
+			return nil, errors.New("ticket booking is cancelled")
+		}
+		if ticket.ScannedAt != nil {
+			return nil, errors.New("ticket already scanned")
+		}
+
+		now := time.Now()
+		ticket.ScannedAt = &now
+		ticket.ScannedBy = &scannedBy
+		r.tickets[id] = ticket
+		return &ticket, nil
+	}
+	return nil, errors.New("ticket not found")
+}
+
+// The fakes must keep satisfying the interfaces the handlers depend on
+var (
+	_ repository.EventRepository   = fakeEventRepository{}
+	_ repository.BookingRepository = fakeBookingRepository{}
+	_ repository.UserRepository    = fakeUserRepository{}
+	_ repository.VenueRepository   = fakeVenueRepository{}
+	_ repository.TicketRepository  = fakeTicketRepository{}
+)
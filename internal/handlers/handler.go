@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"github.com/alexs/golang_test/internal/config"
+	"github.com/alexs/golang_test/internal/mail"
+	"github.com/alexs/golang_test/internal/metrics"
+	"github.com/alexs/golang_test/internal/repository"
+	"github.com/alexs/golang_test/internal/websocket"
+)
+
+// Handler serves the API routes. Its repositories, hub, mail sender,
+// configuration and metrics are injected, so the handlers can run against in-memory fakes in tests.
+type Handler struct {
+	events        repository.EventRepository
+	bookings      repository.BookingRepository
+	users         repository.UserRepository
+	holds         repository.HoldRepository
+	waitlist      repository.WaitlistRepository
+	tickets       repository.TicketRepository
+	venues        repository.VenueRepository
+	sales         repository.SalesRepository
+	sessions      repository.SessionRepository
+	accountTokens repository.AccountTokenRepository
+
+	// hub broadcasts live updates; nil disables them
+	hub *websocket.Hub
+
+	// mailer sends password reset and verification emails
+	mailer mail.Sender
+
+	// config holds the token lifetimes, hold TTL and links the handlers use
+	config *config.Config
+
+	// metrics counts bookings and tickets sold
+	metrics *metrics.Metrics
+}
+
+// Dependencies are the collaborators a Handler is built from
+type Dependencies struct {
+	Events        repository.EventRepository
+	Bookings      repository.BookingRepository
+	Users         repository.UserRepository
+	Holds         repository.HoldRepository
+	Waitlist      repository.WaitlistRepository
+	Tickets       repository.TicketRepository
+	Venues        repository.VenueRepository
+	Sales         repository.SalesRepository
+	Sessions      repository.SessionRepository
+	AccountTokens repository.AccountTokenRepository
+
+	// Hub is optional; without it there are no live updates
+	Hub *websocket.Hub
+
+	// Mailer is optional; emails are logged by default
+	Mailer mail.Sender
+
+	// Config is optional; config.Default is used without it
+	Config *config.Config
+
+	// Metrics is optional; without it bookings are counted but never exposed
+	Metrics *metrics.Metrics
+}
+
+// New creates a Handler. A given hub is set up to load sales figures and
+// refresh availability through the handler's repositories.
+func New(deps Dependencies) *Handler {
+	h := &Handler{
+		events:        deps.Events,
+		bookings:      deps.Bookings,
+		users:         deps.Users,
+		holds:         deps.Holds,
+		waitlist:      deps.Waitlist,
+		tickets:       deps.Tickets,
+		venues:        deps.Venues,
+		sales:         deps.Sales,
+		sessions:      deps.Sessions,
+		accountTokens: deps.AccountTokens,
+		hub:           deps.Hub,
+		mailer:        deps.Mailer,
+		config:        deps.Config,
+		metrics:       deps.Metrics,
+	}
+
+	if h.mailer == nil {
+		h.mailer = mail.NewLogSender()
+	}
+	if h.config == nil {
+		h.config = config.Default()
+	}
+	if h.metrics == nil {
+		h.metrics = metrics.New()
+	}
+
+	if h.hub != nil {
+		h.hub.SetSalesSource(h.organizerSales)
+		h.hub.SetAvailabilityRefresher(h.config.WebSocket.CoalesceWindow, h.refreshAvailability)
+	}
+
+	return h
+}
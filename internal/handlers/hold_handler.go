@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexs/golang_test/internal/middleware"
+	"github.com/alexs/golang_test/internal/models"
+	"github.com/alexs/golang_test/internal/utils"
+	"github.com/alexs/golang_test/internal/websocket"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type CreateHoldRequest struct {
+	TierID   *uint `json:"tier_id,omitempty"`
+	Quantity int   `json:"quantity"`
+}
+
+// CreateHold reserves tickets for the authenticated user for the configured hold TTL
+func (h *Handler) CreateHold(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r)
+	if claims == nil {
+		utils.ErrorResponse(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	eventID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	var req CreateHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Quantity <= 0 {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Valid quantity is required")
+		return
+	}
+
+	hold := models.Hold{
+		UserID:   claims.UserID,
+		EventID:  uint(eventID),
+		TierID:   req.TierID,
+		Quantity: req.Quantity,
+	}
+
+	if err := h.holds.Create(r.Context(), &hold, h.config.Holds.TTL); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(w, http.StatusNotFound, "Event not found")
+			return
+		}
+		if strings.Contains(err.Error(), "not enough tickets available") {
+			utils.ErrorResponse(w, http.StatusBadRequest, "Not enough tickets available")
+			return
+		}
+		if msg, ok := bookingErrorMessage(err); ok {
+			utils.ErrorResponse(w, http.StatusBadRequest, msg)
+			return
+		}
+		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to create hold")
+		return
+	}
+
+	// Broadcast availability update via WebSocket
+	h.broadcastUpdate(r.Context(), hold.EventID)
+
+	utils.SuccessResponse(w, http.StatusCreated, hold)
+}
+
+// ConfirmHold converts the authenticated user's hold into a confirmed booking
+func (h *Handler) ConfirmHold(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r)
+	if claims == nil {
+		utils.ErrorResponse(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid hold ID")
+		return
+	}
+
+	booking, err := h.holds.Confirm(r.Context(), uint(id), claims.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(w, http.StatusNotFound, "Hold not found")
+			return
+		}
+		if strings.Contains(err.Error(), "unauthorized to confirm this hold") {
+			utils.ErrorResponse(w, http.StatusForbidden, "You are not authorized to confirm this hold")
+			return
+		}
+		if strings.Contains(err.Error(), "hold is no longer active") {
+			utils.ErrorResponse(w, http.StatusGone, "Hold has expired or was already used")
+			return
+		}
+		if strings.Contains(err.Error(), "event is no longer available") {
+			utils.ErrorResponse(w, http.StatusConflict, "Event has been cancelled or removed")
+			return
+		}
+		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to confirm hold")
+		return
+	}
+
+	// Fetch complete booking with event details
+	completeBooking, err := h.bookings.GetByID(r.Context(), booking.ID)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, "Booking created but failed to fetch details")
+		return
+	}
+	h.metrics.BookingCreated(completeBooking.Event.EventType, completeBooking.Quantity)
+
+	h.notifyBooking(r.Context(), completeBooking, websocket.MessageTypeBookingConfirmed)
+
+	// Held tickets were already unavailable, but the organizer's sales changed
+	h.broadcastUpdate(r.Context(), completeBooking.EventID)
+
+	utils.SuccessResponse(w, http.StatusCreated, completeBooking)
+}
+
+// holdReaperPassTimeout bounds a single reaper pass
+const holdReaperPassTimeout = 30 * time.Second
+
+// RunHoldReaper periodically expires stale holds, offers the released tickets
+// to the waitlist and broadcasts the new availability. It returns when ctx is
+// done, after finishing the pass in progress.
+func (h *Handler) RunHoldReaper(ctx context.Context, interval time.Duration) {
+	slog.InfoContext(ctx, "Hold reaper started", "interval", interval.String())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "Hold reaper stopped")
+			return
+		case <-ticker.C:
+		}
+
+		// Stopping doesn't cancel a pass halfway, between expiring holds and
+		// offering their tickets to the waitlist, so it runs on a detached context
+		pass, cancel := context.WithTimeout(context.WithoutCancel(ctx), holdReaperPassTimeout)
+		h.reapHolds(pass)
+		cancel()
+	}
+}
+
+// reapHolds runs one reaper pass
+func (h *Handler) reapHolds(ctx context.Context) {
+	eventIDs, err := h.holds.Expire(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to expire holds", "error", err)
+		return
+	}
+
+	for _, eventID := range eventIDs {
+		offered, err := h.waitlist.Promote(ctx, eventID, h.config.Holds.TTL)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to promote waitlist", "event_id", eventID, "error", err)
+		}
+		h.notifyWaitlistOffers(ctx, offered)
+
+		h.broadcastUpdate(ctx, eventID)
+	}
+
+	if len(eventIDs) > 0 {
+		slog.InfoContext(ctx, "Expired holds released tickets", "events", len(eventIDs))
+	}
+}
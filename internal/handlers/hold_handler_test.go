@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alexs/golang_test/internal/middleware"
+	"github.com/alexs/golang_test/internal/models"
+	"github.com/alexs/golang_test/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCreateHold_Validation tests hold creation validation without database
+func TestCreateHold_Validation(t *testing.T) {
+	h, _ := newTestHandler()
+
+	tests := []struct {
+		name           string
+		id             string
+		body           string
+		withAuth       bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "No User Context",
+			id:             "1",
+			body:           `{"quantity": 2}`,
+			withAuth:       false,
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "User not found in context",
+		},
+		{
+			name:           "Non-numeric Event ID",
+			id:             "abc",
+			body:           `{"quantity": 2}`,
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid event ID",
+		},
+		{
+			name:           "Invalid JSON",
+			id:             "1",
+			body:           `{not valid json`,
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid request body",
+		},
+		{
+			name:           "Zero Quantity",
+			id:             "1",
+			body:           `{"quantity": 0}`,
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Valid quantity is required",
+		},
+		{
+			name:           "Negative Quantity",
+			id:             "1",
+			body:           `{"quantity": -3}`,
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Valid quantity is required",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/events/"+tc.id+"/holds", bytes.NewBufferString(tc.body))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			// Add user claims to context if needed
+			if tc.withAuth {
+				claims := &utils.Claims{
+					UserID:   1,
+					Username: "testuser",
+					Email:    "test@example.com",
+				}
+				ctx := context.WithValue(req.Context(), middleware.UserContextKey, claims)
+				req = req.WithContext(ctx)
+			}
+
+			// Simulate chi URL params
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tc.id)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(h.CreateHold)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Status code mismatch")
+			assert.Contains(t, rr.Body.String(), tc.expectedBody, "Response body mismatch")
+		})
+	}
+}
+
+// TestConfirmHold_InvalidID tests confirming a hold with invalid ID
+func TestConfirmHold_InvalidID(t *testing.T) {
+	h, _ := newTestHandler()
+
+	tests := []struct {
+		name           string
+		id             string
+		withAuth       bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Non-numeric ID",
+			id:             "abc",
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid hold ID",
+		},
+		{
+			name:           "Negative ID",
+			id:             "-1",
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid hold ID",
+		},
+		{
+			name:           "No Auth",
+			id:             "1",
+			withAuth:       false,
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "User not found in context",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/holds/"+tc.id+"/confirm", nil)
+			assert.NoError(t, err)
+
+			// Add user claims to context if needed
+			if tc.withAuth {
+				claims := &utils.Claims{
+					UserID:   1,
+					Username: "testuser",
+					Email:    "test@example.com",
+				}
+				ctx := context.WithValue(req.Context(), middleware.UserContextKey, claims)
+				req = req.WithContext(ctx)
+			}
+
+			// Simulate chi URL params
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tc.id)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(h.ConfirmHold)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Status code mismatch")
+			assert.Contains(t, rr.Body.String(), tc.expectedBody, "Response body mismatch")
+		})
+	}
+}
+
+// TestConfirmHold_EventGone tests that a hold can't be confirmed once its event is cancelled or deleted
+func TestConfirmHold_EventGone(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         string
+		deleted        bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Published Event",
+			status:         "published",
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `"status":"confirmed"`,
+		},
+		{
+			name:           "Cancelled Event",
+			status:         "cancelled",
+			expectedStatus: http.StatusConflict,
+			expectedBody:   "Event has been cancelled or removed",
+		},
+		{
+			name:           "Deleted Event",
+			status:         "published",
+			deleted:        true,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   "Event has been cancelled or removed",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h, store := newTestHandler()
+			event := store.addEvent(models.Event{Name: "Jazz Night", Status: tc.status, OrganizerID: 2, Capacity: 10, Price: 25})
+			hold := store.addHold(models.Hold{UserID: 1, EventID: event.ID, Quantity: 2, Status: "active", ExpiresAt: time.Now().Add(time.Minute)})
+			if tc.deleted {
+				require.NoError(t, h.events.Delete(context.Background(), event.ID))
+			}
+
+			id := strconv.FormatUint(uint64(hold.ID), 10)
+			req := httptest.NewRequest("POST", "/holds/"+id+"/confirm", nil)
+			claims := &utils.Claims{UserID: 1, Username: "testuser", Email: "test@example.com"}
+			ctx := context.WithValue(req.Context(), middleware.UserContextKey, claims)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", id)
+			req = req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			h.ConfirmHold(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Status code mismatch")
+			assert.Contains(t, rr.Body.String(), tc.expectedBody, "Response body mismatch")
+		})
+	}
+}
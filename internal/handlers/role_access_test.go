@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexs/golang_test/internal/middleware"
+	"github.com/alexs/golang_test/internal/models"
+	"github.com/alexs/golang_test/internal/utils"
+	"github.com/alexs/golang_test/internal/websocket"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCanManageEvent tests the organizer ownership check and the admin override
+func TestCanManageEvent(t *testing.T) {
+	event := &models.Event{OrganizerID: 7}
+
+	tests := []struct {
+		name     string
+		user     *utils.Claims
+		expected bool
+	}{
+		{
+			name:     "Owning Organizer",
+			user:     &utils.Claims{UserID: 7, Role: models.RoleOrganizer},
+			expected: true,
+		},
+		{
+			name:     "Other Organizer",
+			user:     &utils.Claims{UserID: 8, Role: models.RoleOrganizer},
+			expected: false,
+		},
+		{
+			name:     "Admin",
+			user:     &utils.Claims{UserID: 9, Role: models.RoleAdmin},
+			expected: true,
+		},
+		{
+			name:     "Regular User",
+			user:     &utils.Claims{UserID: 10, Role: models.RoleUser},
+			expected: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, canManageEvent(tc.user, event))
+		})
+	}
+}
+
+// TestUpdateUserRole_Validation tests role assignment validation without database
+func TestUpdateUserRole_Validation(t *testing.T) {
+	h, _ := newTestHandler()
+
+	tests := []struct {
+		name           string
+		id             string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Invalid JSON",
+			id:             "2",
+			body:           `{not valid json`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid request body",
+		},
+		{
+			name:           "Unknown Role",
+			id:             "2",
+			body:           `{"role": "superuser"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Role must be one of",
+		},
+		{
+			name:           "Empty Role",
+			id:             "2",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Role must be one of",
+		},
+		{
+			name:           "Self Demotion",
+			id:             "1",
+			body:           `{"role": "user"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "You cannot remove your own admin role",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("PUT", "/admin/users/"+tc.id+"/role", bytes.NewBufferString(tc.body))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			claims := &utils.Claims{
+				UserID:   1,
+				Username: "admin",
+				Email:    "admin@example.com",
+				Role:     models.RoleAdmin,
+			}
+			ctx := context.WithValue(req.Context(), middleware.UserContextKey, claims)
+
+			// Simulate chi URL params
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tc.id)
+			req = req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(h.UpdateUserRole)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Status code mismatch")
+			assert.Contains(t, rr.Body.String(), tc.expectedBody, "Response body mismatch")
+		})
+	}
+}
+
+// TestGetWebSocketStats tests the admin view of hub counters
+func TestGetWebSocketStats(t *testing.T) {
+	h, _ := newTestHandler()
+
+	rr := httptest.NewRecorder()
+	h.GetWebSocketStats(rr, httptest.NewRequest("GET", "/admin/websocket/stats", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	h.hub = websocket.NewHub()
+	rr = httptest.NewRecorder()
+	h.GetWebSocketStats(rr, httptest.NewRequest("GET", "/admin/websocket/stats", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"dropped_messages"`)
+	assert.Contains(t, rr.Body.String(), `"coalesced_updates":0`)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/alexs/golang_test/internal/repository"
+	"github.com/alexs/golang_test/internal/websocket"
+)
+
+// salesRateWindow is the period the dashboard's per-minute booking and cancellation rates cover
+const salesRateWindow = time.Minute
+
+// salesUpdate converts an event's sales figures into a dashboard message
+func salesUpdate(sales repository.EventSales) websocket.SalesUpdate {
+	return websocket.SalesUpdate{
+		EventID:                sales.EventID,
+		EventName:              sales.EventName,
+		TicketsSold:            sales.TicketsSold,
+		Revenue:                sales.Revenue,
+		RemainingCapacity:      sales.RemainingCapacity(),
+		Capacity:               sales.Capacity,
+		BookingsPerMinute:      sales.RecentBookings,
+		CancellationsPerMinute: sales.RecentCancellations,
+		LastUpdated:            time.Now().Format(time.RFC3339),
+	}
+}
+
+// organizerSales loads the current sales figures of every event an organizer owns
+func (h *Handler) organizerSales(ctx context.Context, organizerID uint) ([]websocket.SalesUpdate, error) {
+	sales, err := h.sales.GetOrganizerSales(ctx, organizerID, salesRateWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make([]websocket.SalesUpdate, len(sales))
+	for i, s := range sales {
+		updates[i] = salesUpdate(s)
+	}
+	return updates, nil
+}
+
+// salesRefreshPassTimeout bounds a single sales refresh pass
+const salesRefreshPassTimeout = 30 * time.Second
+
+// RunSalesRefresher periodically recomputes the figures of every open sales
+// dashboard, since their per-minute rates go stale between bookings. It
+// returns when ctx is done.
+func (h *Handler) RunSalesRefresher(ctx context.Context, interval time.Duration) {
+	if h.hub == nil {
+		return
+	}
+	slog.InfoContext(ctx, "Sales refresher started", "interval", interval.String())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "Sales refresher stopped")
+			return
+		case <-ticker.C:
+		}
+
+		pass, cancel := context.WithTimeout(ctx, salesRefreshPassTimeout)
+		h.hub.RefreshSales(pass)
+		cancel()
+	}
+}
+
+// notifySales pushes an event's latest sales figures to its organizer's dashboard
+func (h *Handler) notifySales(ctx context.Context, eventID uint) {
+	if h.hub == nil {
+		return
+	}
+
+	sales, err := h.sales.GetEventSales(ctx, eventID, salesRateWindow)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load sales", "event_id", eventID, "error", err)
+		return
+	}
+
+	h.hub.SendSalesUpdate(ctx, sales.OrganizerID, salesUpdate(*sales))
+}
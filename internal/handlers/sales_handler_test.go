@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/alexs/golang_test/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSalesUpdate tests converting sales figures into a dashboard message
+func TestSalesUpdate(t *testing.T) {
+	update := salesUpdate(repository.EventSales{
+		EventID:             4,
+		EventName:           "Jazz Night",
+		OrganizerID:         2,
+		Capacity:            100,
+		TicketsSold:         60,
+		TicketsHeld:         5,
+		Revenue:             1800,
+		RecentBookings:      3,
+		RecentCancellations: 1,
+	})
+
+	assert.Equal(t, uint(4), update.EventID)
+	assert.Equal(t, "Jazz Night", update.EventName)
+	assert.Equal(t, int64(60), update.TicketsSold)
+	assert.Equal(t, 1800.0, update.Revenue)
+	assert.Equal(t, 35, update.RemainingCapacity, "Held tickets aren't available either")
+	assert.Equal(t, int64(3), update.BookingsPerMinute)
+	assert.Equal(t, int64(1), update.CancellationsPerMinute)
+	assert.NotEmpty(t, update.LastUpdated)
+}
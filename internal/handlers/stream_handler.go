@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexs/golang_test/internal/logging"
+	"github.com/alexs/golang_test/internal/models"
+	"github.com/alexs/golang_test/internal/utils"
+	"github.com/alexs/golang_test/internal/websocket"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// sseHeartbeatInterval keeps idle streams alive through proxies that close quiet connections
+const sseHeartbeatInterval = 15 * time.Second
+
+// streamEnvelope holds the fields of a hub message needed to frame it as an SSE event
+type streamEnvelope struct {
+	Type websocket.MessageType `json:"type"`
+	Seq  uint64                `json:"seq"`
+}
+
+// writeSSE writes a single Server-Sent Event. Events without an id leave the
+// client's Last-Event-ID unchanged.
+func writeSSE(w io.Writer, id string, eventType websocket.MessageType, data []byte) error {
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, data)
+	return err
+}
+
+// streamEventID combines the hub epoch and a sequence number into an SSE event id
+func streamEventID(epoch string, seq uint64) string {
+	return epoch + ":" + strconv.FormatUint(seq, 10)
+}
+
+// parseStreamEventID splits a Last-Event-ID header into the hub epoch and sequence number
+func parseStreamEventID(id string) (string, uint64, bool) {
+	epoch, seqStr, found := strings.Cut(id, ":")
+	if !found {
+		return "", 0, false
+	}
+
+	seq, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return epoch, seq, true
+}
+
+// authorizeStream checks access to an event's stream. Published events are public;
+// drafts and cancelled events are only streamed to their organizer or an admin,
+// who pass their access token as a query parameter since EventSource can't set headers.
+func authorizeStream(r *http.Request, event *models.Event) bool {
+	token := r.URL.Query().Get("token")
+
+	if token != "" {
+		claims, err := utils.ValidateAccessToken(r.Context(), token)
+		if err != nil {
+			return false
+		}
+		logging.SetUserID(r.Context(), claims.UserID)
+		return event.Status == "published" || canManageEvent(claims, event)
+	}
+
+	return event.Status == "published"
+}
+
+// StreamEvent streams an event's live updates as Server-Sent Events, for clients
+// that can't open a WebSocket. Reconnecting clients that send Last-Event-ID get
+// the updates they missed replayed; every other stream starts with the current availability.
+func (h *Handler) StreamEvent(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	if h.hub == nil {
+		utils.ErrorResponse(w, http.StatusServiceUnavailable, "Live updates are unavailable")
+		return
+	}
+
+	event, err := h.events.GetByID(r.Context(), uint(id))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	if !authorizeStream(r, event) {
+		utils.ErrorResponse(w, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	controller := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // disable proxy buffering
+	w.WriteHeader(http.StatusOK)
+
+	// The stream carries only the event's broadcasts, so its client belongs to
+	// no user and never receives messages sent to the viewer's account
+	client := h.hub.NewStreamClient("sse-"+uuid.New().String(), 0)
+	defer h.hub.Unregister(client)
+
+	resumed := false
+	if epoch, seq, ok := parseStreamEventID(r.Header.Get("Last-Event-ID")); ok {
+		resumed = h.hub.Resume(client, event.ID, epoch, seq)
+		slog.InfoContext(r.Context(), "SSE client resuming event", "client_id", client.ID, "event_id", event.ID, "after_seq", seq, "replayed", resumed)
+	}
+
+	if !resumed {
+		h.hub.SubscribeToEvent(client, event.ID)
+
+		// Start with a snapshot so the client doesn't depend on what it missed
+		available, tiers := h.eventAvailability(r.Context(), event)
+		now := time.Now()
+		snapshot, err := json.Marshal(&websocket.Message{
+			Type:      websocket.MessageTypeAvailabilityUpdate,
+			EventID:   &event.ID,
+			Timestamp: now,
+			Data: websocket.AvailabilityUpdate{
+				EventID:          event.ID,
+				AvailableTickets: available,
+				Capacity:         event.Capacity,
+				Tiers:            tiers,
+				LastUpdated:      now.Format(time.RFC3339),
+			},
+		})
+		if err != nil || writeSSE(w, "", websocket.MessageTypeAvailabilityUpdate, snapshot) != nil {
+			return
+		}
+	}
+	controller.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case data, ok := <-client.Messages():
+			if !ok {
+				// The hub dropped this client
+				return
+			}
+
+			var envelope streamEnvelope
+			if err := json.Unmarshal(data, &envelope); err != nil || envelope.Type == websocket.MessageTypeConnectionAck {
+				continue
+			}
+
+			if err := writeSSE(w, streamEventID(h.hub.Epoch(), envelope.Seq), envelope.Type, data); err != nil {
+				return
+			}
+			controller.Flush()
+
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			controller.Flush()
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexs/golang_test/internal/config"
+	"github.com/alexs/golang_test/internal/models"
+	"github.com/alexs/golang_test/internal/utils"
+	"github.com/alexs/golang_test/internal/websocket"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStreamEvent_Validation tests SSE stream validation without database
+func TestStreamEvent_Validation(t *testing.T) {
+	h, _ := newTestHandler()
+
+	tests := []struct {
+		name           string
+		id             string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Non-numeric Event ID",
+			id:             "abc",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid event ID",
+		},
+		{
+			name:           "Hub Not Configured",
+			id:             "1",
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   "Live updates are unavailable",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/events/"+tc.id+"/stream", nil)
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tc.id)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			h.StreamEvent(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			assert.Contains(t, rr.Body.String(), tc.expectedBody)
+		})
+	}
+}
+
+// TestAuthorizeStream tests who may stream published and unpublished events
+func TestAuthorizeStream(t *testing.T) {
+	// Configure token signing for tests
+	utils.ConfigureJWT(config.JWTConfig{Secret: "test-secret-key-for-testing", AccessTokenTTL: 24 * time.Hour})
+
+	// Every token in this test belongs to an active session
+	originalChecker := utils.SessionChecker
+	defer func() { utils.SessionChecker = originalChecker }()
+	utils.SessionChecker = func(ctx context.Context, sessionID uint) (bool, error) { return true, nil }
+
+	ownerToken, err := utils.GenerateJWT(7, "organizer", "organizer@example.com", models.RoleOrganizer, 1)
+	require.NoError(t, err)
+	userToken, err := utils.GenerateJWT(10, "user", "user@example.com", models.RoleUser, 2)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		status     string
+		token      string
+		expectedOK bool
+	}{
+		{
+			name:       "Published Without Token",
+			status:     "published",
+			expectedOK: true,
+		},
+		{
+			name:       "Published With Token",
+			status:     "published",
+			token:      userToken,
+			expectedOK: true,
+		},
+		{
+			name:       "Published With Invalid Token",
+			status:     "published",
+			token:      "not-a-token",
+			expectedOK: false,
+		},
+		{
+			name:       "Draft Without Token",
+			status:     "draft",
+			expectedOK: false,
+		},
+		{
+			name:       "Draft With Other User",
+			status:     "draft",
+			token:      userToken,
+			expectedOK: false,
+		},
+		{
+			name:       "Draft With Owner",
+			status:     "draft",
+			token:      ownerToken,
+			expectedOK: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			event := &models.Event{OrganizerID: 7, Status: tc.status}
+
+			req := httptest.NewRequest(http.MethodGet, "/events/1/stream?token="+tc.token, nil)
+			assert.Equal(t, tc.expectedOK, authorizeStream(req, event))
+		})
+	}
+}
+
+// TestParseStreamEventID tests parsing Last-Event-ID headers
+func TestParseStreamEventID(t *testing.T) {
+	epoch, seq, ok := parseStreamEventID(streamEventID("3f2a-epoch", 42))
+	assert.True(t, ok)
+	assert.Equal(t, "3f2a-epoch", epoch)
+	assert.Equal(t, uint64(42), seq)
+
+	for _, id := range []string{"", "1712345678", "epoch:", "epoch:abc"} {
+		_, _, ok := parseStreamEventID(id)
+		assert.False(t, ok, "%q should not parse", id)
+	}
+}
+
+// sseEvent is one parsed Server-Sent Event
+type sseEvent struct {
+	id        string
+	eventType string
+	data      string
+}
+
+// newStreamServer serves StreamEvent backed by an in-memory store and a running hub
+func newStreamServer(t *testing.T) (*httptest.Server, *memoryStore, *websocket.Hub) {
+	store := newMemoryStore()
+	hub := websocket.NewHub()
+	go hub.Run()
+
+	h := New(Dependencies{
+		Events:   fakeEventRepository{store},
+		Bookings: fakeBookingRepository{store},
+		Users:    fakeUserRepository{store},
+		Hub:      hub,
+	})
+
+	r := chi.NewRouter()
+	r.Get("/events/{id}/stream", h.StreamEvent)
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		hub.Shutdown(ctx)
+	})
+
+	return server, store, hub
+}
+
+// openStream starts streaming path with the given Last-Event-ID, if any
+func openStream(t *testing.T, server *httptest.Server, path, lastEventID string) *bufio.Reader {
+	req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	return bufio.NewReader(resp.Body)
+}
+
+// readSSE reads the next event from a stream, skipping heartbeats
+func readSSE(t *testing.T, stream *bufio.Reader) sseEvent {
+	var event sseEvent
+	for {
+		line, err := stream.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case line == "" && event.eventType != "":
+			return event
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// TestStreamEvent_ResumeFallback tests that a stream that can't be replayed
+// starts from a snapshot and still gets live updates
+func TestStreamEvent_ResumeFallback(t *testing.T) {
+	server, store, hub := newStreamServer(t)
+	event := store.addEvent(models.Event{Name: "Jazz Night", Status: "published", Capacity: 10, Date: time.Now().Add(24 * time.Hour)})
+
+	stream := openStream(t, server, fmt.Sprintf("/events/%d/stream", event.ID), streamEventID("previous-process", 5))
+
+	snapshot := readSSE(t, stream)
+	assert.Equal(t, string(websocket.MessageTypeAvailabilityUpdate), snapshot.eventType)
+	assert.Empty(t, snapshot.id, "Snapshots aren't numbered broadcasts")
+
+	hub.BroadcastAvailabilityUpdate(context.Background(), websocket.EventInfo{ID: event.ID}, 3, 10, nil)
+	live := readSSE(t, stream)
+	assert.Equal(t, string(websocket.MessageTypeAvailabilityUpdate), live.eventType)
+	assert.True(t, strings.HasPrefix(live.id, hub.Epoch()+":"))
+	assert.Contains(t, live.data, `"available_tickets":3`)
+}
+
+// TestStreamEvent_NoPrivateMessages tests that streams opened with a token don't
+// receive the messages sent to the viewer's account
+func TestStreamEvent_NoPrivateMessages(t *testing.T) {
+	utils.ConfigureJWT(config.JWTConfig{Secret: "test-secret-key-for-testing", AccessTokenTTL: 24 * time.Hour})
+
+	// Every token in this test belongs to an active session
+	originalChecker := utils.SessionChecker
+	defer func() { utils.SessionChecker = originalChecker }()
+	utils.SessionChecker = func(ctx context.Context, sessionID uint) (bool, error) { return true, nil }
+
+	token, err := utils.GenerateJWT(10, "user", "user@example.com", models.RoleUser, 1)
+	require.NoError(t, err)
+
+	server, store, hub := newStreamServer(t)
+	event := store.addEvent(models.Event{Name: "Jazz Night", Status: "published", Capacity: 10, Date: time.Now().Add(24 * time.Hour)})
+
+	stream := openStream(t, server, fmt.Sprintf("/events/%d/stream?token=%s", event.ID, token), "")
+	readSSE(t, stream)
+
+	hub.SendToUser(context.Background(), 10, &websocket.Message{Type: websocket.MessageTypeBookingConfirmed, EventID: &event.ID})
+	hub.BroadcastAvailabilityUpdate(context.Background(), websocket.EventInfo{ID: event.ID}, 3, 10, nil)
+
+	next := readSSE(t, stream)
+	assert.Equal(t, string(websocket.MessageTypeAvailabilityUpdate), next.eventType, "Private messages must not reach the stream")
+}
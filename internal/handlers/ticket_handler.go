@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/alexs/golang_test/internal/middleware"
+	"github.com/alexs/golang_test/internal/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultQRCodeSize = 256
+	maxQRCodeSize     = 1024
+)
+
+type CheckInRequest struct {
+	Code string `json:"code"`
+}
+
+// GetBookingTickets returns the signed tickets of one of the authenticated user's bookings
+func (h *Handler) GetBookingTickets(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r)
+	if claims == nil {
+		utils.ErrorResponse(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid booking ID")
+		return
+	}
+
+	booking, err := h.bookings.GetByID(r.Context(), uint(id))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, "Booking not found")
+		return
+	}
+
+	if booking.UserID != claims.UserID {
+		utils.ErrorResponse(w, http.StatusForbidden, "You are not authorized to view these tickets")
+		return
+	}
+
+	tickets, err := h.tickets.GetForBooking(r.Context(), booking.ID)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch tickets")
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, tickets)
+}
+
+// GetTicketQRCode renders a ticket's signed code as a PNG (default) or SVG QR code
+func (h *Handler) GetTicketQRCode(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r)
+	if claims == nil {
+		utils.ErrorResponse(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid ticket ID")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "png"
+	}
+	if format != "png" && format != "svg" {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid format. Use png or svg")
+		return
+	}
+
+	size := defaultQRCodeSize
+	if sizeStr := r.URL.Query().Get("size"); sizeStr != "" {
+		size, err = strconv.Atoi(sizeStr)
+		if err != nil || size < 64 || size > maxQRCodeSize {
+			utils.ErrorResponse(w, http.StatusBadRequest, "Invalid size. Must be between 64 and 1024")
+			return
+		}
+	}
+
+	ticket, err := h.tickets.GetByID(r.Context(), uint(id))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, "Ticket not found")
+		return
+	}
+
+	booking, err := h.bookings.GetByID(r.Context(), ticket.BookingID)
+	if err != nil || booking.UserID != claims.UserID {
+		utils.ErrorResponse(w, http.StatusForbidden, "You are not authorized to view this ticket")
+		return
+	}
+
+	if booking.Status != "confirmed" {
+		utils.ErrorResponse(w, http.StatusConflict, "Booking is cancelled")
+		return
+	}
+
+	var image []byte
+	if format == "svg" {
+		image, err = utils.RenderQRCodeSVG(ticket.Code)
+		w.Header().Set("Content-Type", "image/svg+xml")
+	} else {
+		image, err = utils.RenderQRCodePNG(ticket.Code, size)
+		w.Header().Set("Content-Type", "image/png")
+	}
+	if err != nil {
+		w.Header().Del("Content-Type")
+		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to render QR code")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(image)
+}
+
+// CheckInTicket verifies a scanned ticket code at the door of an event the
+// authenticated user organizes; admins can check in at any event
+func (h *Handler) CheckInTicket(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	eventID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	user := middleware.GetUserFromContext(r)
+	if user == nil {
+		utils.ErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CheckInRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Code == "" {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Ticket code is required")
+		return
+	}
+
+	// Reject forged codes before touching the database
+	ticketEventID, _, err := utils.VerifyTicketCode(req.Code)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid ticket code")
+		return
+	}
+
+	if ticketEventID != uint(eventID) {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Ticket is for a different event")
+		return
+	}
+
+	event, err := h.events.GetByID(r.Context(), uint(eventID))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	if !canManageEvent(user, event) {
+		utils.ErrorResponse(w, http.StatusForbidden, "You are not authorized to check in tickets for this event")
+		return
+	}
+
+	ticket, err := h.tickets.CheckIn(r.Context(), req.Code, event.ID, user.UserID)
+	if err != nil {
+		if strings.Contains(err.Error(), "ticket not found") {
+			utils.ErrorResponse(w, http.StatusNotFound, "Ticket not found")
+			return
+		}
+		if strings.Contains(err.Error(), "ticket booking is cancelled") {
+			utils.ErrorResponse(w, http.StatusConflict, "Ticket belongs to a cancelled booking")
+			return
+		}
+		if strings.Contains(err.Error(), "ticket already scanned") {
+			utils.ErrorResponse(w, http.StatusConflict, "Ticket has already been scanned")
+			return
+		}
+		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to check in ticket")
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, ticket)
+}
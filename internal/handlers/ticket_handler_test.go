@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alexs/golang_test/internal/config"
+	"github.com/alexs/golang_test/internal/middleware"
+	"github.com/alexs/golang_test/internal/models"
+	"github.com/alexs/golang_test/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCheckInTicket_Validation tests ticket check-in validation without database
+func TestCheckInTicket_Validation(t *testing.T) {
+	h, _ := newTestHandler()
+
+	// Configure token signing for signing test codes
+	utils.ConfigureJWT(config.JWTConfig{Secret: "test-secret-key-for-testing", AccessTokenTTL: 24 * time.Hour})
+
+	otherEventCode, err := utils.GenerateTicketCode(2, 1)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		id             string
+		body           string
+		withAuth       bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Non-numeric Event ID",
+			id:             "abc",
+			body:           `{"code": "x"}`,
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid event ID",
+		},
+		{
+			name:           "No User Context",
+			id:             "1",
+			body:           `{"code": "x"}`,
+			withAuth:       false,
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "Unauthorized",
+		},
+		{
+			name:           "Invalid JSON",
+			id:             "1",
+			body:           `{not valid json`,
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid request body",
+		},
+		{
+			name:           "Missing Code",
+			id:             "1",
+			body:           `{}`,
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Ticket code is required",
+		},
+		{
+			name:           "Forged Code",
+			id:             "1",
+			body:           `{"code": "1.1.abcdef.forged"}`,
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid ticket code",
+		},
+		{
+			name:           "Code For Another Event",
+			id:             "1",
+			body:           `{"code": "` + otherEventCode + `"}`,
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Ticket is for a different event",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/events/"+tc.id+"/checkin", bytes.NewBufferString(tc.body))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			// Add user claims to context if needed
+			if tc.withAuth {
+				claims := &utils.Claims{
+					UserID:   1,
+					Username: "testuser",
+					Email:    "test@example.com",
+				}
+				ctx := context.WithValue(req.Context(), middleware.UserContextKey, claims)
+				req = req.WithContext(ctx)
+			}
+
+			// Simulate chi URL params
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tc.id)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(h.CheckInTicket)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Status code mismatch")
+			assert.Contains(t, rr.Body.String(), tc.expectedBody, "Response body mismatch")
+		})
+	}
+}
+
+// TestCheckInTicket_Access tests who may check in tickets, against the in-memory store
+func TestCheckInTicket_Access(t *testing.T) {
+	h, store := newTestHandler()
+
+	// Configure token signing for signing test codes
+	utils.ConfigureJWT(config.JWTConfig{Secret: "test-secret-key-for-testing", AccessTokenTTL: 24 * time.Hour})
+
+	event := store.addEvent(models.Event{Name: "Jazz Night", OrganizerID: 7, Status: "published", Capacity: 10})
+	eventID := strconv.FormatUint(uint64(event.ID), 10)
+
+	tests := []struct {
+		name           string
+		claims         *utils.Claims
+		expectedStatus int
+	}{
+		{
+			name:           "Owning Organizer",
+			claims:         &utils.Claims{UserID: 7, Role: models.RoleOrganizer},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Admin",
+			claims:         &utils.Claims{UserID: 99, Role: models.RoleAdmin},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Other Organizer",
+			claims:         &utils.Claims{UserID: 8, Role: models.RoleOrganizer},
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			code, err := utils.GenerateTicketCode(event.ID, uint(i+1))
+			require.NoError(t, err)
+			store.addTicket(models.Ticket{BookingID: uint(i + 1), EventID: event.ID, Code: code})
+
+			req := httptest.NewRequest("POST", "/events/"+eventID+"/checkin", bytes.NewBufferString(`{"code": "`+code+`"}`))
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", eventID)
+			ctx := context.WithValue(req.Context(), middleware.UserContextKey, tc.claims)
+			req = req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			h.CheckInTicket(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, rr.Body.String())
+		})
+	}
+}
+
+// TestGetTicketQRCode_Validation tests QR code request validation without database
+func TestGetTicketQRCode_Validation(t *testing.T) {
+	h, _ := newTestHandler()
+
+	tests := []struct {
+		name           string
+		id             string
+		query          string
+		withAuth       bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "No Auth",
+			id:             "1",
+			withAuth:       false,
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "User not found in context",
+		},
+		{
+			name:           "Non-numeric ID",
+			id:             "abc",
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid ticket ID",
+		},
+		{
+			name:           "Unknown Format",
+			id:             "1",
+			query:          "?format=gif",
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid format",
+		},
+		{
+			name:           "Size Too Large",
+			id:             "1",
+			query:          "?size=4096",
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid size",
+		},
+		{
+			name:           "Non-numeric Size",
+			id:             "1",
+			query:          "?size=big",
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid size",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/tickets/"+tc.id+"/qr"+tc.query, nil)
+			assert.NoError(t, err)
+
+			// Add user claims to context if needed
+			if tc.withAuth {
+				claims := &utils.Claims{
+					UserID:   1,
+					Username: "testuser",
+					Email:    "test@example.com",
+				}
+				ctx := context.WithValue(req.Context(), middleware.UserContextKey, claims)
+				req = req.WithContext(ctx)
+			}
+
+			// Simulate chi URL params
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tc.id)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(h.GetTicketQRCode)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Status code mismatch")
+			assert.Contains(t, rr.Body.String(), tc.expectedBody, "Response body mismatch")
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/alexs/golang_test/internal/models"
+	"github.com/alexs/golang_test/internal/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxVenueSeats bounds the size of a seat map created in one request
+const maxVenueSeats = 50000
+
+type CreateVenueRequest struct {
+	Name     string                 `json:"name"`
+	City     string                 `json:"city"`
+	Address  string                 `json:"address"`
+	Sections []CreateSectionRequest `json:"sections"`
+}
+
+type CreateSectionRequest struct {
+	Name string             `json:"name"`
+	Rows []CreateRowRequest `json:"rows"`
+}
+
+// CreateRowRequest describes a row of seats numbered 1..Seats
+type CreateRowRequest struct {
+	Row   string `json:"row"`
+	Seats int    `json:"seats"`
+}
+
+type SeatStatus struct {
+	models.Seat
+	Status string `json:"status"` // available, booked
+}
+
+type SectionSeats struct {
+	ID    uint         `json:"id"`
+	Name  string       `json:"name"`
+	Seats []SeatStatus `json:"seats"`
+}
+
+type EventSeatsResponse struct {
+	EventID  uint           `json:"event_id"`
+	VenueID  uint           `json:"venue_id"`
+	Venue    string         `json:"venue"`
+	Sections []SectionSeats `json:"sections"`
+}
+
+// broadcastSeatUpdate is a helper to send seat state changes
+func (h *Handler) broadcastSeatUpdate(ctx context.Context, event *models.Event, seats []models.BookingSeat, status string) {
+	if h.hub == nil || len(seats) == 0 {
+		return
+	}
+
+	seatIDs := make([]uint, len(seats))
+	for i, seat := range seats {
+		seatIDs[i] = seat.SeatID
+	}
+	h.hub.BroadcastSeatUpdate(ctx, liveEventInfo(event), seatIDs, status)
+}
+
+// CreateVenue creates a venue with a numbered seat map
+func (h *Handler) CreateVenue(w http.ResponseWriter, r *http.Request) {
+	var req CreateVenueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Name == "" || len(req.Sections) == 0 {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Name and at least one section are required")
+		return
+	}
+
+	venue := models.Venue{
+		Name:    req.Name,
+		City:    req.City,
+		Address: req.Address,
+	}
+
+	totalSeats := 0
+	for _, sectionReq := range req.Sections {
+		if sectionReq.Name == "" || len(sectionReq.Rows) == 0 {
+			utils.ErrorResponse(w, http.StatusBadRequest, "Each section requires a name and at least one row")
+			return
+		}
+
+		section := models.Section{Name: sectionReq.Name}
+		seenRows := make(map[string]bool)
+		for _, row := range sectionReq.Rows {
+			if row.Row == "" || row.Seats <= 0 {
+				utils.ErrorResponse(w, http.StatusBadRequest, "Each row requires a label and a positive seat count")
+				return
+			}
+			if seenRows[row.Row] {
+				utils.ErrorResponse(w, http.StatusBadRequest, "Row labels must be unique within a section")
+				return
+			}
+			seenRows[row.Row] = true
+
+			totalSeats += row.Seats
+			if totalSeats > maxVenueSeats {
+				utils.ErrorResponse(w, http.StatusBadRequest, "Venue exceeds the maximum of "+strconv.Itoa(maxVenueSeats)+" seats")
+				return
+			}
+
+			for number := 1; number <= row.Seats; number++ {
+				section.Seats = append(section.Seats, models.Seat{Row: row.Row, Number: number})
+			}
+		}
+		venue.Sections = append(venue.Sections, section)
+	}
+
+	if err := h.venues.Create(r.Context(), &venue); err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to create venue")
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusCreated, venue)
+}
+
+// GetVenue returns a venue with its seat map
+func (h *Handler) GetVenue(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid venue ID")
+		return
+	}
+
+	venue, err := h.venues.GetByID(r.Context(), uint(id))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, "Venue not found")
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, venue)
+}
+
+// GetEventSeats reports the state of every seat in an event's seat map
+func (h *Handler) GetEventSeats(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	event, err := h.events.GetByID(r.Context(), uint(id))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	if event.VenueID == nil {
+		utils.ErrorResponse(w, http.StatusNotFound, "Event does not have reserved seating")
+		return
+	}
+
+	venue, err := h.venues.GetByID(r.Context(), *event.VenueID)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch seat map")
+		return
+	}
+
+	bookedIDs, err := h.venues.GetBookedSeatIDs(r.Context(), event.ID)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch seat availability")
+		return
+	}
+
+	booked := make(map[uint]bool, len(bookedIDs))
+	for _, seatID := range bookedIDs {
+		booked[seatID] = true
+	}
+
+	response := EventSeatsResponse{
+		EventID:  event.ID,
+		VenueID:  venue.ID,
+		Venue:    venue.Name,
+		Sections: make([]SectionSeats, len(venue.Sections)),
+	}
+	for i, section := range venue.Sections {
+		seats := make([]SeatStatus, len(section.Seats))
+		for j, seat := range section.Seats {
+			status := "available"
+			if booked[seat.ID] {
+				status = "booked"
+			}
+			seats[j] = SeatStatus{Seat: seat, Status: status}
+		}
+		response.Sections[i] = SectionSeats{ID: section.ID, Name: section.Name, Seats: seats}
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, response)
+}
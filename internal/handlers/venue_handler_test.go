@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCreateVenue_Validation tests venue seat map validation without database
+func TestCreateVenue_Validation(t *testing.T) {
+	h, _ := newTestHandler()
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Invalid JSON",
+			body:           `{invalid json`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid request body",
+		},
+		{
+			name:           "Missing Name",
+			body:           `{"sections": [{"name": "Orchestra", "rows": [{"row": "A", "seats": 10}]}]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Name and at least one section are required",
+		},
+		{
+			name:           "No Sections",
+			body:           `{"name": "City Theater", "sections": []}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Name and at least one section are required",
+		},
+		{
+			name:           "Section Without Rows",
+			body:           `{"name": "City Theater", "sections": [{"name": "Balcony", "rows": []}]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Each section requires a name and at least one row",
+		},
+		{
+			name:           "Row Without Seats",
+			body:           `{"name": "City Theater", "sections": [{"name": "Balcony", "rows": [{"row": "A", "seats": 0}]}]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Each row requires a label and a positive seat count",
+		},
+		{
+			name:           "Duplicate Row Labels",
+			body:           `{"name": "City Theater", "sections": [{"name": "Balcony", "rows": [{"row": "A", "seats": 5}, {"row": "A", "seats": 5}]}]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Row labels must be unique within a section",
+		},
+		{
+			name:           "Too Many Seats",
+			body:           `{"name": "Mega Dome", "sections": [{"name": "Floor", "rows": [{"row": "A", "seats": 60000}]}]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Venue exceeds the maximum",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/venues", bytes.NewBufferString(tc.body))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(h.CreateVenue)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Status code mismatch")
+			assert.Contains(t, rr.Body.String(), tc.expectedBody, "Response body mismatch")
+		})
+	}
+}
+
+// TestSeatRoutes_InvalidID tests venue and event seat lookups with invalid IDs
+func TestSeatRoutes_InvalidID(t *testing.T) {
+	h, _ := newTestHandler()
+
+	tests := []struct {
+		name         string
+		handler      http.HandlerFunc
+		id           string
+		expectedBody string
+	}{
+		{
+			name:         "Venue Non-numeric ID",
+			handler:      h.GetVenue,
+			id:           "abc",
+			expectedBody: "Invalid venue ID",
+		},
+		{
+			name:         "Event Seats Non-numeric ID",
+			handler:      h.GetEventSeats,
+			id:           "abc",
+			expectedBody: "Invalid event ID",
+		},
+		{
+			name:         "Event Seats Negative ID",
+			handler:      h.GetEventSeats,
+			id:           "-1",
+			expectedBody: "Invalid event ID",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/"+tc.id, nil)
+			assert.NoError(t, err)
+
+			// Simulate chi URL params
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tc.id)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			tc.handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code, "Status code mismatch")
+			assert.Contains(t, rr.Body.String(), tc.expectedBody, "Response body mismatch")
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexs/golang_test/internal/middleware"
+	"github.com/alexs/golang_test/internal/models"
+	"github.com/alexs/golang_test/internal/utils"
+	"github.com/alexs/golang_test/internal/websocket"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type JoinWaitlistRequest struct {
+	TierID   *uint `json:"tier_id,omitempty"`
+	Quantity int   `json:"quantity"`
+}
+
+// notifyWaitlistOffers tells each waitlisted user that tickets are being held for them
+func (h *Handler) notifyWaitlistOffers(ctx context.Context, entries []models.WaitlistEntry) {
+	if h.hub == nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.HoldID == nil || entry.OfferExpiresAt == nil {
+			continue
+		}
+		h.hub.SendToUser(ctx, entry.UserID, &websocket.Message{
+			Type:    websocket.MessageTypeWaitlistOffer,
+			EventID: &entry.EventID,
+			Data: websocket.WaitlistOffer{
+				EventID:   entry.EventID,
+				HoldID:    *entry.HoldID,
+				Quantity:  entry.Quantity,
+				ExpiresAt: entry.OfferExpiresAt.Format(time.RFC3339),
+			},
+		})
+	}
+}
+
+// JoinWaitlist queues the authenticated user for a sold-out event
+func (h *Handler) JoinWaitlist(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r)
+	if claims == nil {
+		utils.ErrorResponse(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	eventID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	var req JoinWaitlistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Quantity <= 0 {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Valid quantity is required")
+		return
+	}
+
+	entry := models.WaitlistEntry{
+		UserID:   claims.UserID,
+		EventID:  uint(eventID),
+		TierID:   req.TierID,
+		Quantity: req.Quantity,
+	}
+
+	if err := h.waitlist.Join(r.Context(), &entry); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(w, http.StatusNotFound, "Event not found")
+			return
+		}
+		if strings.Contains(err.Error(), "tickets are still available") {
+			utils.ErrorResponse(w, http.StatusConflict, "Tickets are still available, book them directly")
+			return
+		}
+		if strings.Contains(err.Error(), "already on the waitlist") {
+			utils.ErrorResponse(w, http.StatusConflict, "You are already on the waitlist for this event")
+			return
+		}
+		if msg, ok := bookingErrorMessage(err); ok {
+			utils.ErrorResponse(w, http.StatusBadRequest, msg)
+			return
+		}
+		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to join waitlist")
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusCreated, entry)
+}
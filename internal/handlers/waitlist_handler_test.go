@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexs/golang_test/internal/middleware"
+	"github.com/alexs/golang_test/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestJoinWaitlist_Validation tests waitlist validation without database
+func TestJoinWaitlist_Validation(t *testing.T) {
+	h, _ := newTestHandler()
+
+	tests := []struct {
+		name           string
+		id             string
+		body           string
+		withAuth       bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "No User Context",
+			id:             "1",
+			body:           `{"quantity": 2}`,
+			withAuth:       false,
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "User not found in context",
+		},
+		{
+			name:           "Non-numeric Event ID",
+			id:             "abc",
+			body:           `{"quantity": 2}`,
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid event ID",
+		},
+		{
+			name:           "Invalid JSON",
+			id:             "1",
+			body:           `not json`,
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid request body",
+		},
+		{
+			name:           "Zero Quantity",
+			id:             "1",
+			body:           `{"quantity": 0}`,
+			withAuth:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Valid quantity is required",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/events/"+tc.id+"/waitlist", bytes.NewBufferString(tc.body))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			// Add user claims to context if needed
+			if tc.withAuth {
+				claims := &utils.Claims{
+					UserID:   1,
+					Username: "testuser",
+					Email:    "test@example.com",
+				}
+				ctx := context.WithValue(req.Context(), middleware.UserContextKey, claims)
+				req = req.WithContext(ctx)
+			}
+
+			// Simulate chi URL params
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tc.id)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(h.JoinWaitlist)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Status code mismatch")
+			assert.Contains(t, rr.Body.String(), tc.expectedBody, "Response body mismatch")
+		})
+	}
+}
@@ -0,0 +1,86 @@
+// Package logging sets up structured JSON logging and carries the request ID
+// and authenticated user of a request in its context, so every log line
+// written with that context can be correlated.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestKey is the context key of a request's requestInfo
+type requestKey struct{}
+
+// requestInfo identifies a request. The user ID is filled in once the request
+// is authenticated, which happens further down the middleware chain than where
+// the info is attached, so it is shared by pointer.
+type requestInfo struct {
+	id     string
+	userID atomic.Uint64
+}
+
+// Setup makes a JSON logger writing to w at level the default for slog. The
+// standard log package then writes through it too, at info level.
+func Setup(w io.Writer, level slog.Level) {
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})
+	slog.SetDefault(slog.New(contextHandler{handler}))
+}
+
+// WithRequestID returns a context carrying the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestKey{}, &requestInfo{id: requestID})
+}
+
+// RequestID returns the request ID carried by ctx, if any
+func RequestID(ctx context.Context) string {
+	if info, ok := ctx.Value(requestKey{}).(*requestInfo); ok {
+		return info.id
+	}
+	return ""
+}
+
+// SetUserID records the authenticated user of the request carried by ctx
+func SetUserID(ctx context.Context, userID uint) {
+	if info, ok := ctx.Value(requestKey{}).(*requestInfo); ok {
+		info.userID.Store(uint64(userID))
+	}
+}
+
+// UserID returns the authenticated user of the request carried by ctx, or 0
+func UserID(ctx context.Context) uint {
+	if info, ok := ctx.Value(requestKey{}).(*requestInfo); ok {
+		return uint(info.userID.Load())
+	}
+	return 0
+}
+
+// contextHandler adds the request ID, user ID and trace of the context to each
+// record, so log lines can be matched to their spans
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if info, ok := ctx.Value(requestKey{}).(*requestInfo); ok {
+		record.AddAttrs(slog.String("request_id", info.id))
+		if userID := info.userID.Load(); userID != 0 {
+			record.AddAttrs(slog.Uint64("user_id", userID))
+		}
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
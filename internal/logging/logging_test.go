@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// record logs one line through a contextHandler and returns its fields
+func record(t *testing.T, ctx context.Context, args ...any) map[string]any {
+	var buf bytes.Buffer
+	logger := slog.New(contextHandler{slog.NewJSONHandler(&buf, nil)}).With("component", "test")
+	logger.InfoContext(ctx, "Hello", args...)
+
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &fields))
+	return fields
+}
+
+// TestContextHandler tests that log lines carry the request and user IDs of their context
+func TestContextHandler(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "req-1")
+
+	fields := record(t, ctx, "event_id", 3)
+	assert.Equal(t, "req-1", fields["request_id"])
+	assert.NotContains(t, fields, "user_id", "Anonymous requests have no user")
+	assert.Equal(t, "test", fields["component"])
+	assert.EqualValues(t, 3, fields["event_id"])
+
+	// The user is authenticated further down the chain, on a derived context
+	SetUserID(context.WithValue(ctx, struct{}{}, "derived"), 42)
+
+	fields = record(t, ctx)
+	assert.Equal(t, "req-1", fields["request_id"])
+	assert.EqualValues(t, 42, fields["user_id"])
+	assert.Equal(t, "req-1", RequestID(ctx))
+	assert.Equal(t, uint(42), UserID(ctx))
+
+	fields = record(t, context.Background())
+	assert.NotContains(t, fields, "request_id")
+	assert.Equal(t, "", RequestID(context.Background()))
+	assert.Equal(t, uint(0), UserID(context.Background()))
+	assert.NotContains(t, fields, "trace_id", "Untraced contexts have no trace")
+}
+
+// TestContextHandlerTrace tests that log lines carry the trace and span IDs of their context
+func TestContextHandlerTrace(t *testing.T) {
+	span := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	})
+
+	fields := record(t, trace.ContextWithSpanContext(context.Background(), span))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", fields["trace_id"])
+	assert.Equal(t, "00f067aa0ba902b7", fields["span_id"])
+}
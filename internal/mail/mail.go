@@ -0,0 +1,68 @@
+// Package mail sends transactional emails such as password resets and
+// email verification links.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers emails. Implementations must be safe for concurrent use.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogSender logs that an email would have been sent instead of delivering it.
+// Bodies carry password reset and verification tokens, so only the recipient
+// and subject are logged; use a FileSender to read the emails themselves.
+type LogSender struct{}
+
+// NewLogSender returns a Sender that logs the recipient and subject of every email
+func NewLogSender() *LogSender {
+	return &LogSender{}
+}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	slog.InfoContext(ctx, "Mail not delivered", "to", msg.To, "subject", msg.Subject)
+	return nil
+}
+
+// FileSender appends emails to a mailbox file, one message per block
+type FileSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileSender returns a Sender that appends emails to the file at path
+func NewFileSender(path string) *FileSender {
+	return &FileSender{path: path}
+}
+
+func (s *FileSender) Send(ctx context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open mailbox: %w", err)
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().UTC().Format(time.RFC1123Z), msg.To, msg.Subject, msg.Body)
+	if err != nil {
+		return fmt.Errorf("failed to write mailbox: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFileSender tests that emails are appended to the mailbox file
+func TestFileSender(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mailbox.txt")
+	sender := NewFileSender(path)
+
+	err := sender.Send(context.Background(), Message{To: "a@example.com", Subject: "First", Body: "Hello"})
+	assert.NoError(t, err)
+	err = sender.Send(context.Background(), Message{To: "b@example.com", Subject: "Second", Body: "World"})
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	mailbox := string(content)
+	assert.Contains(t, mailbox, "To: a@example.com\nSubject: First\n\nHello")
+	assert.Contains(t, mailbox, "To: b@example.com\nSubject: Second\n\nWorld")
+	assert.Less(t, strings.Index(mailbox, "First"), strings.Index(mailbox, "Second"), "Messages should be appended in order")
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "Mailbox may contain tokens and should be private")
+}
+
+// TestFileSender_UnwritablePath tests that delivery errors are reported
+func TestFileSender_UnwritablePath(t *testing.T) {
+	sender := NewFileSender(filepath.Join(t.TempDir(), "missing", "mailbox.txt"))
+
+	err := sender.Send(context.Background(), Message{To: "a@example.com", Subject: "Hi", Body: "Body"})
+	assert.Error(t, err)
+}
+
+// TestLogSender_OmitsBody tests that logged emails don't leak the tokens in their bodies
+func TestLogSender_OmitsBody(t *testing.T) {
+	previous := slog.Default()
+	defer slog.SetDefault(previous)
+
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	err := NewLogSender().Send(context.Background(), Message{To: "a@example.com", Subject: "Reset your password", Body: "token=secret-token"})
+	assert.NoError(t, err)
+
+	assert.Contains(t, buf.String(), "a@example.com")
+	assert.Contains(t, buf.String(), "Reset your password")
+	assert.NotContains(t, buf.String(), "secret-token")
+}
+
+// TestSenders_ImplementInterface tests that the development senders satisfy Sender
+func TestSenders_ImplementInterface(t *testing.T) {
+	var _ Sender = NewLogSender()
+	var _ Sender = NewFileSender("unused")
+}
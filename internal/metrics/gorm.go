@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+// queryStartKey stores when a statement started on its gorm.DB instance
+const queryStartKey = "metrics:query_start"
+
+// gormPlugin times every GORM statement into db_query_duration_seconds
+type gormPlugin struct {
+	queryDuration *prometheus.HistogramVec
+}
+
+// GormPlugin returns a plugin recording query durations; install it with db.Use
+func (m *Metrics) GormPlugin() gorm.Plugin {
+	return &gormPlugin{queryDuration: m.queryDuration}
+}
+
+func (p *gormPlugin) Name() string {
+	return "metrics"
+}
+
+// Initialize wraps each of GORM's statement callbacks with a timer
+func (p *gormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("metrics:before_create", p.start),
+		callbacks.Create().After("gorm:create").Register("metrics:after_create", p.observe("create")),
+		callbacks.Query().Before("gorm:query").Register("metrics:before_query", p.start),
+		callbacks.Query().After("gorm:query").Register("metrics:after_query", p.observe("query")),
+		callbacks.Update().Before("gorm:update").Register("metrics:before_update", p.start),
+		callbacks.Update().After("gorm:update").Register("metrics:after_update", p.observe("update")),
+		callbacks.Delete().Before("gorm:delete").Register("metrics:before_delete", p.start),
+		callbacks.Delete().After("gorm:delete").Register("metrics:after_delete", p.observe("delete")),
+		callbacks.Row().Before("gorm:row").Register("metrics:before_row", p.start),
+		callbacks.Row().After("gorm:row").Register("metrics:after_row", p.observe("row")),
+		callbacks.Raw().Before("gorm:raw").Register("metrics:before_raw", p.start),
+		callbacks.Raw().After("gorm:raw").Register("metrics:after_raw", p.observe("raw")),
+	)
+}
+
+// start notes when the statement began
+func (p *gormPlugin) start(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+// observe records the statement's duration under operation and its table
+func (p *gormPlugin) observe(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		p.queryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/alexs/golang_test/internal/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxEventSubscriberSeries caps the per-event subscriber gauge to the events
+// with the most subscribers, so its series don't grow with the number of events
+const maxEventSubscriberSeries = 50
+
+// hubCollector reads the hub's stats at scrape time
+type hubCollector struct {
+	hub *websocket.Hub
+
+	clients            *prometheus.Desc
+	eventSubscribers   *prometheus.Desc
+	eventSubscriptions *prometheus.Desc
+	subscribedEvents   *prometheus.Desc
+	droppedMessages    *prometheus.Desc
+	coalescedUpdates   *prometheus.Desc
+}
+
+// RegisterHub exposes the hub's connected clients, event subscriptions and
+// delivery counters. Subscribers per event are only reported for the
+// maxEventSubscriberSeries most watched events; the subscription totals cover
+// every event.
+func (m *Metrics) RegisterHub(hub *websocket.Hub) {
+	m.registry.MustRegister(&hubCollector{
+		hub: hub,
+		clients: prometheus.NewDesc("websocket_clients",
+			"Connected WebSocket and Server-Sent Event clients.", nil, nil),
+		eventSubscribers: prometheus.NewDesc("websocket_event_subscribers",
+			"Clients subscribed to an event's live updates, for the most watched events only.", []string{"event_id"}, nil),
+		eventSubscriptions: prometheus.NewDesc("websocket_event_subscriptions",
+			"Subscriptions to events' live updates, summed over all events.", nil, nil),
+		subscribedEvents: prometheus.NewDesc("websocket_subscribed_events",
+			"Events with at least one subscribed client.", nil, nil),
+		droppedMessages: prometheus.NewDesc("websocket_dropped_messages_total",
+			"Messages dropped because a client's send buffer was full, by message type.", []string{"type"}, nil),
+		coalescedUpdates: prometheus.NewDesc("websocket_coalesced_updates_total",
+			"Availability update requests merged into an already scheduled broadcast.", nil, nil),
+	})
+}
+
+func (c *hubCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.clients
+	ch <- c.eventSubscribers
+	ch <- c.eventSubscriptions
+	ch <- c.subscribedEvents
+	ch <- c.droppedMessages
+	ch <- c.coalescedUpdates
+}
+
+func (c *hubCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.hub.Stats()
+
+	ch <- prometheus.MustNewConstMetric(c.clients, prometheus.GaugeValue, float64(stats.Clients))
+	for _, eventID := range mostWatchedEvents(stats.EventSubscribers, maxEventSubscriberSeries) {
+		ch <- prometheus.MustNewConstMetric(c.eventSubscribers, prometheus.GaugeValue,
+			float64(stats.EventSubscribers[eventID]), strconv.FormatUint(uint64(eventID), 10))
+	}
+	subscriptions := 0
+	for _, subscribers := range stats.EventSubscribers {
+		subscriptions += subscribers
+	}
+	ch <- prometheus.MustNewConstMetric(c.eventSubscriptions, prometheus.GaugeValue, float64(subscriptions))
+	ch <- prometheus.MustNewConstMetric(c.subscribedEvents, prometheus.GaugeValue, float64(stats.SubscribedEvents))
+	for msgType, dropped := range stats.DroppedMessages {
+		ch <- prometheus.MustNewConstMetric(c.droppedMessages, prometheus.CounterValue, float64(dropped), string(msgType))
+	}
+	ch <- prometheus.MustNewConstMetric(c.coalescedUpdates, prometheus.CounterValue, float64(stats.CoalescedUpdates))
+}
+
+// mostWatchedEvents returns the IDs of up to n events with the most
+// subscribers, breaking ties by event ID so the reported events stay stable
+func mostWatchedEvents(subscribers map[uint]int, n int) []uint {
+	eventIDs := make([]uint, 0, len(subscribers))
+	for eventID := range subscribers {
+		eventIDs = append(eventIDs, eventID)
+	}
+	sort.Slice(eventIDs, func(i, j int) bool {
+		a, b := eventIDs[i], eventIDs[j]
+		if subscribers[a] != subscribers[b] {
+			return subscribers[a] > subscribers[b]
+		}
+		return a < b
+	})
+
+	if len(eventIDs) > n {
+		eventIDs = eventIDs[:n]
+	}
+	return eventIDs
+}
@@ -0,0 +1,117 @@
+// Package metrics collects Prometheus metrics for the API, bookings, database
+// queries and the WebSocket hub, and serves them for scraping.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	gorillaws "github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds the application's collectors on a registry of its own, so
+// separate instances, such as one per test, don't clash
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requestDuration *prometheus.HistogramVec
+	bookings        *prometheus.CounterVec
+	ticketsSold     *prometheus.CounterVec
+	queryDuration   *prometheus.HistogramVec
+}
+
+// New creates the collectors, along with the Go runtime and process ones
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time taken to serve HTTP requests, by method, route pattern and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		bookings: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "bookings_total",
+			Help: "Bookings created, cancelled and rejected because not enough tickets were available.",
+		}, []string{"result"}),
+		ticketsSold: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "tickets_sold_total",
+			Help: "Tickets sold through confirmed bookings, by event type.",
+		}, []string{"event_type"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Time taken by database queries made through GORM, by operation and table.",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "table"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requestDuration,
+		m.bookings,
+		m.ticketsSold,
+		m.queryDuration,
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Middleware times each request and labels it with the chi route pattern
+// rather than the path, so /events/1 and /events/2 share a series. It must be
+// registered on the root router to see the full pattern.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		// Hijacked WebSocket connections never write a status through the writer
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+			if gorillaws.IsWebSocketUpgrade(r) {
+				status = http.StatusSwitchingProtocols
+			}
+		}
+
+		m.requestDuration.
+			WithLabelValues(r.Method, route, strconv.Itoa(status)).
+			Observe(time.Since(start).Seconds())
+	})
+}
+
+// BookingCreated counts a confirmed booking and adds its tickets to the event type's sales
+func (m *Metrics) BookingCreated(eventType string, tickets int) {
+	if eventType == "" {
+		eventType = "unknown"
+	}
+	m.bookings.WithLabelValues("created").Inc()
+	m.ticketsSold.WithLabelValues(eventType).Add(float64(tickets))
+}
+
+// BookingCancelled counts a cancelled booking
+func (m *Metrics) BookingCancelled() {
+	m.bookings.WithLabelValues("cancelled").Inc()
+}
+
+// BookingRejected counts a booking refused because not enough tickets were available
+func (m *Metrics) BookingRejected() {
+	m.bookings.WithLabelValues("rejected_capacity").Inc()
+}
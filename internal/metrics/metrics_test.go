@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexs/golang_test/internal/websocket"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scrape returns the metrics page served by m
+func scrape(t *testing.T, m *Metrics) string {
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	return rr.Body.String()
+}
+
+// TestMiddleware tests that requests are labelled by route pattern and status
+func TestMiddleware(t *testing.T) {
+	m := New()
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Get("/events/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	r.Route("/admin", func(r chi.Router) {
+		r.Post("/users/{id}/role", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		})
+	})
+
+	for _, path := range []string{"/events/1", "/events/2", "/nowhere"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/admin/users/5/role", nil))
+
+	page := scrape(t, m)
+	assert.Contains(t, page, `http_request_duration_seconds_count{method="GET",route="/events/{id}",status="200"} 2`)
+	assert.Contains(t, page, `http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, page, `http_request_duration_seconds_count{method="POST",route="/admin/users/{id}/role",status="403"} 1`)
+}
+
+// TestBookingCounters tests the booking results and tickets sold per event type
+func TestBookingCounters(t *testing.T) {
+	m := New()
+	m.BookingCreated("concert", 2)
+	m.BookingCreated("concert", 3)
+	m.BookingCreated("", 1)
+	m.BookingCancelled()
+	m.BookingRejected()
+
+	page := scrape(t, m)
+	assert.Contains(t, page, `bookings_total{result="created"} 3`)
+	assert.Contains(t, page, `bookings_total{result="cancelled"} 1`)
+	assert.Contains(t, page, `bookings_total{result="rejected_capacity"} 1`)
+	assert.Contains(t, page, `tickets_sold_total{event_type="concert"} 5`)
+	assert.Contains(t, page, `tickets_sold_total{event_type="unknown"} 1`)
+}
+
+// TestRegisterHub tests that the hub gauges follow its clients and subscriptions
+func TestRegisterHub(t *testing.T) {
+	hub := websocket.NewHub()
+	go hub.Run()
+
+	m := New()
+	m.RegisterHub(hub)
+
+	client := hub.NewStreamClient("stream-1", 1)
+	other := hub.NewStreamClient("stream-2", 2)
+	hub.SubscribeToEvent(client, 3)
+	hub.SubscribeToEvent(other, 3)
+	hub.SubscribeToEvent(other, 4)
+
+	require.Eventually(t, func() bool {
+		return hub.Stats().Clients == 2
+	}, time.Second, 5*time.Millisecond)
+
+	page := scrape(t, m)
+	assert.Contains(t, page, "websocket_clients 2")
+	assert.Contains(t, page, "websocket_event_subscriptions 3")
+	assert.Contains(t, page, "websocket_subscribed_events 2")
+	assert.Contains(t, page, `websocket_event_subscribers{event_id="3"} 2`)
+	assert.Contains(t, page, `websocket_event_subscribers{event_id="4"} 1`)
+	assert.Contains(t, page, "websocket_coalesced_updates_total 0")
+}
+
+// TestMostWatchedEvents tests that the per-event gauge keeps the events with the most subscribers
+func TestMostWatchedEvents(t *testing.T) {
+	subscribers := map[uint]int{1: 4, 2: 9, 3: 4, 4: 1, 5: 7}
+
+	assert.Equal(t, []uint{2, 5, 1}, mostWatchedEvents(subscribers, 3), "Ties go to the lower event ID")
+	assert.Equal(t, []uint{2, 5, 1, 3, 4}, mostWatchedEvents(subscribers, 10))
+	assert.Empty(t, mostWatchedEvents(map[uint]int{}, 3))
+}
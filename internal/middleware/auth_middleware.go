@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/alexs/golang_test/internal/logging"
 	"github.com/alexs/golang_test/internal/utils"
 )
 
@@ -31,19 +32,43 @@ func RequireAuth(next http.Handler) http.Handler {
 
 		tokenString := parts[1]
 
-		// Validate token
-		claims, err := utils.ValidateJWT(tokenString)
+		// Validate token and make sure its session hasn't been revoked
+		claims, err := utils.ValidateAccessToken(r.Context(), tokenString)
 		if err != nil {
 			utils.Error(w, http.StatusUnauthorized, "Invalid or expired token")
 			return
 		}
 
-		// Add claims to request context
+		// Add claims to request context, and the user to its log lines
+		logging.SetUserID(r.Context(), claims.UserID)
 		ctx := context.WithValue(r.Context(), UserContextKey, claims)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// RequireRole only lets through requests whose token carries one of the given roles.
+// It must be mounted after RequireAuth.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := GetUserFromContext(r)
+			if claims == nil {
+				utils.Error(w, http.StatusUnauthorized, "Authorization required")
+				return
+			}
+
+			for _, role := range roles {
+				if claims.Role == role {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			utils.Error(w, http.StatusForbidden, "Insufficient permissions")
+		})
+	}
+}
+
 // GetUserFromContext retrieves user claims from request context
 func GetUserFromContext(r *http.Request) *utils.Claims {
 	claims, ok := r.Context().Value(UserContextKey).(*utils.Claims)
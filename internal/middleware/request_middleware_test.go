@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alexs/golang_test/internal/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLogs sends the default logger's output to a buffer for the rest of the test
+func captureLogs(t *testing.T) *bytes.Buffer {
+	previous, writer, flags := slog.Default(), log.Writer(), log.Flags()
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+		log.SetOutput(writer)
+		log.SetFlags(flags)
+	})
+
+	var buf bytes.Buffer
+	logging.Setup(&buf, slog.LevelInfo)
+	return &buf
+}
+
+// TestRequestID tests that a sane incoming request ID is kept and anything else replaced
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		kept     bool
+	}{
+		{name: "Kept", incoming: "abc-123.DEF_4", kept: true},
+		{name: "Missing", incoming: ""},
+		{name: "Invalid characters", incoming: "abc\n123"},
+		{name: "Too long", incoming: strings.Repeat("a", 65)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = logging.RequestID(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/events", nil)
+			req.Header.Set(RequestIDHeader, tt.incoming)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.NotEmpty(t, seen)
+			assert.Equal(t, seen, rr.Header().Get(RequestIDHeader))
+			if tt.kept {
+				assert.Equal(t, tt.incoming, seen)
+			} else {
+				assert.NotEqual(t, tt.incoming, seen)
+			}
+		})
+	}
+}
+
+// TestLogger tests that the access log line carries the route, status, request ID and authenticated user
+func TestLogger(t *testing.T) {
+	logs := captureLogs(t)
+
+	r := chi.NewRouter()
+	r.Use(RequestID)
+	r.Use(Logger)
+	r.With(func(next http.Handler) http.Handler {
+		// Stands in for RequireAuth
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logging.SetUserID(r.Context(), 7)
+			next.ServeHTTP(w, r)
+		})
+	}).Post("/events/{id}/book", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/events/5/book", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &line))
+	assert.Equal(t, "Request served", line["msg"])
+	assert.Equal(t, "/events/{id}/book", line["route"])
+	assert.Equal(t, "/events/5/book", line["path"])
+	assert.EqualValues(t, http.StatusConflict, line["status"])
+	assert.Equal(t, "req-42", line["request_id"])
+	assert.EqualValues(t, 7, line["user_id"])
+}
@@ -0,0 +1,310 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoad tests reading and ordering migration files
+func TestLoad(t *testing.T) {
+	file := func(sql string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(sql)} }
+
+	tests := []struct {
+		name          string
+		files         fstest.MapFS
+		expectedNames []string
+		expectedError string
+	}{
+		{
+			name: "Ordered by version",
+			files: fstest.MapFS{
+				"sql/0010_add_slug.up.sql":         file("ALTER TABLE events ADD slug text"),
+				"sql/0010_add_slug.down.sql":       file("ALTER TABLE events DROP slug"),
+				"sql/0002_add_tags.up.sql":         file("CREATE TABLE tags ()"),
+				"sql/0002_add_tags.down.sql":       file("DROP TABLE tags"),
+				"sql/0001_initial_schema.up.sql":   file("CREATE TABLE events ()"),
+				"sql/0001_initial_schema.down.sql": file("DROP TABLE events"),
+			},
+			expectedNames: []string{"initial_schema", "add_tags", "add_slug"},
+		},
+		{
+			name: "Missing down file",
+			files: fstest.MapFS{
+				"sql/0001_initial_schema.up.sql": file("CREATE TABLE events ()"),
+			},
+			expectedError: "needs both an up and a down file",
+		},
+		{
+			name: "Invalid file name",
+			files: fstest.MapFS{
+				"sql/initial_schema.sql": file("CREATE TABLE events ()"),
+			},
+			expectedError: "invalid migration file name",
+		},
+		{
+			name: "Version zero",
+			files: fstest.MapFS{
+				"sql/0000_initial_schema.up.sql":   file("CREATE TABLE events ()"),
+				"sql/0000_initial_schema.down.sql": file("DROP TABLE events"),
+			},
+			expectedError: "invalid migration version",
+		},
+		{
+			name: "Up and down named differently",
+			files: fstest.MapFS{
+				"sql/0001_initial_schema.up.sql": file("CREATE TABLE events ()"),
+				"sql/0001_first.down.sql":        file("DROP TABLE events"),
+			},
+			expectedError: "has files named",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := load(tt.files)
+
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			var names []string
+			for _, migration := range migrations {
+				names = append(names, migration.Name)
+			}
+			assert.Equal(t, tt.expectedNames, names)
+		})
+	}
+}
+
+// TestLoad_Embedded tests that the migrations shipped in the binary are valid
+// and that the first one creates the event search indexes
+func TestLoad_Embedded(t *testing.T) {
+	migrations, err := load(embedded)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	first := migrations[0]
+	assert.Equal(t, int64(1), first.Version)
+	assert.Contains(t, first.Up, "idx_events_search_trgm")
+	assert.Contains(t, first.Up, "idx_events_status_date")
+	assert.Contains(t, first.Up, "idx_events_city_date")
+}
+
+// TestMigrator_Postgres tests applying, listing and reverting the embedded
+// migrations, with two migrators racing as replicas starting together would.
+// It needs a real Postgres and is skipped unless TEST_DATABASE_DSN is set; it
+// works in a throwaway schema and leaves the rest of the database alone.
+func TestMigrator_Postgres(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, schema := openTestSchema(t, ctx)
+
+	migrator, err := New(db)
+	require.NoError(t, err)
+
+	// Both migrators wait for the lock; only one of them applies anything
+	var wg sync.WaitGroup
+	applied := make([][]Migration, 2)
+	errs := make([]error, 2)
+	for i := range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			applied[i], errs[i] = migrator.Up(ctx)
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, errs[0])
+	require.NoError(t, errs[1])
+	assert.Equal(t, len(migrator.migrations), len(applied[0])+len(applied[1]), "Each migration is applied exactly once")
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.NotNil(t, status.AppliedAt, "Migration %d is applied", status.Version)
+	}
+
+	var indexes int
+	err = db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM pg_indexes WHERE schemaname = $1 AND indexname IN ('idx_events_search_trgm', 'idx_events_city_date')",
+		schema).Scan(&indexes)
+	require.NoError(t, err)
+	assert.Equal(t, 2, indexes)
+
+	// Reverting every migration leaves only the bookkeeping table
+	for range migrator.migrations {
+		reverted, err := migrator.Down(ctx)
+		require.NoError(t, err)
+		require.NotNil(t, reverted)
+	}
+	reverted, err := migrator.Down(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, reverted, "Nothing is left to revert")
+
+	var tables int
+	err = db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = $1 AND table_name <> 'schema_migrations'",
+		schema).Scan(&tables)
+	require.NoError(t, err)
+	assert.Zero(t, tables)
+
+	// And the migrations apply cleanly again
+	applied[0], err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied[0], len(migrator.migrations))
+}
+
+// openTestSchema connects to the database in TEST_DATABASE_DSN with a throwaway
+// schema first on the search path, dropping the schema when the test ends. It
+// skips the test when TEST_DATABASE_DSN isn't set.
+func openTestSchema(t *testing.T, ctx context.Context) (*sql.DB, string) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN not set; skipping Postgres migration test")
+	}
+
+	admin, err := sql.Open("pgx", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("migrations_test_%d", time.Now().UnixNano())
+	_, err = admin.ExecContext(ctx, "CREATE SCHEMA "+schema)
+	require.NoError(t, err)
+	t.Cleanup(func() { admin.ExecContext(context.Background(), "DROP SCHEMA "+schema+" CASCADE") })
+
+	// public stays on the path for pg_trgm's operator classes
+	db, err := sql.Open("pgx", dsn+" search_path="+schema+",public")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return db, schema
+}
+
+// baselineSchema is what AutoMigrate created for users, events and bookings
+// before roles, email verification, seat maps and ticket tiers existed
+const baselineSchema = `
+CREATE TABLE users (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    username text NOT NULL,
+    email text NOT NULL,
+    password text NOT NULL
+);
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
+CREATE UNIQUE INDEX idx_users_username ON users (username);
+CREATE UNIQUE INDEX idx_users_email ON users (email);
+
+CREATE TABLE events (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    name text NOT NULL,
+    description text,
+    event_type text,
+    status text DEFAULT 'published',
+    organizer_id bigint NOT NULL,
+    venue_name text,
+    city text,
+    address text,
+    date timestamptz NOT NULL,
+    price decimal DEFAULT 0,
+    capacity bigint NOT NULL,
+    image_url text,
+    CONSTRAINT fk_events_organizer FOREIGN KEY (organizer_id) REFERENCES users (id)
+);
+CREATE INDEX idx_events_deleted_at ON events (deleted_at);
+
+CREATE TABLE bookings (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL,
+    event_id bigint NOT NULL,
+    quantity bigint NOT NULL,
+    price_per_ticket decimal,
+    total_price decimal,
+    status text DEFAULT 'confirmed',
+    CONSTRAINT fk_users_bookings FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_events_bookings FOREIGN KEY (event_id) REFERENCES events (id)
+);
+CREATE INDEX idx_bookings_deleted_at ON bookings (deleted_at);
+
+INSERT INTO users (username, email, password) VALUES ('organizer', 'organizer@example.com', 'hash');
+INSERT INTO users (username, email, password) VALUES ('attendee', 'attendee@example.com', 'hash');
+INSERT INTO events (name, organizer_id, date, capacity) VALUES ('Jazz Night', 1, now(), 100);
+INSERT INTO bookings (user_id, event_id, quantity) VALUES (1, 1, 2);
+`
+
+// TestMigrator_Baseline tests that a database AutoMigrate built before
+// migrations existed adopts them, gaining the columns and foreign keys added
+// since, keeping its data and promoting its event organizers. Like TestMigrator_Postgres it needs TEST_DATABASE_DSN.
+func TestMigrator_Baseline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, schema := openTestSchema(t, ctx)
+	_, err := db.ExecContext(ctx, baselineSchema)
+	require.NoError(t, err)
+
+	migrator, err := New(db)
+	require.NoError(t, err)
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, len(migrator.migrations))
+
+	for _, column := range [][2]string{
+		{"users", "role"},
+		{"users", "email_verified_at"},
+		{"events", "venue_id"},
+		{"bookings", "tier_id"},
+	} {
+		var count int
+		err := db.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = $1 AND table_name = $2 AND column_name = $3",
+			schema, column[0], column[1]).Scan(&count)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count, "%s.%s is added", column[0], column[1])
+	}
+
+	var constraints int
+	err = db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM information_schema.table_constraints WHERE table_schema = $1 AND constraint_name IN ('fk_events_venue', 'fk_bookings_tier')",
+		schema).Scan(&constraints)
+	require.NoError(t, err)
+	assert.Equal(t, 2, constraints)
+
+	var bookings int
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT COUNT(*) FROM bookings WHERE tier_id IS NULL").Scan(&bookings))
+	assert.Equal(t, 1, bookings, "Existing bookings are kept")
+
+	roles := make(map[string]string)
+	rows, err := db.QueryContext(ctx, "SELECT username, role FROM users")
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var username, role string
+		require.NoError(t, rows.Scan(&username, &role))
+		roles[username] = role
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, map[string]string{"organizer": "organizer", "attendee": "user"}, roles,
+		"Users who organize events become organizers")
+}
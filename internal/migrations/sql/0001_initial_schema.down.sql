@@ -0,0 +1,16 @@
+-- Drops every table of the initial schema, and all data with them. pg_trgm
+-- stays installed, since other schemas of the database may use it.
+DROP TABLE IF EXISTS account_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS waitlist_entries;
+DROP TABLE IF EXISTS holds;
+DROP TABLE IF EXISTS tickets;
+DROP TABLE IF EXISTS booking_seats;
+DROP TABLE IF EXISTS bookings;
+DROP TABLE IF EXISTS ticket_tiers;
+DROP TABLE IF EXISTS events;
+DROP TABLE IF EXISTS seats;
+DROP TABLE IF EXISTS sections;
+DROP TABLE IF EXISTS venues;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,251 @@
+-- Initial schema, matching what GORM's AutoMigrate created before migrations
+-- existed. Every statement is guarded with IF NOT EXISTS so databases created
+-- by AutoMigrate adopt this migration. Those databases may predate roles, email
+-- verification, seat maps and ticket tiers, so the columns and foreign keys
+-- those added to users, events and bookings are added separately when missing.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    username text NOT NULL,
+    email text NOT NULL,
+    password text NOT NULL,
+    role text NOT NULL DEFAULT 'user',
+    email_verified_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role text NOT NULL DEFAULT 'user',
+    ADD COLUMN IF NOT EXISTS email_verified_at timestamptz;
+
+CREATE TABLE IF NOT EXISTS venues (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    name text NOT NULL,
+    city text,
+    address text
+);
+CREATE INDEX IF NOT EXISTS idx_venues_deleted_at ON venues (deleted_at);
+
+CREATE TABLE IF NOT EXISTS sections (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    venue_id bigint NOT NULL,
+    name text NOT NULL,
+    CONSTRAINT fk_venues_sections FOREIGN KEY (venue_id) REFERENCES venues (id)
+);
+CREATE INDEX IF NOT EXISTS idx_sections_deleted_at ON sections (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_sections_venue_id ON sections (venue_id);
+
+CREATE TABLE IF NOT EXISTS seats (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    section_id bigint NOT NULL,
+    "row" text NOT NULL,
+    number bigint NOT NULL,
+    CONSTRAINT fk_sections_seats FOREIGN KEY (section_id) REFERENCES sections (id)
+);
+CREATE INDEX IF NOT EXISTS idx_seats_deleted_at ON seats (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_seats_section_row_number ON seats (section_id, "row", number);
+
+CREATE TABLE IF NOT EXISTS events (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    name text NOT NULL,
+    description text,
+    event_type text,
+    status text DEFAULT 'published',
+    organizer_id bigint NOT NULL,
+    venue_name text,
+    city text,
+    address text,
+    date timestamptz NOT NULL,
+    price decimal DEFAULT 0,
+    capacity bigint NOT NULL,
+    image_url text,
+    venue_id bigint,
+    CONSTRAINT fk_events_organizer FOREIGN KEY (organizer_id) REFERENCES users (id),
+    CONSTRAINT fk_events_venue FOREIGN KEY (venue_id) REFERENCES venues (id)
+);
+ALTER TABLE events ADD COLUMN IF NOT EXISTS venue_id bigint;
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_events_venue' AND conrelid = 'events'::regclass) THEN
+        ALTER TABLE events ADD CONSTRAINT fk_events_venue FOREIGN KEY (venue_id) REFERENCES venues (id);
+    END IF;
+END $$;
+CREATE INDEX IF NOT EXISTS idx_events_deleted_at ON events (deleted_at);
+
+-- Trigram index for full-text search across multiple fields
+CREATE INDEX IF NOT EXISTS idx_events_search_trgm ON events
+    USING gin ((name || ' ' || description || ' ' || venue_name || ' ' || city) gin_trgm_ops);
+
+-- Individual field indexes for filtering
+CREATE INDEX IF NOT EXISTS idx_events_event_type ON events (event_type);
+CREATE INDEX IF NOT EXISTS idx_events_city ON events (city);
+CREATE INDEX IF NOT EXISTS idx_events_date ON events (date);
+CREATE INDEX IF NOT EXISTS idx_events_price ON events (price);
+CREATE INDEX IF NOT EXISTS idx_events_status ON events (status);
+
+-- Composite indexes for common filter combinations
+CREATE INDEX IF NOT EXISTS idx_events_status_date ON events (status, date);
+CREATE INDEX IF NOT EXISTS idx_events_city_date ON events (city, date);
+
+CREATE TABLE IF NOT EXISTS ticket_tiers (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    event_id bigint NOT NULL,
+    name text NOT NULL,
+    price decimal DEFAULT 0,
+    capacity bigint NOT NULL,
+    sales_start timestamptz,
+    sales_end timestamptz,
+    CONSTRAINT fk_events_tiers FOREIGN KEY (event_id) REFERENCES events (id)
+);
+CREATE INDEX IF NOT EXISTS idx_ticket_tiers_deleted_at ON ticket_tiers (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_ticket_tiers_event_id ON ticket_tiers (event_id);
+
+CREATE TABLE IF NOT EXISTS bookings (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL,
+    event_id bigint NOT NULL,
+    tier_id bigint,
+    quantity bigint NOT NULL,
+    price_per_ticket decimal,
+    total_price decimal,
+    status text DEFAULT 'confirmed',
+    CONSTRAINT fk_users_bookings FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_events_bookings FOREIGN KEY (event_id) REFERENCES events (id),
+    CONSTRAINT fk_bookings_tier FOREIGN KEY (tier_id) REFERENCES ticket_tiers (id)
+);
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS tier_id bigint;
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_bookings_tier' AND conrelid = 'bookings'::regclass) THEN
+        ALTER TABLE bookings ADD CONSTRAINT fk_bookings_tier FOREIGN KEY (tier_id) REFERENCES ticket_tiers (id);
+    END IF;
+END $$;
+CREATE INDEX IF NOT EXISTS idx_bookings_deleted_at ON bookings (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_bookings_tier_id ON bookings (tier_id);
+
+CREATE TABLE IF NOT EXISTS booking_seats (
+    id bigserial PRIMARY KEY,
+    booking_id bigint NOT NULL,
+    event_id bigint NOT NULL,
+    seat_id bigint NOT NULL,
+    created_at timestamptz,
+    CONSTRAINT fk_bookings_seats FOREIGN KEY (booking_id) REFERENCES bookings (id),
+    CONSTRAINT fk_booking_seats_seat FOREIGN KEY (seat_id) REFERENCES seats (id)
+);
+CREATE INDEX IF NOT EXISTS idx_booking_seats_booking_id ON booking_seats (booking_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_booking_seats_event_seat ON booking_seats (event_id, seat_id);
+
+CREATE TABLE IF NOT EXISTS tickets (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    booking_id bigint NOT NULL,
+    event_id bigint NOT NULL,
+    code text NOT NULL,
+    scanned_at timestamptz,
+    scanned_by bigint,
+    CONSTRAINT fk_bookings_tickets FOREIGN KEY (booking_id) REFERENCES bookings (id)
+);
+CREATE INDEX IF NOT EXISTS idx_tickets_deleted_at ON tickets (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_tickets_booking_id ON tickets (booking_id);
+CREATE INDEX IF NOT EXISTS idx_tickets_event_id ON tickets (event_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tickets_code ON tickets (code);
+
+CREATE TABLE IF NOT EXISTS holds (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL,
+    event_id bigint NOT NULL,
+    tier_id bigint,
+    quantity bigint NOT NULL,
+    status text DEFAULT 'active',
+    expires_at timestamptz NOT NULL,
+    booking_id bigint
+);
+CREATE INDEX IF NOT EXISTS idx_holds_deleted_at ON holds (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_holds_user_id ON holds (user_id);
+CREATE INDEX IF NOT EXISTS idx_holds_event_id ON holds (event_id);
+CREATE INDEX IF NOT EXISTS idx_holds_tier_id ON holds (tier_id);
+CREATE INDEX IF NOT EXISTS idx_holds_status ON holds (status);
+CREATE INDEX IF NOT EXISTS idx_holds_expires_at ON holds (expires_at);
+
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL,
+    event_id bigint NOT NULL,
+    tier_id bigint,
+    quantity bigint NOT NULL,
+    status text DEFAULT 'waiting',
+    hold_id bigint,
+    offer_expires_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_deleted_at ON waitlist_entries (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_user_id ON waitlist_entries (user_id);
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_event_id ON waitlist_entries (event_id);
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_status ON waitlist_entries (status);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL,
+    revoked_at timestamptz,
+    revoked_reason text
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_deleted_at ON sessions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id bigserial PRIMARY KEY,
+    session_id bigint NOT NULL,
+    token_hash text NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens (session_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+
+CREATE TABLE IF NOT EXISTS account_tokens (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    purpose text NOT NULL,
+    token_hash text NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_account_tokens_user_id ON account_tokens (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_account_tokens_token_hash ON account_tokens (token_hash);
@@ -0,0 +1,3 @@
+-- Roles granted by the backfill can't be told apart from ones assigned since,
+-- so reverting keeps every user's role as it is.
+SELECT 1;
@@ -0,0 +1,6 @@
+-- Organizer routes need the organizer role, which every user created before
+-- roles existed lacks. Promote the users who already organize events so they
+-- keep managing them; admins are left alone.
+UPDATE users SET role = 'organizer'
+WHERE role = 'user'
+  AND id IN (SELECT organizer_id FROM events);
@@ -6,57 +6,245 @@ import (
 	"gorm.io/gorm"
 )
 
+// User roles. Organizers can create and manage their own events, admins can
+// moderate any event and assign roles.
+const (
+	RoleUser      = "user"
+	RoleOrganizer = "organizer"
+	RoleAdmin     = "admin"
+)
+
 type User struct {
 	gorm.Model
-	Username string    `json:"username" gorm:"uniqueIndex;not null"`
-	Email    string    `json:"email" gorm:"uniqueIndex;not null"`
-	Password string    `json:"-" gorm:"not null"`
-	Bookings []Booking `json:"bookings,omitempty" gorm:"foreignKey:UserID"`
+	Username        string     `json:"username" gorm:"uniqueIndex;not null"`
+	Email           string     `json:"email" gorm:"uniqueIndex;not null"`
+	Password        string     `json:"-" gorm:"not null"`
+	Role            string     `json:"role" gorm:"not null;default:'user'"` // user, organizer, admin
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	Bookings        []Booking  `json:"bookings,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// IsValidRole reports whether role is one of the known user roles
+func IsValidRole(role string) bool {
+	return role == RoleUser || role == RoleOrganizer || role == RoleAdmin
 }
 
 // Event represents a concert, tour, standup show, lecture, musical, etc.
 type Event struct {
 	gorm.Model
-	Name        string    `json:"name" gorm:"not null"`
-	Description string    `json:"description"`
-	EventType   string    `json:"event_type"`                        // concert, tour, standup, lecture, musical, etc.
-	Status      string    `json:"status" gorm:"default:'published'"` // draft, published, cancelled
-	OrganizerID uint      `json:"organizer_id" gorm:"not null"`
-	Organizer   User      `json:"organizer,omitempty" gorm:"foreignKey:OrganizerID"`
-	VenueName   string    `json:"venue_name"`
-	City        string    `json:"city"`
-	Address     string    `json:"address"`
-	Date        time.Time `json:"date" gorm:"not null"`
-	Price       float64   `json:"price" gorm:"default:0"` // Price per ticket, 0 for free events
-	Capacity    int       `json:"capacity" gorm:"not null"`
-	ImageURL    string    `json:"image_url"`
-	Bookings    []Booking `json:"bookings,omitempty" gorm:"foreignKey:EventID"`
+	Name        string       `json:"name" gorm:"not null"`
+	Description string       `json:"description"`
+	EventType   string       `json:"event_type"`                        // concert, tour, standup, lecture, musical, etc.
+	Status      string       `json:"status" gorm:"default:'published'"` // draft, published, cancelled
+	OrganizerID uint         `json:"organizer_id" gorm:"not null"`
+	Organizer   User         `json:"organizer,omitempty" gorm:"foreignKey:OrganizerID"`
+	VenueName   string       `json:"venue_name"`
+	City        string       `json:"city"`
+	Address     string       `json:"address"`
+	Date        time.Time    `json:"date" gorm:"not null"`
+	Price       float64      `json:"price" gorm:"default:0"` // Price per ticket (lowest tier price for tiered events), 0 for free events
+	Capacity    int          `json:"capacity" gorm:"not null"`
+	ImageURL    string       `json:"image_url"`
+	VenueID     *uint        `json:"venue_id,omitempty"` // set for events with reserved seating
+	Venue       *Venue       `json:"venue,omitempty" gorm:"foreignKey:VenueID"`
+	Tiers       []TicketTier `json:"tiers,omitempty" gorm:"foreignKey:EventID"`
+	Bookings    []Booking    `json:"bookings,omitempty" gorm:"foreignKey:EventID"`
 }
 
 // AvailableTickets calculates remaining tickets
 func (e *Event) AvailableTickets(db *gorm.DB) (int, error) {
-	var totalBooked int64
-	err := db.Model(&Booking{}).
-		Where("event_id = ? AND status = ?", e.ID, "confirmed").
-		Select("COALESCE(SUM(quantity), 0)").
-		Scan(&totalBooked).Error
+	totalBooked, err := bookedQuantity(db, "event_id", e.ID)
+	if err != nil {
+		return 0, err
+	}
 
+	// Tickets reserved by live holds are not available either
+	totalHeld, err := heldQuantity(db, "event_id", e.ID)
 	if err != nil {
 		return 0, err
 	}
 
-	return e.Capacity - int(totalBooked), nil
+	return e.Capacity - int(totalBooked) - int(totalHeld), nil
+}
+
+// TicketTier is a priced share of an event's capacity, such as GA, VIP or early-bird
+type TicketTier struct {
+	gorm.Model
+	EventID    uint       `json:"event_id" gorm:"not null;index"`
+	Name       string     `json:"name" gorm:"not null"`
+	Price      float64    `json:"price" gorm:"default:0"`
+	Capacity   int        `json:"capacity" gorm:"not null"`
+	SalesStart *time.Time `json:"sales_start,omitempty"` // nil means on sale immediately
+	SalesEnd   *time.Time `json:"sales_end,omitempty"`   // nil means on sale until the event
+}
+
+// OnSale reports whether the tier's sales window includes the given time
+func (t *TicketTier) OnSale(now time.Time) bool {
+	if t.SalesStart != nil && now.Before(*t.SalesStart) {
+		return false
+	}
+	if t.SalesEnd != nil && !now.Before(*t.SalesEnd) {
+		return false
+	}
+	return true
+}
+
+// AvailableTickets calculates remaining tickets in this tier
+func (t *TicketTier) AvailableTickets(db *gorm.DB) (int, error) {
+	totalBooked, err := bookedQuantity(db, "tier_id", t.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	totalHeld, err := heldQuantity(db, "tier_id", t.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return t.Capacity - int(totalBooked) - int(totalHeld), nil
+}
+
+// bookedQuantity sums confirmed booking quantities where column equals id
+func bookedQuantity(db *gorm.DB, column string, id uint) (int64, error) {
+	var total int64
+	err := db.Model(&Booking{}).
+		Where(column+" = ? AND status = ?", id, "confirmed").
+		Select("COALESCE(SUM(quantity), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+// heldQuantity sums live hold quantities where column equals id
+func heldQuantity(db *gorm.DB, column string, id uint) (int64, error) {
+	var total int64
+	err := db.Model(&Hold{}).
+		Where(column+" = ? AND status = ? AND expires_at > ?", id, "active", time.Now()).
+		Select("COALESCE(SUM(quantity), 0)").
+		Scan(&total).Error
+	return total, err
 }
 
 // Booking represents a user's ticket booking for an event
 type Booking struct {
 	gorm.Model
-	UserID         uint    `json:"user_id" gorm:"not null"`
-	EventID        uint    `json:"event_id" gorm:"not null"`
-	Quantity       int     `json:"quantity" gorm:"not null"`
-	PricePerTicket float64 `json:"price_per_ticket"`
-	TotalPrice     float64 `json:"total_price"`
-	Status         string  `json:"status" gorm:"default:'confirmed'"` // confirmed, cancelled
-	User           User    `json:"user,omitempty" gorm:"foreignKey:UserID"`
-	Event          Event   `json:"event,omitempty" gorm:"foreignKey:EventID"`
+	UserID         uint          `json:"user_id" gorm:"not null"`
+	EventID        uint          `json:"event_id" gorm:"not null"`
+	TierID         *uint         `json:"tier_id,omitempty" gorm:"index"`
+	Quantity       int           `json:"quantity" gorm:"not null"`
+	PricePerTicket float64       `json:"price_per_ticket"`
+	TotalPrice     float64       `json:"total_price"`
+	Status         string        `json:"status" gorm:"default:'confirmed'"` // confirmed, cancelled
+	User           User          `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Event          Event         `json:"event,omitempty" gorm:"foreignKey:EventID"`
+	Tier           *TicketTier   `json:"tier,omitempty" gorm:"foreignKey:TierID"`
+	Seats          []BookingSeat `json:"seats,omitempty" gorm:"foreignKey:BookingID"`
+	Tickets        []Ticket      `json:"tickets,omitempty" gorm:"foreignKey:BookingID"`
+}
+
+// Hold temporarily reserves tickets for a user until it is confirmed or expires
+type Hold struct {
+	gorm.Model
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	EventID   uint      `json:"event_id" gorm:"not null;index"`
+	TierID    *uint     `json:"tier_id,omitempty" gorm:"index"`
+	Quantity  int       `json:"quantity" gorm:"not null"`
+	Status    string    `json:"status" gorm:"default:'active';index"` // active, confirmed, expired
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	BookingID *uint     `json:"booking_id,omitempty"`
+}
+
+// WaitlistEntry queues a user for a sold-out event until tickets are offered to them
+type WaitlistEntry struct {
+	gorm.Model
+	UserID         uint       `json:"user_id" gorm:"not null;index"`
+	EventID        uint       `json:"event_id" gorm:"not null;index"`
+	TierID         *uint      `json:"tier_id,omitempty"`
+	Quantity       int        `json:"quantity" gorm:"not null"`
+	Status         string     `json:"status" gorm:"default:'waiting';index"` // waiting, offered, fulfilled, expired
+	HoldID         *uint      `json:"hold_id,omitempty"`
+	OfferExpiresAt *time.Time `json:"offer_expires_at,omitempty"`
+}
+
+// Venue is a physical location with a numbered seat map
+type Venue struct {
+	gorm.Model
+	Name     string    `json:"name" gorm:"not null"`
+	City     string    `json:"city"`
+	Address  string    `json:"address"`
+	Sections []Section `json:"sections,omitempty" gorm:"foreignKey:VenueID"`
+}
+
+// Section groups the seats of a venue, such as "Orchestra" or "Balcony"
+type Section struct {
+	gorm.Model
+	VenueID uint   `json:"venue_id" gorm:"not null;index"`
+	Name    string `json:"name" gorm:"not null"`
+	Seats   []Seat `json:"seats,omitempty" gorm:"foreignKey:SectionID"`
+}
+
+// Seat is a single numbered seat within a section
+type Seat struct {
+	gorm.Model
+	SectionID uint   `json:"section_id" gorm:"not null;uniqueIndex:idx_seats_section_row_number"`
+	Row       string `json:"row" gorm:"not null;uniqueIndex:idx_seats_section_row_number"`
+	Number    int    `json:"number" gorm:"not null;uniqueIndex:idx_seats_section_row_number"`
+}
+
+// BookingSeat assigns a seat to a booking. The unique index guarantees a seat
+// is never booked twice for the same event; rows are deleted on cancellation.
+type BookingSeat struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	BookingID uint      `json:"booking_id" gorm:"not null;index"`
+	EventID   uint      `json:"event_id" gorm:"not null;uniqueIndex:idx_booking_seats_event_seat"`
+	SeatID    uint      `json:"seat_id" gorm:"not null;uniqueIndex:idx_booking_seats_event_seat"`
+	Seat      *Seat     `json:"seat,omitempty" gorm:"foreignKey:SeatID"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Ticket is a single admission issued for a confirmed booking, identified by a signed code
+type Ticket struct {
+	gorm.Model
+	BookingID uint       `json:"booking_id" gorm:"not null;index"`
+	EventID   uint       `json:"event_id" gorm:"not null;index"`
+	Code      string     `json:"code" gorm:"not null;uniqueIndex"`
+	ScannedAt *time.Time `json:"scanned_at,omitempty"`
+	ScannedBy *uint      `json:"scanned_by,omitempty"`
+}
+
+// Session is one login of a user. Every refresh token rotated from that login
+// belongs to the same session, so revoking the session ends the whole token family.
+type Session struct {
+	gorm.Model
+	UserID        uint       `json:"user_id" gorm:"not null;index"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason string     `json:"revoked_reason,omitempty"` // logout, refresh_token_reuse, password_change
+}
+
+// RefreshToken is a single-use token exchanged for a new access token. Only a
+// SHA-256 hash of the token is stored.
+type RefreshToken struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	SessionID uint       `json:"session_id" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"` // set when rotated; presenting it again is reuse
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Account token purposes
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+)
+
+// AccountToken is a single-use, expiring token mailed to a user to reset their
+// password or verify their email address. Only a SHA-256 hash of the token is stored.
+type AccountToken struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	Purpose   string     `json:"purpose" gorm:"not null"` // password_reset, email_verification
+	TokenHash string     `json:"-" gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
 }
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alexs/golang_test/internal/models"
+	"github.com/alexs/golang_test/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AccountTokenRepository issues and redeems single-use password reset and
+// email verification tokens
+type AccountTokenRepository interface {
+	// Create issues a new token for the given purpose and returns the raw
+	// token. Earlier unused tokens of the same purpose stop working.
+	Create(ctx context.Context, userID uint, purpose string, ttl time.Duration) (string, error)
+
+	// ResetPassword sets a new password using a password reset token and signs
+	// the user out everywhere. Receiving the reset email also proves the
+	// address, so it is marked verified.
+	ResetPassword(ctx context.Context, token, newPassword string) error
+
+	// VerifyEmail marks a user's email address verified using an email verification token
+	VerifyEmail(ctx context.Context, token string) (*models.User, error)
+}
+
+// GormAccountTokenRepository is the AccountTokenRepository backed by the database
+type GormAccountTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewGormAccountTokenRepository creates an AccountTokenRepository using db
+func NewGormAccountTokenRepository(db *gorm.DB) *GormAccountTokenRepository {
+	return &GormAccountTokenRepository{db: db}
+}
+
+func (r *GormAccountTokenRepository) Create(ctx context.Context, userID uint, purpose string, ttl time.Duration) (string, error) {
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&models.AccountToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.AccountToken{
+			UserID:    userID,
+			Purpose:   purpose,
+			TokenHash: utils.HashToken(token),
+			ExpiresAt: now.Add(ttl),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// consumeAccountToken locks a token and marks it used, rejecting unknown, used and expired tokens
+func consumeAccountToken(tx *gorm.DB, token, purpose string) (*models.AccountToken, error) {
+	var accountToken models.AccountToken
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ? AND purpose = ?", utils.HashToken(token), purpose).
+		First(&accountToken).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invalid or expired token")
+		}
+		return nil, err
+	}
+
+	if accountToken.UsedAt != nil || time.Now().After(accountToken.ExpiresAt) {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	now := time.Now()
+	if err := tx.Model(&accountToken).Update("used_at", now).Error; err != nil {
+		return nil, err
+	}
+	accountToken.UsedAt = &now
+
+	return &accountToken, nil
+}
+
+func (r *GormAccountTokenRepository) ResetPassword(ctx context.Context, token, newPassword string) error {
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		accountToken, err := consumeAccountToken(tx, token, models.TokenPurposePasswordReset)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.User{}).Where("id = ?", accountToken.UserID).
+			Update("password", hashedPassword).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.User{}).Where("id = ? AND email_verified_at IS NULL", accountToken.UserID).
+			Update("email_verified_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		return revokeUserSessions(tx, accountToken.UserID, RevokeReasonPasswordChange)
+	})
+}
+
+func (r *GormAccountTokenRepository) VerifyEmail(ctx context.Context, token string) (*models.User, error) {
+	var user models.User
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		accountToken, err := consumeAccountToken(tx, token, models.TokenPurposeEmailVerification)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.First(&user, accountToken.UserID).Error; err != nil {
+			return err
+		}
+
+		if user.EmailVerifiedAt != nil {
+			return nil
+		}
+
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		return tx.Model(&user).Update("email_verified_at", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
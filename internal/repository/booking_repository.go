@@ -1,17 +1,55 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"time"
 
 	"github.com/alexs/golang_test/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-func CreateBooking(booking *models.Booking) error {
-	return DB.Transaction(func(tx *gorm.DB) error {
-		// Get the event to check availability
-		var event models.Event
-		if err := tx.First(&event, booking.EventID).Error; err != nil {
+// lockEventForUpdate loads an event with a row lock held until the transaction ends.
+// Every path that changes an event's availability takes this lock first, so
+// concurrent purchases for the same event are serialized instead of overselling.
+func lockEventForUpdate(tx *gorm.DB, eventID uint) (*models.Event, error) {
+	var event models.Event
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&event, eventID).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// BookingRepository stores bookings; creating and cancelling them keeps
+// availability, seats, tickets and the waitlist consistent
+type BookingRepository interface {
+	Create(ctx context.Context, booking *models.Booking) error
+	GetByID(ctx context.Context, id uint) (*models.Booking, error)
+	GetByUser(ctx context.Context, userID uint) ([]models.Booking, error)
+
+	// Cancel cancels a user's booking and returns the waitlist entries offered the freed tickets
+	Cancel(ctx context.Context, bookingID uint, userID uint, offerTTL time.Duration) ([]models.WaitlistEntry, error)
+
+	// GetEventTicketHolderIDs returns the users holding confirmed bookings for an event
+	GetEventTicketHolderIDs(ctx context.Context, eventID uint) ([]uint, error)
+}
+
+// GormBookingRepository is the BookingRepository backed by the database
+type GormBookingRepository struct {
+	db *gorm.DB
+}
+
+// NewGormBookingRepository creates a BookingRepository using db
+func NewGormBookingRepository(db *gorm.DB) *GormBookingRepository {
+	return &GormBookingRepository{db: db}
+}
+
+func (r *GormBookingRepository) Create(ctx context.Context, booking *models.Booking) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the event row to check availability
+		event, err := lockEventForUpdate(tx, booking.EventID)
+		if err != nil {
 			return err
 		}
 
@@ -26,35 +64,60 @@ func CreateBooking(booking *models.Booking) error {
 			return errors.New("not enough tickets available")
 		}
 
+		// Reserved seating events must book specific, free seats
+		if err := checkSeats(tx, event, booking); err != nil {
+			return err
+		}
+
+		// Tiered events are priced and limited per tier
+		price := event.Price
+		tier, err := resolveTier(tx, event, booking.TierID, booking.Quantity)
+		if err != nil {
+			return err
+		}
+		if tier != nil {
+			price = tier.Price
+		}
+
 		// Set pricing information
-		booking.PricePerTicket = event.Price
-		booking.TotalPrice = event.Price * float64(booking.Quantity)
+		booking.PricePerTicket = price
+		booking.TotalPrice = price * float64(booking.Quantity)
 		booking.Status = "confirmed"
 
-		// Create the booking
-		return tx.Create(booking).Error
+		// Create the booking (and its seat assignments)
+		if err := tx.Create(booking).Error; err != nil {
+			return err
+		}
+
+		return issueTickets(tx, booking)
 	})
 }
 
-func GetBookingByID(id uint) (*models.Booking, error) {
+func (r *GormBookingRepository) GetByID(ctx context.Context, id uint) (*models.Booking, error) {
 	var booking models.Booking
-	err := DB.Preload("Event").Preload("User").First(&booking, id).Error
+	err := r.db.WithContext(ctx).Preload("Event").Preload("User").Preload("Tier").Preload("Seats.Seat").Preload("Tickets").First(&booking, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &booking, nil
 }
 
-func GetUserBookings(userID uint) ([]models.Booking, error) {
+func (r *GormBookingRepository) GetByUser(ctx context.Context, userID uint) ([]models.Booking, error) {
 	var bookings []models.Booking
-	err := DB.Preload("Event").Where("user_id = ?", userID).Order("created_at DESC").Find(&bookings).Error
+	err := r.db.WithContext(ctx).Preload("Event").Preload("Tier").Preload("Seats.Seat").Where("user_id = ?", userID).Order("created_at DESC").Find(&bookings).Error
 	return bookings, err
 }
 
-func CancelBooking(bookingID uint, userID uint) error {
-	return DB.Transaction(func(tx *gorm.DB) error {
+// Cancel cancels a user's booking and offers the freed tickets to the
+// waitlist, returning the entries that received an offer
+func (r *GormBookingRepository) Cancel(ctx context.Context, bookingID uint, userID uint, offerTTL time.Duration) ([]models.WaitlistEntry, error) {
+	var offered []models.WaitlistEntry
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the booking so concurrent cancels see each other's status change
+		// instead of both freeing its tickets
 		var booking models.Booking
-		if err := tx.First(&booking, bookingID).Error; err != nil {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, bookingID).Error; err != nil {
 			return err
 		}
 
@@ -68,8 +131,39 @@ func CancelBooking(bookingID uint, userID uint) error {
 			return errors.New("booking is already cancelled")
 		}
 
+		// Lock the event before freeing its tickets
+		event, err := lockEventForUpdate(tx, booking.EventID)
+		if err != nil {
+			return err
+		}
+
 		// Update status to cancelled
 		booking.Status = "cancelled"
-		return tx.Save(&booking).Error
+		if err := tx.Save(&booking).Error; err != nil {
+			return err
+		}
+
+		// Release any reserved seats
+		if err := tx.Where("booking_id = ?", booking.ID).Delete(&models.BookingSeat{}).Error; err != nil {
+			return err
+		}
+
+		offered, err = promoteWaitlist(tx, event, offerTTL)
+		return err
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return offered, nil
+}
+
+// GetEventTicketHolderIDs returns the users holding confirmed bookings for an event
+func (r *GormBookingRepository) GetEventTicketHolderIDs(ctx context.Context, eventID uint) ([]uint, error) {
+	var userIDs []uint
+	err := r.db.WithContext(ctx).Model(&models.Booking{}).
+		Where("event_id = ? AND status = ?", eventID, "confirmed").
+		Distinct().
+		Pluck("user_id", &userIDs).Error
+	return userIDs, err
 }
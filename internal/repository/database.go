@@ -1,95 +1,77 @@
 package repository
 
 import (
-	"fmt"
-	"log"
+	"context"
+	"log/slog"
 	"os"
 
-	"github.com/alexs/golang_test/internal/models"
+	"github.com/alexs/golang_test/internal/config"
+	"github.com/alexs/golang_test/internal/migrations"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
-func ConnectDB() {
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=UTC",
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"),
-		os.Getenv("DB_PORT"),
-	)
-
+// ConnectDB opens the connection pool sized by cfg. Queries are logged
+// through slog with the context they run in.
+func ConnectDB(cfg config.DatabaseConfig) {
 	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	DB, err = gorm.Open(postgres.Open(cfg.DSN()), &gorm.Config{
+		Logger: newSlogLogger(cfg.SlowQueryThreshold),
+	})
 	if err != nil {
-		log.Fatal("Failed to connect to database. ", err)
+		slog.Error("Failed to connect to database", "error", err)
+		os.Exit(1)
 	}
 
-	log.Println("Connected to Database!")
-
-	// Auto-migrate the schemas
-	log.Println("Running Migrations...")
-	err = DB.AutoMigrate(&models.User{}, &models.Event{}, &models.Booking{})
+	sqlDB, err := DB.DB()
 	if err != nil {
-		log.Fatal("Failed to migrate database. ", err)
+		slog.Error("Failed to connect to database", "error", err)
+		os.Exit(1)
 	}
-	log.Println("Migrations completed!")
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
-	// Create indexes for search and filtering
-	log.Println("Creating indexes...")
-	err = CreateEventIndexes()
-	if err != nil {
-		log.Printf("Warning: Failed to create some indexes. %v", err)
-	} else {
-		log.Println("Indexes created successfully!")
-	}
+	slog.Info("Connected to database", "host", cfg.Host, "name", cfg.Name)
 }
 
-// CreateEventIndexes creates database indexes for event search and filtering performance
-func CreateEventIndexes() error {
-	// Enable PostgreSQL trigram extension for search optimization
-	if err := DB.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error; err != nil {
-		return fmt.Errorf("failed to create pg_trgm extension: %w", err)
-	}
-
-	// Trigram index for full-text search across multiple fields
-	if err := DB.Exec(`CREATE INDEX IF NOT EXISTS idx_events_search_trgm ON events
-		USING gin ((name || ' ' || description || ' ' || venue_name || ' ' || city) gin_trgm_ops)`).Error; err != nil {
-		return fmt.Errorf("failed to create search trigram index: %w", err)
-	}
-
-	// Individual field indexes for filtering
-	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_events_event_type ON events(event_type)").Error; err != nil {
-		return fmt.Errorf("failed to create event_type index: %w", err)
-	}
-
-	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_events_city ON events(city)").Error; err != nil {
-		return fmt.Errorf("failed to create city index: %w", err)
-	}
-
-	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_events_date ON events(date)").Error; err != nil {
-		return fmt.Errorf("failed to create date index: %w", err)
+// NewMigrator creates a schema migrator for the connected database
+func NewMigrator() (*migrations.Migrator, error) {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return nil, err
 	}
+	return migrations.New(sqlDB)
+}
 
-	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_events_price ON events(price)").Error; err != nil {
-		return fmt.Errorf("failed to create price index: %w", err)
+// MigrateDB applies the pending schema migrations. Replicas starting together
+// take turns, so each migration runs once.
+func MigrateDB(ctx context.Context) error {
+	migrator, err := NewMigrator()
+	if err != nil {
+		return err
 	}
 
-	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_events_status ON events(status)").Error; err != nil {
-		return fmt.Errorf("failed to create status index: %w", err)
+	slog.InfoContext(ctx, "Running migrations")
+	applied, err := migrator.Up(ctx)
+	for _, migration := range applied {
+		slog.InfoContext(ctx, "Applied migration", "version", migration.Version, "name", migration.Name)
 	}
-
-	// Composite indexes for common filter combinations
-	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_events_status_date ON events(status, date)").Error; err != nil {
-		return fmt.Errorf("failed to create status_date composite index: %w", err)
+	if err != nil {
+		return err
 	}
+	slog.InfoContext(ctx, "Migrations completed")
+	return nil
+}
 
-	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_events_city_date ON events(city, date)").Error; err != nil {
-		return fmt.Errorf("failed to create city_date composite index: %w", err)
+// CloseDB closes the connection pool; call it once nothing uses DB anymore
+func CloseDB() error {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
 	}
-
-	return nil
+	return sqlDB.Close()
 }
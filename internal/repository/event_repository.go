@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"math"
 	"time"
 
@@ -11,8 +13,14 @@ import (
 type EventWithStats struct {
 	models.Event
 	TotalBooked int64 `gorm:"column:total_booked"`
+	TotalHeld   int64 `gorm:"column:total_held"`
 }
 
+// totalHeldSelect sums the tickets reserved by live holds for each event row
+const totalHeldSelect = `COALESCE((SELECT SUM(holds.quantity) FROM holds
+	WHERE holds.event_id = events.id AND holds.status = 'active' AND holds.expires_at > NOW()
+	AND holds.deleted_at IS NULL), 0) as total_held`
+
 // EventFilters contains all possible filtering, pagination, and sorting options for events
 type EventFilters struct {
 	Search    string
@@ -40,46 +48,194 @@ type PaginatedEventsResponse struct {
 	HasPrevious bool             `json:"has_previous"`
 }
 
-func CreateEvent(event *models.Event) error {
-	return DB.Create(event).Error
+// EventChanges lists the fields of an event to update; nil fields are left as they are
+type EventChanges struct {
+	Name        *string
+	Description *string
+	VenueName   *string
+	City        *string
+	Address     *string
+	Date        *time.Time
+	Price       *float64
+	Capacity    *int
+	ImageURL    *string
+	VenueID     *uint // attaching a seat map also sets Capacity to its seat count
+	Status      *string
 }
 
-func GetAllEvents() ([]models.Event, error) {
-	var events []models.Event
-	err := DB.Order("date ASC").Find(&events).Error
-	return events, err
+// EventRepository stores events and reports their availability
+type EventRepository interface {
+	Create(ctx context.Context, event *models.Event) error
+	GetByID(ctx context.Context, id uint) (*models.Event, error)
+
+	// Update applies changes to an event and returns it as saved
+	Update(ctx context.Context, id uint, changes EventChanges) (*models.Event, error)
+
+	Delete(ctx context.Context, id uint) error
+	GetWithFilters(ctx context.Context, filters EventFilters) (*PaginatedEventsResponse, error)
+
+	// AvailableTickets returns the tickets of an event that are neither booked nor held
+	AvailableTickets(ctx context.Context, event *models.Event) (int, error)
+
+	// GetTiersWithStats loads the tiers of the given events with their availability, cheapest first
+	GetTiersWithStats(ctx context.Context, eventIDs []uint) ([]TierWithStats, error)
 }
 
-func GetAllEventsWithStats() ([]EventWithStats, error) {
-	var results []EventWithStats
-	err := DB.Model(&models.Event{}).
-		Select("events.*, COALESCE(SUM(bookings.quantity), 0) as total_booked").
-		Joins("LEFT JOIN bookings ON bookings.event_id = events.id AND bookings.status = ?", "confirmed").
-		Group("events.id").
-		Order("events.date ASC").
-		Scan(&results).Error
-	return results, err
+// GormEventRepository is the EventRepository backed by the database
+type GormEventRepository struct {
+	db *gorm.DB
 }
 
-func GetEventByID(id uint) (*models.Event, error) {
+// NewGormEventRepository creates an EventRepository using db
+func NewGormEventRepository(db *gorm.DB) *GormEventRepository {
+	return &GormEventRepository{db: db}
+}
+
+func (r *GormEventRepository) Create(ctx context.Context, event *models.Event) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r *GormEventRepository) GetByID(ctx context.Context, id uint) (*models.Event, error) {
 	var event models.Event
-	err := DB.First(&event, id).Error
+	err := r.db.WithContext(ctx).First(&event, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &event, nil
 }
 
-func UpdateEvent(event *models.Event) error {
-	return DB.Save(event).Error
+// AvailableTickets returns the tickets of an event that are neither booked nor held
+func (r *GormEventRepository) AvailableTickets(ctx context.Context, event *models.Event) (int, error) {
+	return event.AvailableTickets(r.db.WithContext(ctx))
+}
+
+// Update applies changes to the locked event row, so concurrent updates,
+// bookings and holds are never overwritten with what the caller read earlier.
+// It refuses to lower capacity below the tickets already booked or held, or
+// below the combined capacity of the event's tiers. Confirming a hold doesn't
+// check availability again, so held tickets must stay within capacity.
+func (r *GormEventRepository) Update(ctx context.Context, id uint, changes EventChanges) (*models.Event, error) {
+	var event *models.Event
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the event so no booking or hold can slip in between the check and the save
+		current, err := lockEventForUpdate(tx, id)
+		if err != nil {
+			return err
+		}
+		event = current
+
+		// Seat maps set the capacity of their events
+		if changes.Capacity != nil && changes.VenueID == nil && current.VenueID != nil {
+			return errors.New("capacity is set by the venue's seat map")
+		}
+
+		updates := make(map[string]interface{})
+		if changes.Name != nil {
+			current.Name = *changes.Name
+			updates["name"] = current.Name
+		}
+		if changes.Description != nil {
+			current.Description = *changes.Description
+			updates["description"] = current.Description
+		}
+		if changes.VenueName != nil {
+			current.VenueName = *changes.VenueName
+			updates["venue_name"] = current.VenueName
+		}
+		if changes.City != nil {
+			current.City = *changes.City
+			updates["city"] = current.City
+		}
+		if changes.Address != nil {
+			current.Address = *changes.Address
+			updates["address"] = current.Address
+		}
+		if changes.Date != nil {
+			current.Date = *changes.Date
+			updates["date"] = current.Date
+		}
+		if changes.Price != nil {
+			current.Price = *changes.Price
+			updates["price"] = current.Price
+		}
+		if changes.ImageURL != nil {
+			current.ImageURL = *changes.ImageURL
+			updates["image_url"] = current.ImageURL
+		}
+		if changes.Status != nil {
+			current.Status = *changes.Status
+			updates["status"] = current.Status
+		}
+
+		var totalBooked int64
+		err = tx.Model(&models.Booking{}).
+			Where("event_id = ? AND status = ?", id, "confirmed").
+			Select("COALESCE(SUM(quantity), 0)").
+			Scan(&totalBooked).Error
+		if err != nil {
+			return err
+		}
+
+		// Existing bookings would not have seats in a different seat map
+		if changes.VenueID != nil && (current.VenueID == nil || *current.VenueID != *changes.VenueID) {
+			if totalBooked > 0 {
+				return errors.New("cannot change the seat map of an event with bookings")
+			}
+			current.VenueID = changes.VenueID
+			updates["venue_id"] = *current.VenueID
+		}
+
+		if changes.Capacity != nil && *changes.Capacity != current.Capacity {
+			var totalHeld int64
+			err = tx.Model(&models.Hold{}).
+				Where("event_id = ? AND status = ? AND expires_at > ?", id, "active", time.Now()).
+				Select("COALESCE(SUM(quantity), 0)").
+				Scan(&totalHeld).Error
+			if err != nil {
+				return err
+			}
+
+			if *changes.Capacity < int(totalBooked+totalHeld) {
+				return errors.New("capacity cannot be lower than tickets already booked or held")
+			}
+
+			// Tiers share the event's capacity, so together they must still fit in it
+			var tierCapacity int64
+			err = tx.Model(&models.TicketTier{}).
+				Where("event_id = ?", id).
+				Select("COALESCE(SUM(capacity), 0)").
+				Scan(&tierCapacity).Error
+			if err != nil {
+				return err
+			}
+
+			if *changes.Capacity < int(tierCapacity) {
+				return errors.New("capacity cannot be lower than the combined capacity of its tiers")
+			}
+
+			current.Capacity = *changes.Capacity
+			updates["capacity"] = current.Capacity
+		}
+
+		if len(updates) == 0 {
+			return nil
+		}
+		return tx.Model(current).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return event, nil
 }
 
-func DeleteEvent(id uint) error {
-	return DB.Delete(&models.Event{}, id).Error
+func (r *GormEventRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Event{}, id).Error
 }
 
-// GetEventsWithFilters retrieves events with search, filtering, pagination, and sorting
-func GetEventsWithFilters(filters EventFilters) (*PaginatedEventsResponse, error) {
+// GetWithFilters retrieves events with search, filtering, pagination, and sorting
+func (r *GormEventRepository) GetWithFilters(ctx context.Context, filters EventFilters) (*PaginatedEventsResponse, error) {
 	// Apply defaults
 	if filters.Page < 1 {
 		filters.Page = 1
@@ -92,8 +248,8 @@ func GetEventsWithFilters(filters EventFilters) (*PaginatedEventsResponse, error
 	}
 
 	// Start with base query joining bookings for stats
-	query := DB.Model(&models.Event{}).
-		Select("events.*, COALESCE(SUM(bookings.quantity), 0) as total_booked").
+	query := r.db.WithContext(ctx).Model(&models.Event{}).
+		Select("events.*, COALESCE(SUM(bookings.quantity), 0) as total_booked, "+totalHeldSelect).
 		Joins("LEFT JOIN bookings ON bookings.event_id = events.id AND bookings.status = ?", "confirmed").
 		Group("events.id")
 
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// slogLogger writes GORM's logs through slog with the query's context, so
+// each SQL statement carries the request ID and user of the request that ran
+// it. Failed queries are errors and slow queries warnings; every other query
+// is only logged at debug level.
+type slogLogger struct {
+	slowThreshold time.Duration
+	level         logger.LogLevel
+}
+
+// newSlogLogger creates a GORM logger that warns about queries slower than slowThreshold
+func newSlogLogger(slowThreshold time.Duration) logger.Interface {
+	return &slogLogger{slowThreshold: slowThreshold, level: logger.Info}
+}
+
+func (l *slogLogger) LogMode(level logger.LogLevel) logger.Interface {
+	copied := *l
+	copied.level = level
+	return &copied
+}
+
+func (l *slogLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Info {
+		slog.InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *slogLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Warn {
+		slog.WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *slogLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Error {
+		slog.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *slogLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	attrs := func() []any {
+		sql, rows := fc()
+		return []any{"sql", sql, "rows", rows, "duration_ms", float64(elapsed.Microseconds()) / 1000}
+	}
+
+	switch {
+	// Lookups of missing rows are answered with 404s, not logged as failures
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= logger.Error:
+		slog.ErrorContext(ctx, "Query failed", append(attrs(), "error", err)...)
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= logger.Warn:
+		slog.WarnContext(ctx, "Slow query", attrs()...)
+	case l.level >= logger.Info && slog.Default().Enabled(ctx, slog.LevelDebug):
+		slog.DebugContext(ctx, "Query", attrs()...)
+	}
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alexs/golang_test/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HoldRepository stores timed ticket holds
+type HoldRepository interface {
+	// Create reserves tickets for a user until hold.ExpiresAt
+	Create(ctx context.Context, hold *models.Hold, ttl time.Duration) error
+
+	// Confirm converts an active hold into a confirmed booking in a single
+	// transaction, unless its event has since been cancelled or deleted
+	Confirm(ctx context.Context, holdID uint, userID uint) (*models.Booking, error)
+
+	// Expire marks every active hold past its expiry as expired and
+	// returns the IDs of the events whose availability changed
+	Expire(ctx context.Context) ([]uint, error)
+}
+
+// GormHoldRepository is the HoldRepository backed by the database
+type GormHoldRepository struct {
+	db *gorm.DB
+}
+
+// NewGormHoldRepository creates a HoldRepository using db
+func NewGormHoldRepository(db *gorm.DB) *GormHoldRepository {
+	return &GormHoldRepository{db: db}
+}
+
+func (r *GormHoldRepository) Create(ctx context.Context, hold *models.Hold, ttl time.Duration) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the event row to check availability
+		event, err := lockEventForUpdate(tx, hold.EventID)
+		if err != nil {
+			return err
+		}
+
+		// Reserved seating events are booked seat by seat
+		if event.VenueID != nil {
+			return errors.New("event uses reserved seating")
+		}
+
+		// Available tickets already exclude other live holds
+		available, err := event.AvailableTickets(tx)
+		if err != nil {
+			return err
+		}
+
+		if available < hold.Quantity {
+			return errors.New("not enough tickets available")
+		}
+
+		if _, err := resolveTier(tx, event, hold.TierID, hold.Quantity); err != nil {
+			return err
+		}
+
+		return createHold(tx, hold, ttl)
+	})
+}
+
+// createHold inserts an active hold; the caller must already have checked availability
+func createHold(tx *gorm.DB, hold *models.Hold, ttl time.Duration) error {
+	hold.Status = "active"
+	hold.ExpiresAt = time.Now().Add(ttl)
+
+	return tx.Create(hold).Error
+}
+
+func (r *GormHoldRepository) Confirm(ctx context.Context, holdID uint, userID uint) (*models.Booking, error) {
+	var booking models.Booking
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the hold so it cannot be confirmed twice or expired underneath us
+		var hold models.Hold
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&hold, holdID).Error; err != nil {
+			return err
+		}
+
+		// Verify the hold belongs to the user
+		if hold.UserID != userID {
+			return errors.New("unauthorized to confirm this hold")
+		}
+
+		if hold.Status != "active" || !hold.ExpiresAt.After(time.Now()) {
+			return errors.New("hold is no longer active")
+		}
+
+		// Lock the event too, so it can't be cancelled or deleted while the
+		// hold turns into a booking
+		event, err := lockEventForUpdate(tx, hold.EventID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && event.Status == "cancelled") {
+			return errors.New("event is no longer available")
+		}
+		if err != nil {
+			return err
+		}
+
+		price := event.Price
+		if hold.TierID != nil {
+			var tier models.TicketTier
+			if err := tx.First(&tier, *hold.TierID).Error; err != nil {
+				return err
+			}
+			price = tier.Price
+		}
+
+		// The held tickets are already reserved, and capacity can't drop below
+		// them, so no availability check is needed
+		booking = models.Booking{
+			UserID:         hold.UserID,
+			EventID:        hold.EventID,
+			TierID:         hold.TierID,
+			Quantity:       hold.Quantity,
+			PricePerTicket: price,
+			TotalPrice:     price * float64(hold.Quantity),
+			Status:         "confirmed",
+		}
+		if err := tx.Create(&booking).Error; err != nil {
+			return err
+		}
+
+		if err := issueTickets(tx, &booking); err != nil {
+			return err
+		}
+
+		hold.Status = "confirmed"
+		hold.BookingID = &booking.ID
+		if err := tx.Save(&hold).Error; err != nil {
+			return err
+		}
+
+		// A hold offered from the waitlist fulfils that waitlist entry
+		return tx.Model(&models.WaitlistEntry{}).
+			Where("hold_id = ? AND status = ?", hold.ID, "offered").
+			Update("status", "fulfilled").Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &booking, nil
+}
+
+func (r *GormHoldRepository) Expire(ctx context.Context) ([]uint, error) {
+	var eventIDs []uint
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var expired []models.Hold
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND expires_at <= ?", "active", time.Now()).
+			Find(&expired).Error
+		if err != nil || len(expired) == 0 {
+			return err
+		}
+
+		holdIDs := make([]uint, 0, len(expired))
+		seen := make(map[uint]bool)
+		for _, hold := range expired {
+			holdIDs = append(holdIDs, hold.ID)
+			if !seen[hold.EventID] {
+				seen[hold.EventID] = true
+				eventIDs = append(eventIDs, hold.EventID)
+			}
+		}
+
+		if err := tx.Model(&models.Hold{}).Where("id IN ?", holdIDs).Update("status", "expired").Error; err != nil {
+			return err
+		}
+
+		// Waitlist offers that were never confirmed lapse with their hold
+		return tx.Model(&models.WaitlistEntry{}).
+			Where("hold_id IN ? AND status = ?", holdIDs, "offered").
+			Update("status", "expired").Error
+	})
+
+	return eventIDs, err
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/alexs/golang_test/internal/models"
+	"gorm.io/gorm"
+)
+
+// EventSales summarizes an event's ticket sales for the organizer dashboard
+type EventSales struct {
+	EventID             uint    `gorm:"column:event_id"`
+	EventName           string  `gorm:"column:event_name"`
+	OrganizerID         uint    `gorm:"column:organizer_id"`
+	Capacity            int     `gorm:"column:capacity"`
+	TicketsSold         int64   `gorm:"column:tickets_sold"`
+	TicketsHeld         int64   `gorm:"column:total_held"`
+	Revenue             float64 `gorm:"column:revenue"`
+	RecentBookings      int64   `gorm:"column:recent_bookings"`
+	RecentCancellations int64   `gorm:"column:recent_cancellations"`
+}
+
+// RemainingCapacity returns the tickets that are neither sold nor held
+func (s EventSales) RemainingCapacity() int {
+	return s.Capacity - int(s.TicketsSold) - int(s.TicketsHeld)
+}
+
+// SalesRepository loads the sales figures shown on organizer dashboards.
+// Bookings and cancellations within window of now count as recent.
+type SalesRepository interface {
+	// GetEventSales loads the sales figures of one event
+	GetEventSales(ctx context.Context, eventID uint, window time.Duration) (*EventSales, error)
+
+	// GetOrganizerSales loads the sales figures of every event an organizer owns, soonest first
+	GetOrganizerSales(ctx context.Context, organizerID uint, window time.Duration) ([]EventSales, error)
+}
+
+// GormSalesRepository is the SalesRepository backed by the database
+type GormSalesRepository struct {
+	db *gorm.DB
+}
+
+// NewGormSalesRepository creates a SalesRepository using db
+func NewGormSalesRepository(db *gorm.DB) *GormSalesRepository {
+	return &GormSalesRepository{db: db}
+}
+
+// salesQuery selects sales figures per event, counting bookings and
+// cancellations made after since as recent
+func (r *GormSalesRepository) salesQuery(ctx context.Context, since time.Time) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&models.Event{}).
+		Select(`events.id as event_id, events.name as event_name, events.organizer_id, events.capacity,
+			COALESCE((SELECT SUM(bookings.quantity) FROM bookings
+				WHERE bookings.event_id = events.id AND bookings.status = 'confirmed'
+				AND bookings.deleted_at IS NULL), 0) as tickets_sold,
+			COALESCE((SELECT SUM(bookings.total_price) FROM bookings
+				WHERE bookings.event_id = events.id AND bookings.status = 'confirmed'
+				AND bookings.deleted_at IS NULL), 0) as revenue,
+			(SELECT COUNT(*) FROM bookings
+				WHERE bookings.event_id = events.id AND bookings.created_at > ?
+				AND bookings.deleted_at IS NULL) as recent_bookings,
+			(SELECT COUNT(*) FROM bookings
+				WHERE bookings.event_id = events.id AND bookings.status = 'cancelled' AND bookings.updated_at > ?
+				AND bookings.deleted_at IS NULL) as recent_cancellations, `+totalHeldSelect, since, since)
+}
+
+func (r *GormSalesRepository) GetEventSales(ctx context.Context, eventID uint, window time.Duration) (*EventSales, error) {
+	var sales EventSales
+	result := r.salesQuery(ctx, time.Now().Add(-window)).
+		Where("events.id = ?", eventID).
+		Limit(1).
+		Scan(&sales)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &sales, nil
+}
+
+func (r *GormSalesRepository) GetOrganizerSales(ctx context.Context, organizerID uint, window time.Duration) ([]EventSales, error) {
+	var sales []EventSales
+	err := r.salesQuery(ctx, time.Now().Add(-window)).
+		Where("events.organizer_id = ?", organizerID).
+		Order("events.date ASC").
+		Scan(&sales).Error
+	return sales, err
+}
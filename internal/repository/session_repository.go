@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alexs/golang_test/internal/models"
+	"github.com/alexs/golang_test/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Session revocation reasons
+const (
+	RevokeReasonLogout         = "logout"
+	RevokeReasonReuse          = "refresh_token_reuse"
+	RevokeReasonPasswordChange = "password_change"
+	RevokeReasonRoleChange     = "role_change"
+)
+
+// SessionRepository stores login sessions and their rotating refresh tokens
+type SessionRepository interface {
+	// Create starts a login session and returns it with its first refresh token
+	Create(ctx context.Context, userID uint, refreshTTL time.Duration) (*models.Session, string, error)
+
+	// Rotate exchanges a refresh token for a new one in the same session.
+	// Presenting a token that was already rotated revokes the whole session.
+	Rotate(ctx context.Context, token string, refreshTTL time.Duration) (*models.Session, string, error)
+
+	// RevokeByRefreshToken ends the session a refresh token belongs to.
+	// Unknown tokens are ignored so logout stays idempotent.
+	RevokeByRefreshToken(ctx context.Context, token string) error
+
+	// IsActive reports whether a session exists and has not been revoked
+	IsActive(ctx context.Context, sessionID uint) (bool, error)
+}
+
+// GormSessionRepository is the SessionRepository backed by the database
+type GormSessionRepository struct {
+	db *gorm.DB
+}
+
+// NewGormSessionRepository creates a SessionRepository using db
+func NewGormSessionRepository(db *gorm.DB) *GormSessionRepository {
+	return &GormSessionRepository{db: db}
+}
+
+func (r *GormSessionRepository) Create(ctx context.Context, userID uint, refreshTTL time.Duration) (*models.Session, string, error) {
+	session := models.Session{UserID: userID}
+	var token string
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+
+		var err error
+		token, err = createRefreshToken(tx, session.ID, refreshTTL)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &session, token, nil
+}
+
+// createRefreshToken stores the hash of a new refresh token for a session and returns the raw token
+func createRefreshToken(tx *gorm.DB, sessionID uint, ttl time.Duration) (string, error) {
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	refresh := models.RefreshToken{
+		SessionID: sessionID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := tx.Create(&refresh).Error; err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Rotate revokes the whole session on reuse, since either the client or an
+// attacker is holding a stolen copy of the token
+func (r *GormSessionRepository) Rotate(ctx context.Context, token string, refreshTTL time.Duration) (*models.Session, string, error) {
+	var session models.Session
+	var newToken string
+	reused := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the token so concurrent refreshes with the same token serialize
+		var refresh models.RefreshToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", utils.HashToken(token)).
+			First(&refresh).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("invalid refresh token")
+			}
+			return err
+		}
+
+		if err := tx.First(&session, refresh.SessionID).Error; err != nil {
+			return err
+		}
+
+		if session.RevokedAt != nil {
+			return errors.New("session revoked")
+		}
+
+		if refresh.UsedAt != nil {
+			reused = true
+			return revokeSession(tx, &session, RevokeReasonReuse)
+		}
+
+		if time.Now().After(refresh.ExpiresAt) {
+			return errors.New("refresh token expired")
+		}
+
+		now := time.Now()
+		if err := tx.Model(&refresh).Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		newToken, err = createRefreshToken(tx, session.ID, refreshTTL)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The revocation has to commit before the request is rejected
+	if reused {
+		return nil, "", errors.New("refresh token reuse detected")
+	}
+
+	return &session, newToken, nil
+}
+
+func (r *GormSessionRepository) RevokeByRefreshToken(ctx context.Context, token string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var refresh models.RefreshToken
+		err := tx.Where("token_hash = ?", utils.HashToken(token)).First(&refresh).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		var session models.Session
+		if err := tx.First(&session, refresh.SessionID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		return revokeSession(tx, &session, RevokeReasonLogout)
+	})
+}
+
+// revokeUserSessions ends every active session of a user within tx, e.g. after
+// a password or role change
+func revokeUserSessions(tx *gorm.DB, userID uint, reason string) error {
+	return tx.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
+
+// revokeSession marks a session revoked, keeping the first revocation reason
+func revokeSession(tx *gorm.DB, session *models.Session, reason string) error {
+	if session.RevokedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	session.RevokedAt = &now
+	session.RevokedReason = reason
+	return tx.Model(session).Updates(map[string]interface{}{"revoked_at": now, "revoked_reason": reason}).Error
+}
+
+func (r *GormSessionRepository) IsActive(ctx context.Context, sessionID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Count(&count).Error
+	return count > 0, err
+}
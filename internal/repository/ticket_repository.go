@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alexs/golang_test/internal/models"
+	"github.com/alexs/golang_test/internal/utils"
+	"gorm.io/gorm"
+)
+
+// issueTickets creates one signed ticket per booked quantity inside the booking transaction
+func issueTickets(tx *gorm.DB, booking *models.Booking) error {
+	tickets := make([]models.Ticket, booking.Quantity)
+	for i := range tickets {
+		code, err := utils.GenerateTicketCode(booking.EventID, booking.ID)
+		if err != nil {
+			return err
+		}
+		tickets[i] = models.Ticket{
+			BookingID: booking.ID,
+			EventID:   booking.EventID,
+			Code:      code,
+		}
+	}
+
+	if err := tx.Create(&tickets).Error; err != nil {
+		return err
+	}
+
+	booking.Tickets = tickets
+	return nil
+}
+
+// TicketRepository stores the tickets issued for bookings
+type TicketRepository interface {
+	GetByID(ctx context.Context, id uint) (*models.Ticket, error)
+
+	// GetForBooking retrieves the tickets issued for a booking
+	GetForBooking(ctx context.Context, bookingID uint) ([]models.Ticket, error)
+
+	// CheckIn records a scan for a ticket of the given event, rejecting
+	// unknown, cancelled and already-scanned tickets
+	CheckIn(ctx context.Context, code string, eventID uint, scannedBy uint) (*models.Ticket, error)
+}
+
+// GormTicketRepository is the TicketRepository backed by the database
+type GormTicketRepository struct {
+	db *gorm.DB
+}
+
+// NewGormTicketRepository creates a TicketRepository using db
+func NewGormTicketRepository(db *gorm.DB) *GormTicketRepository {
+	return &GormTicketRepository{db: db}
+}
+
+func (r *GormTicketRepository) GetForBooking(ctx context.Context, bookingID uint) ([]models.Ticket, error) {
+	var tickets []models.Ticket
+	err := r.db.WithContext(ctx).Where("booking_id = ?", bookingID).Order("id ASC").Find(&tickets).Error
+	return tickets, err
+}
+
+func (r *GormTicketRepository) GetByID(ctx context.Context, id uint) (*models.Ticket, error) {
+	var ticket models.Ticket
+	err := r.db.WithContext(ctx).First(&ticket, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+func (r *GormTicketRepository) CheckIn(ctx context.Context, code string, eventID uint, scannedBy uint) (*models.Ticket, error) {
+	var ticket models.Ticket
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("code = ? AND event_id = ?", code, eventID).First(&ticket).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("ticket not found")
+			}
+			return err
+		}
+
+		var booking models.Booking
+		if err := tx.First(&booking, ticket.BookingID).Error; err != nil {
+			return err
+		}
+
+		if booking.Status != "confirmed" {
+			return errors.New("ticket booking is cancelled")
+		}
+
+		// Only the first concurrent scan can flip scanned_at from NULL
+		now := time.Now()
+		result := tx.Model(&models.Ticket{}).
+			Where("id = ? AND scanned_at IS NULL", ticket.ID).
+			Updates(map[string]interface{}{"scanned_at": now, "scanned_by": scannedBy})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return errors.New("ticket already scanned")
+		}
+
+		ticket.ScannedAt = &now
+		ticket.ScannedBy = &scannedBy
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ticket, nil
+}
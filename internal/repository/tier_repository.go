@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alexs/golang_test/internal/models"
+	"gorm.io/gorm"
+)
+
+// TierWithStats is a ticket tier with its booked and held ticket counts
+type TierWithStats struct {
+	models.TicketTier
+	TotalBooked int64 `gorm:"column:total_booked"`
+	TotalHeld   int64 `gorm:"column:total_held"`
+}
+
+// AvailableTickets returns the tickets left in the tier
+func (t TierWithStats) AvailableTickets() int {
+	return t.Capacity - int(t.TotalBooked) - int(t.TotalHeld)
+}
+
+// GetTiersWithStats loads the tiers of the given events with their availability, cheapest first
+func (r *GormEventRepository) GetTiersWithStats(ctx context.Context, eventIDs []uint) ([]TierWithStats, error) {
+	var results []TierWithStats
+	if len(eventIDs) == 0 {
+		return results, nil
+	}
+
+	err := r.db.WithContext(ctx).Model(&models.TicketTier{}).
+		Select(`ticket_tiers.*,
+			COALESCE((SELECT SUM(bookings.quantity) FROM bookings
+				WHERE bookings.tier_id = ticket_tiers.id AND bookings.status = 'confirmed'
+				AND bookings.deleted_at IS NULL), 0) as total_booked,
+			COALESCE((SELECT SUM(holds.quantity) FROM holds
+				WHERE holds.tier_id = ticket_tiers.id AND holds.status = 'active' AND holds.expires_at > NOW()
+				AND holds.deleted_at IS NULL), 0) as total_held`).
+		Where("ticket_tiers.event_id IN ?", eventIDs).
+		Order("ticket_tiers.price ASC").
+		Scan(&results).Error
+	return results, err
+}
+
+// resolveTier validates the requested tier for an event and checks it can fit
+// quantity. It returns nil for events sold without tiers.
+func resolveTier(tx *gorm.DB, event *models.Event, tierID *uint, quantity int) (*models.TicketTier, error) {
+	var tierCount int64
+	if err := tx.Model(&models.TicketTier{}).Where("event_id = ?", event.ID).Count(&tierCount).Error; err != nil {
+		return nil, err
+	}
+
+	if tierCount == 0 {
+		if tierID != nil {
+			return nil, errors.New("ticket tier not found")
+		}
+		return nil, nil
+	}
+
+	if tierID == nil {
+		return nil, errors.New("tier_id is required for this event")
+	}
+
+	var tier models.TicketTier
+	if err := tx.Where("id = ? AND event_id = ?", *tierID, event.ID).First(&tier).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("ticket tier not found")
+		}
+		return nil, err
+	}
+
+	if !tier.OnSale(time.Now()) {
+		return nil, errors.New("ticket tier is not on sale")
+	}
+
+	available, err := tier.AvailableTickets(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if available < quantity {
+		return nil, errors.New("not enough tickets available")
+	}
+
+	return &tier, nil
+}
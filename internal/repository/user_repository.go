@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"errors"
 
 	"github.com/alexs/golang_test/internal/models"
@@ -8,8 +9,28 @@ import (
 	"gorm.io/gorm"
 )
 
-// CreateUser creates a new user with hashed password
-func CreateUser(username, email, password string) (*models.User, error) {
+// UserRepository stores user accounts
+type UserRepository interface {
+	Create(ctx context.Context, username, email, password string) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	FindByUsername(ctx context.Context, username string) (*models.User, error)
+	FindByID(ctx context.Context, id uint) (*models.User, error)
+	UpdateRole(ctx context.Context, id uint, role string) (*models.User, error)
+	PromoteAdmins(ctx context.Context, emails []string) (int64, error)
+}
+
+// GormUserRepository is the UserRepository backed by the database
+type GormUserRepository struct {
+	db *gorm.DB
+}
+
+// NewGormUserRepository creates a UserRepository using db
+func NewGormUserRepository(db *gorm.DB) *GormUserRepository {
+	return &GormUserRepository{db: db}
+}
+
+// Create creates a new user with hashed password
+func (r *GormUserRepository) Create(ctx context.Context, username, email, password string) (*models.User, error) {
 	// Hash password
 	hashedPassword, err := utils.HashPassword(password)
 	if err != nil {
@@ -20,9 +41,10 @@ func CreateUser(username, email, password string) (*models.User, error) {
 		Username: username,
 		Email:    email,
 		Password: hashedPassword,
+		Role:     models.RoleUser,
 	}
 
-	result := DB.Create(user)
+	result := r.db.WithContext(ctx).Create(user)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -30,10 +52,10 @@ func CreateUser(username, email, password string) (*models.User, error) {
 	return user, nil
 }
 
-// FindUserByEmail retrieves a user by email
-func FindUserByEmail(email string) (*models.User, error) {
+// FindByEmail retrieves a user by email
+func (r *GormUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
-	result := DB.Where("email = ?", email).First(&user)
+	result := r.db.WithContext(ctx).Where("email = ?", email).First(&user)
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
@@ -45,10 +67,10 @@ func FindUserByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
-// FindUserByUsername retrieves a user by username
-func FindUserByUsername(username string) (*models.User, error) {
+// FindByUsername retrieves a user by username
+func (r *GormUserRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
-	result := DB.Where("username = ?", username).First(&user)
+	result := r.db.WithContext(ctx).Where("username = ?", username).First(&user)
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
@@ -60,10 +82,10 @@ func FindUserByUsername(username string) (*models.User, error) {
 	return &user, nil
 }
 
-// FindUserByID retrieves a user by ID
-func FindUserByID(id uint) (*models.User, error) {
+// FindByID retrieves a user by ID
+func (r *GormUserRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
 	var user models.User
-	result := DB.First(&user, id)
+	result := r.db.WithContext(ctx).First(&user, id)
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
@@ -74,3 +96,41 @@ func FindUserByID(id uint) (*models.User, error) {
 
 	return &user, nil
 }
+
+// UpdateRole changes a user's role. Access tokens carry the role they were
+// issued with, so the user is signed out everywhere and the new role takes
+// effect right away rather than when their tokens expire.
+func (r *GormUserRepository) UpdateRole(ctx context.Context, id uint, role string) (*models.User, error) {
+	user, err := r.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Role == role {
+		return user, nil
+	}
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("role", role).Error; err != nil {
+			return err
+		}
+		return revokeUserSessions(tx, user.ID, RevokeReasonRoleChange)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// PromoteAdmins grants the admin role to existing users with the given emails
+func (r *GormUserRepository) PromoteAdmins(ctx context.Context, emails []string) (int64, error) {
+	if len(emails) == 0 {
+		return 0, nil
+	}
+
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("email IN ? AND role <> ?", emails, models.RoleAdmin).
+		Update("role", models.RoleAdmin)
+	return result.RowsAffected, result.Error
+}
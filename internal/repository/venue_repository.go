@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alexs/golang_test/internal/models"
+	"gorm.io/gorm"
+)
+
+// VenueRepository stores venues and their seat maps
+type VenueRepository interface {
+	// Create creates a venue together with its sections and seats
+	Create(ctx context.Context, venue *models.Venue) error
+
+	// GetByID retrieves a venue with its full seat map
+	GetByID(ctx context.Context, id uint) (*models.Venue, error)
+
+	// CountSeats returns the number of seats in a venue's seat map
+	CountSeats(ctx context.Context, venueID uint) (int, error)
+
+	// GetBookedSeatIDs returns the IDs of seats taken by bookings for an event
+	GetBookedSeatIDs(ctx context.Context, eventID uint) ([]uint, error)
+}
+
+// GormVenueRepository is the VenueRepository backed by the database
+type GormVenueRepository struct {
+	db *gorm.DB
+}
+
+// NewGormVenueRepository creates a VenueRepository using db
+func NewGormVenueRepository(db *gorm.DB) *GormVenueRepository {
+	return &GormVenueRepository{db: db}
+}
+
+func (r *GormVenueRepository) Create(ctx context.Context, venue *models.Venue) error {
+	return r.db.WithContext(ctx).Create(venue).Error
+}
+
+func (r *GormVenueRepository) GetByID(ctx context.Context, id uint) (*models.Venue, error) {
+	var venue models.Venue
+	err := r.db.WithContext(ctx).Preload("Sections", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sections.id ASC")
+	}).Preload("Sections.Seats", func(db *gorm.DB) *gorm.DB {
+		return db.Order("seats.row ASC, seats.number ASC")
+	}).First(&venue, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &venue, nil
+}
+
+func (r *GormVenueRepository) CountSeats(ctx context.Context, venueID uint) (int, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Seat{}).
+		Joins("JOIN sections ON sections.id = seats.section_id AND sections.deleted_at IS NULL").
+		Where("sections.venue_id = ?", venueID).
+		Count(&count).Error
+	return int(count), err
+}
+
+func (r *GormVenueRepository) GetBookedSeatIDs(ctx context.Context, eventID uint) ([]uint, error) {
+	var seatIDs []uint
+	err := r.db.WithContext(ctx).Model(&models.BookingSeat{}).Where("event_id = ?", eventID).Pluck("seat_id", &seatIDs).Error
+	return seatIDs, err
+}
+
+// checkSeats validates the seats requested by a booking against the event's
+// seat map and existing bookings. The caller must hold the event row lock.
+func checkSeats(tx *gorm.DB, event *models.Event, booking *models.Booking) error {
+	if event.VenueID == nil {
+		if len(booking.Seats) > 0 {
+			return errors.New("event does not have reserved seating")
+		}
+		return nil
+	}
+
+	if len(booking.Seats) == 0 {
+		return errors.New("seat_ids are required for this event")
+	}
+
+	seatIDs := make([]uint, len(booking.Seats))
+	for i := range booking.Seats {
+		booking.Seats[i].EventID = event.ID
+		seatIDs[i] = booking.Seats[i].SeatID
+	}
+
+	// Every seat must belong to the event's venue
+	var valid int64
+	err := tx.Model(&models.Seat{}).
+		Joins("JOIN sections ON sections.id = seats.section_id AND sections.deleted_at IS NULL").
+		Where("seats.id IN ? AND sections.venue_id = ?", seatIDs, *event.VenueID).
+		Count(&valid).Error
+	if err != nil {
+		return err
+	}
+
+	if int(valid) != len(seatIDs) {
+		return errors.New("invalid seat selection")
+	}
+
+	// None of the seats may already be booked for this event
+	var taken []uint
+	err = tx.Model(&models.BookingSeat{}).
+		Where("event_id = ? AND seat_id IN ?", event.ID, seatIDs).
+		Pluck("seat_id", &taken).Error
+	if err != nil {
+		return err
+	}
+
+	if len(taken) > 0 {
+		return fmt.Errorf("seats already booked: %v", taken)
+	}
+
+	return nil
+}
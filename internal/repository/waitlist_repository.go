@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alexs/golang_test/internal/models"
+	"gorm.io/gorm"
+)
+
+// WaitlistRepository queues users for sold-out events and offers them
+// released tickets
+type WaitlistRepository interface {
+	// Join queues a user for an event that cannot currently satisfy their quantity
+	Join(ctx context.Context, entry *models.WaitlistEntry) error
+
+	// Promote offers any free tickets for an event to its waitlist
+	Promote(ctx context.Context, eventID uint, offerTTL time.Duration) ([]models.WaitlistEntry, error)
+}
+
+// GormWaitlistRepository is the WaitlistRepository backed by the database
+type GormWaitlistRepository struct {
+	db *gorm.DB
+}
+
+// NewGormWaitlistRepository creates a WaitlistRepository using db
+func NewGormWaitlistRepository(db *gorm.DB) *GormWaitlistRepository {
+	return &GormWaitlistRepository{db: db}
+}
+
+func (r *GormWaitlistRepository) Join(ctx context.Context, entry *models.WaitlistEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		event, err := lockEventForUpdate(tx, entry.EventID)
+		if err != nil {
+			return err
+		}
+
+		// Reserved seating events are booked seat by seat
+		if event.VenueID != nil {
+			return errors.New("event uses reserved seating")
+		}
+
+		available, err := event.AvailableTickets(tx)
+		if err != nil {
+			return err
+		}
+
+		// Users should book directly while tickets are still available
+		if available >= entry.Quantity {
+			_, err := resolveTier(tx, event, entry.TierID, entry.Quantity)
+			if err == nil {
+				return errors.New("tickets are still available")
+			}
+			if err.Error() != "not enough tickets available" {
+				return err
+			}
+		}
+
+		var existing int64
+		err = tx.Model(&models.WaitlistEntry{}).
+			Where("user_id = ? AND event_id = ? AND status IN ?", entry.UserID, entry.EventID, []string{"waiting", "offered"}).
+			Count(&existing).Error
+		if err != nil {
+			return err
+		}
+
+		if existing > 0 {
+			return errors.New("already on the waitlist for this event")
+		}
+
+		entry.Status = "waiting"
+		return tx.Create(entry).Error
+	})
+}
+
+func (r *GormWaitlistRepository) Promote(ctx context.Context, eventID uint, offerTTL time.Duration) ([]models.WaitlistEntry, error) {
+	var offered []models.WaitlistEntry
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		event, err := lockEventForUpdate(tx, eventID)
+		if err != nil {
+			return err
+		}
+
+		offered, err = promoteWaitlist(tx, event, offerTTL)
+		return err
+	})
+
+	return offered, err
+}
+
+// promoteWaitlist gives the oldest waiting entries that fit the free tickets a
+// timed hold. The caller must hold the event row lock.
+func promoteWaitlist(tx *gorm.DB, event *models.Event, offerTTL time.Duration) ([]models.WaitlistEntry, error) {
+	available, err := event.AvailableTickets(tx)
+	if err != nil || available <= 0 {
+		return nil, err
+	}
+
+	var waiting []models.WaitlistEntry
+	err = tx.Where("event_id = ? AND status = ?", event.ID, "waiting").
+		Order("created_at ASC").
+		Find(&waiting).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var offered []models.WaitlistEntry
+	for _, entry := range waiting {
+		if available <= 0 {
+			break
+		}
+
+		// Skip entries that want more than is free; a later, smaller one may fit
+		if entry.Quantity > available {
+			continue
+		}
+
+		// The entry's tier must also have room and still be on sale
+		if _, err := resolveTier(tx, event, entry.TierID, entry.Quantity); err != nil {
+			continue
+		}
+
+		hold := models.Hold{
+			UserID:   entry.UserID,
+			EventID:  entry.EventID,
+			TierID:   entry.TierID,
+			Quantity: entry.Quantity,
+		}
+		if err := createHold(tx, &hold, offerTTL); err != nil {
+			return nil, err
+		}
+
+		entry.Status = "offered"
+		entry.HoldID = &hold.ID
+		entry.OfferExpiresAt = &hold.ExpiresAt
+		if err := tx.Save(&entry).Error; err != nil {
+			return nil, err
+		}
+
+		available -= entry.Quantity
+		offered = append(offered, entry)
+	}
+
+	return offered, nil
+}
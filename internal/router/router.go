@@ -4,30 +4,36 @@ import (
 	"net/http"
 
 	"github.com/alexs/golang_test/internal/handlers"
+	"github.com/alexs/golang_test/internal/metrics"
 	"github.com/alexs/golang_test/internal/middleware"
+	"github.com/alexs/golang_test/internal/models"
+	"github.com/alexs/golang_test/internal/tracing"
 	"github.com/alexs/golang_test/internal/websocket"
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 )
 
-// New returns the configured router with all routes and middleware
-func New(hub *websocket.Hub) http.Handler {
+// New returns the configured router with all routes and middleware. The
+// WebSocket endpoint registers its clients with hub; CORS and the WebSocket
+// handshake accept allowedOrigins. Requests are traced and timed into m,
+// which main serves on its own listener rather than here.
+func New(h *handlers.Handler, hub *websocket.Hub, allowedOrigins []string, m *metrics.Metrics) http.Handler {
 	r := chi.NewRouter()
 
-	// Initialize handlers with the WebSocket hub
-	handlers.SetWebSocketHub(hub)
-
 	// Global Middlewares
-	r.Use(chimiddleware.Logger)
+	r.Use(middleware.RequestID)
+	r.Use(tracing.Middleware)
+	r.Use(m.Middleware)
+	r.Use(middleware.Logger)
 	r.Use(chimiddleware.Recoverer)
 
-	// CORS middleware for Nuxt frontend
+	// CORS middleware for Nuxt frontend (the WebSocket handshake checks the same origins)
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:3000"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedOrigins:   allowedOrigins,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", middleware.RequestIDHeader},
+		ExposedHeaders:   []string{"Link", middleware.RequestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -41,31 +47,72 @@ func New(hub *websocket.Hub) http.Handler {
 	})
 
 	// Auth routes
-	r.Post("/auth/signup", handlers.Signup)
-	r.Post("/auth/login", handlers.Login)
+	r.Post("/auth/signup", h.Signup)
+	r.Post("/auth/login", h.Login)
+	r.Post("/auth/refresh", h.RefreshToken)
+	r.Post("/auth/logout", h.Logout)
+	r.Post("/auth/forgot-password", h.ForgotPassword)
+	r.Post("/auth/reset-password", h.ResetPassword)
+	r.Post("/auth/verify-email", h.VerifyEmail)
 
 	// Public event routes (no auth required for browsing)
-	r.Get("/events", handlers.GetEvents)
-	r.Get("/events/{id}", handlers.GetEvent)
+	r.Get("/events", h.GetEvents)
+	r.Get("/events/{id}", h.GetEvent)
+	r.Get("/events/{id}/seats", h.GetEventSeats)
+	r.Get("/venues/{id}", h.GetVenue)
 
 	// WebSocket endpoint (auth via token query parameter)
-	r.Get("/ws", websocket.HandleWebSocket(hub))
+	r.Get("/ws", websocket.HandleWebSocket(hub, allowedOrigins))
+
+	// Server-Sent Events fallback for live availability (token only needed for unpublished events)
+	r.Get("/events/{id}/stream", h.StreamEvent)
 
 	// Protected Routes (auth required)
 	r.Group(func(r chi.Router) {
 		r.Use(middleware.RequireAuth)
 
 		// User profile
-		r.Get("/profile", handlers.GetProfile)
+		r.Get("/profile", h.GetProfile)
+
+		// Event management (organizers manage their own events, admins can moderate any)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireRole(models.RoleOrganizer, models.RoleAdmin))
+
+			r.Post("/events", h.CreateEvent)
+			r.Put("/events/{id}", h.UpdateEvent)
+			r.Patch("/events/{id}", h.UpdateEvent)
+			r.Delete("/events/{id}", h.DeleteEvent)
+
+			// Venue seat maps for reserved seating
+			r.Post("/venues", h.CreateVenue)
 
-		// Event management (protected - could add admin check later)
-		r.Post("/events", handlers.CreateEvent)
-		r.Delete("/events/{id}", handlers.DeleteEvent)
+			// Door check-in
+			r.Post("/events/{id}/checkin", h.CheckInTicket)
+		})
+
+		// Administration
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireRole(models.RoleAdmin))
+
+			r.Put("/admin/users/{id}/role", h.UpdateUserRole)
+			r.Get("/admin/websocket/stats", h.GetWebSocketStats)
+		})
 
 		// Booking routes
-		r.Post("/bookings", handlers.BookTicket)
-		r.Get("/bookings", handlers.GetMyBookings)
-		r.Delete("/bookings/{id}", handlers.CancelBooking)
+		r.Post("/bookings", h.BookTicket)
+		r.Get("/bookings", h.GetMyBookings)
+		r.Delete("/bookings/{id}", h.CancelBooking)
+
+		// Tickets
+		r.Get("/bookings/{id}/tickets", h.GetBookingTickets)
+		r.Get("/tickets/{id}/qr", h.GetTicketQRCode)
+
+		// Ticket holds (temporary reservations during checkout)
+		r.Post("/events/{id}/holds", h.CreateHold)
+		r.Post("/holds/{id}/confirm", h.ConfirmHold)
+
+		// Waitlist for sold-out events
+		r.Post("/events/{id}/waitlist", h.JoinWaitlist)
 	})
 
 	return r
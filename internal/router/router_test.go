@@ -0,0 +1,166 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexs/golang_test/internal/config"
+	"github.com/alexs/golang_test/internal/handlers"
+	"github.com/alexs/golang_test/internal/metrics"
+	"github.com/alexs/golang_test/internal/models"
+	"github.com/alexs/golang_test/internal/utils"
+	"github.com/alexs/golang_test/internal/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRoleAccess tests every role against every role-restricted route of the
+// application router without database. Requests that pass the role check are
+// built to fail before the handler needs a repository, so its error response
+// means the request reached the handler.
+func TestRoleAccess(t *testing.T) {
+	// Configure token signing for tests
+	utils.ConfigureJWT(config.JWTConfig{Secret: "test-secret-key-for-testing", AccessTokenTTL: 24 * time.Hour})
+
+	// Every token in this test belongs to an active session
+	originalChecker := utils.SessionChecker
+	defer func() { utils.SessionChecker = originalChecker }()
+	utils.SessionChecker = func(ctx context.Context, sessionID uint) (bool, error) { return true, nil }
+
+	routes := []struct {
+		name          string
+		method        string
+		path          string
+		body          string
+		allowedRoles  []string
+		reachedStatus int
+		reachedBody   string
+	}{
+		{
+			name:          "Create Event",
+			method:        "POST",
+			path:          "/events",
+			body:          `{}`,
+			allowedRoles:  []string{models.RoleOrganizer, models.RoleAdmin},
+			reachedStatus: http.StatusBadRequest,
+			reachedBody:   "Name, date, and capacity are required",
+		},
+		{
+			name:          "Update Event",
+			method:        "PUT",
+			path:          "/events/abc",
+			body:          `{}`,
+			allowedRoles:  []string{models.RoleOrganizer, models.RoleAdmin},
+			reachedStatus: http.StatusBadRequest,
+			reachedBody:   "Invalid event ID",
+		},
+		{
+			name:          "Patch Event",
+			method:        "PATCH",
+			path:          "/events/abc",
+			body:          `{}`,
+			allowedRoles:  []string{models.RoleOrganizer, models.RoleAdmin},
+			reachedStatus: http.StatusBadRequest,
+			reachedBody:   "Invalid event ID",
+		},
+		{
+			name:          "Delete Event",
+			method:        "DELETE",
+			path:          "/events/abc",
+			allowedRoles:  []string{models.RoleOrganizer, models.RoleAdmin},
+			reachedStatus: http.StatusBadRequest,
+			reachedBody:   "Invalid event ID",
+		},
+		{
+			name:          "Create Venue",
+			method:        "POST",
+			path:          "/venues",
+			body:          `{}`,
+			allowedRoles:  []string{models.RoleOrganizer, models.RoleAdmin},
+			reachedStatus: http.StatusBadRequest,
+			reachedBody:   "Name and at least one section are required",
+		},
+		{
+			name:          "Check In Ticket",
+			method:        "POST",
+			path:          "/events/abc/checkin",
+			body:          `{}`,
+			allowedRoles:  []string{models.RoleOrganizer, models.RoleAdmin},
+			reachedStatus: http.StatusBadRequest,
+			reachedBody:   "Invalid event ID",
+		},
+		{
+			name:          "Update User Role",
+			method:        "PUT",
+			path:          "/admin/users/abc/role",
+			body:          `{"role": "organizer"}`,
+			allowedRoles:  []string{models.RoleAdmin},
+			reachedStatus: http.StatusBadRequest,
+			reachedBody:   "Invalid user ID",
+		},
+		{
+			name:          "WebSocket Stats",
+			method:        "GET",
+			path:          "/admin/websocket/stats",
+			allowedRoles:  []string{models.RoleAdmin},
+			reachedStatus: http.StatusServiceUnavailable,
+			reachedBody:   "Live updates are unavailable",
+		},
+	}
+
+	// An empty role covers tokens issued before roles existed
+	roles := []string{models.RoleUser, models.RoleOrganizer, models.RoleAdmin, ""}
+
+	// The handler has no repositories or hub; none of the requests get that far
+	router := New(handlers.New(handlers.Dependencies{}), websocket.NewHub(), nil, metrics.New())
+
+	for _, route := range routes {
+		for _, role := range roles {
+			name := route.name + "/" + role
+			if role == "" {
+				name = route.name + "/no role"
+			}
+
+			t.Run(name, func(t *testing.T) {
+				token, err := utils.GenerateJWT(1, "testuser", "test@example.com", role, 1)
+				assert.NoError(t, err)
+
+				req, err := http.NewRequest(route.method, route.path, bytes.NewBufferString(route.body))
+				assert.NoError(t, err)
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Authorization", "Bearer "+token)
+
+				rr := httptest.NewRecorder()
+				router.ServeHTTP(rr, req)
+
+				allowed := false
+				for _, allowedRole := range route.allowedRoles {
+					if role == allowedRole {
+						allowed = true
+					}
+				}
+
+				if allowed {
+					assert.Equal(t, route.reachedStatus, rr.Code, "Request should reach the handler")
+					assert.Contains(t, rr.Body.String(), route.reachedBody, "Response body mismatch")
+				} else {
+					assert.Equal(t, http.StatusForbidden, rr.Code, "Role should be rejected")
+					assert.Contains(t, rr.Body.String(), "Insufficient permissions", "Response body mismatch")
+				}
+			})
+		}
+
+		t.Run(route.name+"/anonymous", func(t *testing.T) {
+			req, err := http.NewRequest(route.method, route.path, bytes.NewBufferString(route.body))
+			assert.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusUnauthorized, rr.Code, "Anonymous requests should be rejected")
+		})
+	}
+}
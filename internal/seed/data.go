@@ -84,6 +84,44 @@ var priceRanges = map[string][2]float64{
 	"other":   {0.0, 100.0},
 }
 
+// tierTemplate describes one ticket tier as a share of event capacity and base price
+type tierTemplate struct {
+	Name            string
+	CapacityPercent int     // share of event capacity; the last tier takes the remainder
+	PriceMultiplier float64 // multiplied with the event's base price
+	EarlyBird       bool    // sales close 30 days before the event
+}
+
+// tierTemplates defines realistic ticket tiers for each event type (paid events only)
+var tierTemplates = map[string][]tierTemplate{
+	"concert": {
+		{Name: "Early Bird", CapacityPercent: 15, PriceMultiplier: 0.75, EarlyBird: true},
+		{Name: "General Admission", CapacityPercent: 65, PriceMultiplier: 1.0},
+		{Name: "VIP", CapacityPercent: 20, PriceMultiplier: 2.5},
+	},
+	"musical": {
+		{Name: "Balcony", CapacityPercent: 30, PriceMultiplier: 0.7},
+		{Name: "Orchestra", CapacityPercent: 50, PriceMultiplier: 1.0},
+		{Name: "Premium", CapacityPercent: 20, PriceMultiplier: 1.8},
+	},
+	"standup": {
+		{Name: "General Admission", CapacityPercent: 85, PriceMultiplier: 1.0},
+		{Name: "Front Row", CapacityPercent: 15, PriceMultiplier: 1.6},
+	},
+	"lecture": {
+		{Name: "Student", CapacityPercent: 30, PriceMultiplier: 0.5},
+		{Name: "General", CapacityPercent: 70, PriceMultiplier: 1.0},
+	},
+	"tour": {
+		{Name: "Standard", CapacityPercent: 80, PriceMultiplier: 1.0},
+		{Name: "Small Group", CapacityPercent: 20, PriceMultiplier: 1.5},
+	},
+	"other": {
+		{Name: "Early Bird", CapacityPercent: 20, PriceMultiplier: 0.8, EarlyBird: true},
+		{Name: "General Admission", CapacityPercent: 80, PriceMultiplier: 1.0},
+	},
+}
+
 // concertDescriptors for generating concert names
 var concertDescriptors = []string{
 	"Tour", "Festival", "Show", "Concert", "Night", "Sessions",
@@ -2,6 +2,7 @@ package seed
 
 import (
 	"fmt"
+	"math"
 	"math/rand"
 	"time"
 
@@ -22,10 +23,16 @@ func generateUser(index int, isOrganizer bool) (*models.User, error) {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
 
+	role := models.RoleUser
+	if isOrganizer {
+		role = models.RoleOrganizer
+	}
+
 	user := &models.User{
 		Username: username,
 		Email:    email,
 		Password: hashedPassword,
+		Role:     role,
 	}
 
 	return user, nil
@@ -42,6 +49,14 @@ func generateEvent(organizerID uint, eventType string) *models.Event {
 	price := randomPrice(eventType)
 	capacity := randomCapacity()
 	imageURL := randomImageForType(eventType)
+	tiers := generateTiers(eventType, price, capacity, date)
+
+	// Tiered events advertise their cheapest tier as the event price
+	for _, tier := range tiers {
+		if tier.Price < price {
+			price = tier.Price
+		}
+	}
 
 	event := &models.Event{
 		Name:        eventName,
@@ -56,11 +71,57 @@ func generateEvent(organizerID uint, eventType string) *models.Event {
 		Price:       price,
 		Capacity:    capacity,
 		ImageURL:    imageURL,
+		Tiers:       tiers,
 	}
 
 	return event
 }
 
+// generateTiers splits a paid event's capacity into ticket tiers for its type.
+// Free events are sold without tiers.
+func generateTiers(eventType string, basePrice float64, capacity int, eventDate time.Time) []models.TicketTier {
+	if basePrice == 0 {
+		return nil
+	}
+
+	templates, ok := tierTemplates[eventType]
+	if !ok {
+		templates = tierTemplates["other"]
+	}
+
+	tiers := make([]models.TicketTier, 0, len(templates))
+	remaining := capacity
+
+	for i, tmpl := range templates {
+		tierCapacity := capacity * tmpl.CapacityPercent / 100
+		if i == len(templates)-1 {
+			tierCapacity = remaining
+		}
+		if tierCapacity <= 0 {
+			continue
+		}
+		remaining -= tierCapacity
+
+		// Round to nearest $5, keeping paid tiers above zero
+		price := math.Max(5, math.Round(basePrice*tmpl.PriceMultiplier/5)*5)
+
+		tier := models.TicketTier{
+			Name:     tmpl.Name,
+			Price:    price,
+			Capacity: tierCapacity,
+		}
+
+		if tmpl.EarlyBird {
+			salesEnd := eventDate.AddDate(0, 0, -30)
+			tier.SalesEnd = &salesEnd
+		}
+
+		tiers = append(tiers, tier)
+	}
+
+	return tiers
+}
+
 // generateBooking creates a booking with capacity awareness
 func generateBooking(userID uint, eventID uint, eventCapacity int, currentBookedCount int) *models.Booking {
 	// Calculate remaining capacity
@@ -139,6 +200,7 @@ func generateSentinelUser() (*models.User, error) {
 		Username: "seed-marker",
 		Email:    "seed-marker@ticketdb.system",
 		Password: hashedPassword,
+		Role:     models.RoleUser,
 	}
 
 	return user, nil
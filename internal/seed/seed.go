@@ -1,8 +1,11 @@
 package seed
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand"
+	"time"
 
 	"github.com/alexs/golang_test/internal/models"
 	"github.com/alexs/golang_test/internal/repository"
@@ -29,41 +32,73 @@ func IsDatabaseSeeded() (bool, error) {
 
 // ClearSeedData removes all seeded data from the database
 func ClearSeedData() error {
-	log.Println("Clearing existing seed data...")
+	slog.Info("Clearing existing seed data")
 
 	return repository.DB.Transaction(func(tx *gorm.DB) error {
 		// Order matters: delete in reverse FK dependency order
-		// bookings -> events -> users
+		// waitlist -> holds -> tickets -> booking seats -> bookings -> ticket tiers -> events -> users
+
+		if err := tx.Exec("DELETE FROM waitlist_entries").Error; err != nil {
+			return fmt.Errorf("failed to delete waitlist entries: %w", err)
+		}
+
+		if err := tx.Exec("DELETE FROM holds").Error; err != nil {
+			return fmt.Errorf("failed to delete holds: %w", err)
+		}
+
+		if err := tx.Exec("DELETE FROM tickets").Error; err != nil {
+			return fmt.Errorf("failed to delete tickets: %w", err)
+		}
+
+		if err := tx.Exec("DELETE FROM booking_seats").Error; err != nil {
+			return fmt.Errorf("failed to delete booking seats: %w", err)
+		}
 
 		if err := tx.Exec("DELETE FROM bookings").Error; err != nil {
 			return fmt.Errorf("failed to delete bookings: %w", err)
 		}
 
+		if err := tx.Exec("DELETE FROM ticket_tiers").Error; err != nil {
+			return fmt.Errorf("failed to delete ticket tiers: %w", err)
+		}
+
 		if err := tx.Exec("DELETE FROM events").Error; err != nil {
 			return fmt.Errorf("failed to delete events: %w", err)
 		}
 
+		if err := tx.Exec("DELETE FROM account_tokens").Error; err != nil {
+			return fmt.Errorf("failed to delete account tokens: %w", err)
+		}
+
+		if err := tx.Exec("DELETE FROM refresh_tokens").Error; err != nil {
+			return fmt.Errorf("failed to delete refresh tokens: %w", err)
+		}
+
+		if err := tx.Exec("DELETE FROM sessions").Error; err != nil {
+			return fmt.Errorf("failed to delete sessions: %w", err)
+		}
+
 		if err := tx.Exec("DELETE FROM users").Error; err != nil {
 			return fmt.Errorf("failed to delete users: %w", err)
 		}
 
 		// Reset sequences for clean IDs
-		sequences := []string{"bookings_id_seq", "events_id_seq", "users_id_seq"}
+		sequences := []string{"waitlist_entries_id_seq", "holds_id_seq", "tickets_id_seq", "booking_seats_id_seq", "bookings_id_seq", "ticket_tiers_id_seq", "events_id_seq", "account_tokens_id_seq", "refresh_tokens_id_seq", "sessions_id_seq", "users_id_seq"}
 		for _, seq := range sequences {
 			if err := tx.Exec(fmt.Sprintf("ALTER SEQUENCE %s RESTART WITH 1", seq)).Error; err != nil {
 				// Log but don't fail - sequence reset is nice-to-have
-				log.Printf("Warning: Failed to reset sequence %s: %v", seq, err)
+				slog.Warn("Failed to reset sequence", "sequence", seq, "error", err)
 			}
 		}
 
-		log.Println("Successfully cleared all seed data")
+		slog.Info("Cleared all seed data")
 		return nil
 	})
 }
 
 // SeedUsers creates organizer and regular users
 func SeedUsers() ([]uint, error) {
-	log.Printf("Creating %d users (%d organizers, %d regular)...", totalUsers, organizerCount, regularUsers)
+	slog.Info("Creating users", "total", totalUsers, "organizers", organizerCount, "regular", regularUsers)
 
 	var userIDs []uint
 
@@ -84,17 +119,18 @@ func SeedUsers() ([]uint, error) {
 
 		// Log progress every 50 users
 		if i%50 == 0 {
-			log.Printf("  Created %d/%d users...", i, totalUsers)
+			slog.Info("Created users", "created", i, "total", totalUsers)
 		}
 	}
 
-	log.Printf("Successfully created %d users", totalUsers)
+	slog.Info("Finished creating users", "total", totalUsers)
 	return userIDs, nil
 }
 
 // SeedEvents creates events with varied data
 func SeedEvents(organizerIDs []uint) ([]uint, error) {
-	log.Printf("Creating %d events...", totalEvents)
+	events := repository.NewGormEventRepository(repository.DB)
+	slog.Info("Creating events", "total", totalEvents)
 
 	var eventIDs []uint
 	eventIndex := 0
@@ -109,7 +145,7 @@ func SeedEvents(organizerIDs []uint) ([]uint, error) {
 
 			event := generateEvent(organizerID, eventType)
 
-			if err := repository.CreateEvent(event); err != nil {
+			if err := events.Create(context.Background(), event); err != nil {
 				return nil, fmt.Errorf("failed to create event %d (%s): %w", eventIndex, eventType, err)
 			}
 
@@ -117,18 +153,19 @@ func SeedEvents(organizerIDs []uint) ([]uint, error) {
 
 			// Log progress every 50 events
 			if eventIndex%50 == 0 {
-				log.Printf("  Created %d/%d events...", eventIndex, totalEvents)
+				slog.Info("Created events", "created", eventIndex, "total", totalEvents)
 			}
 		}
 	}
 
-	log.Printf("Successfully created %d events", totalEvents)
+	slog.Info("Finished creating events", "total", totalEvents)
 	return eventIDs, nil
 }
 
 // SeedBookings creates bookings based on distribution strategy
 func SeedBookings(userIDs []uint, eventIDs []uint) error {
-	log.Println("Creating bookings with realistic distribution...")
+	bookings := repository.NewGormBookingRepository(repository.DB)
+	slog.Info("Creating bookings with realistic distribution")
 
 	// Get booking distribution plan
 	distributions := generateBookingDistributions(eventIDs)
@@ -146,6 +183,19 @@ func SeedBookings(userIDs []uint, eventIDs []uint) error {
 			return fmt.Errorf("failed to get event %d: %w", dist.EventID, err)
 		}
 
+		// Load the event's ticket tiers that are currently on sale
+		var tiers []models.TicketTier
+		if err := repository.DB.Where("event_id = ?", dist.EventID).Find(&tiers).Error; err != nil {
+			return fmt.Errorf("failed to get tiers for event %d: %w", dist.EventID, err)
+		}
+		var onSale []models.TicketTier
+		for _, tier := range tiers {
+			if tier.OnSale(time.Now()) {
+				onSale = append(onSale, tier)
+			}
+		}
+		tierBooked := make(map[uint]int)
+
 		// Calculate target number of tickets to book
 		targetTickets := (event.Capacity * dist.TargetPercent) / 100
 		bookedSoFar := 0
@@ -155,31 +205,48 @@ func SeedBookings(userIDs []uint, eventIDs []uint) error {
 			// Random user from regular users
 			userID := regularUserIDs[totalBookings%len(regularUserIDs)]
 
-			booking := generateBooking(userID, dist.EventID, event.Capacity, bookedSoFar)
+			var booking *models.Booking
+			if len(tiers) == 0 {
+				booking = generateBooking(userID, dist.EventID, event.Capacity, bookedSoFar)
+			} else if len(onSale) > 0 {
+				// Tiered events book against a random tier on sale
+				i := rand.Intn(len(onSale))
+				tier := onSale[i]
+				booking = generateBooking(userID, dist.EventID, tier.Capacity, tierBooked[tier.ID])
+				if booking == nil {
+					// Tier sold out, try the others
+					onSale = append(onSale[:i], onSale[i+1:]...)
+					continue
+				}
+				booking.TierID = &tier.ID
+			}
 			if booking == nil {
 				// No more capacity
 				break
 			}
 
 			// CreateBooking handles validation and pricing
-			if err := repository.CreateBooking(booking); err != nil {
+			if err := bookings.Create(context.Background(), booking); err != nil {
 				// Log but continue - might be capacity exceeded
-				log.Printf("  Warning: Failed to create booking for event %d: %v", dist.EventID, err)
+				slog.Warn("Failed to create booking", "event_id", dist.EventID, "error", err)
 				break
 			}
 
 			bookedSoFar += booking.Quantity
+			if booking.TierID != nil {
+				tierBooked[*booking.TierID] += booking.Quantity
+			}
 			totalBookings++
 		}
 	}
 
-	log.Printf("Successfully created %d bookings across %d events", totalBookings, eventsWithBookings)
+	slog.Info("Finished creating bookings", "bookings", totalBookings, "events", eventsWithBookings)
 	return nil
 }
 
 // CreateSentinelUser creates the sentinel marker user
 func CreateSentinelUser() error {
-	log.Println("Creating sentinel marker user...")
+	slog.Info("Creating sentinel marker user")
 
 	user, err := generateSentinelUser()
 	if err != nil {
@@ -190,15 +257,13 @@ func CreateSentinelUser() error {
 		return fmt.Errorf("failed to create sentinel user: %w", err)
 	}
 
-	log.Println("Sentinel user created successfully")
+	slog.Info("Sentinel user created")
 	return nil
 }
 
 // Run executes the full database seeding process
 func Run(force bool) error {
-	log.Println("========================================")
-	log.Println("Database Seeding Started")
-	log.Println("========================================")
+	slog.Info("Database seeding started")
 
 	// Check if already seeded
 	seeded, err := IsDatabaseSeeded()
@@ -207,12 +272,12 @@ func Run(force bool) error {
 	}
 
 	if seeded && !force {
-		log.Println("Database is already seeded. Skipping. Set FORCE_RESEED=true to reseed.")
+		slog.Info("Database is already seeded, skipping. Set FORCE_RESEED=true to reseed.")
 		return nil
 	}
 
 	if seeded && force {
-		log.Println("FORCE_RESEED enabled. Clearing existing data...")
+		slog.Info("FORCE_RESEED enabled, clearing existing data")
 		if err := ClearSeedData(); err != nil {
 			return fmt.Errorf("failed to clear seed data: %w", err)
 		}
@@ -241,12 +306,7 @@ func Run(force bool) error {
 		return fmt.Errorf("failed to create sentinel user: %w", err)
 	}
 
-	log.Println("========================================")
-	log.Printf("Database Seeding Completed Successfully!")
-	log.Printf("  Users: %d", totalUsers)
-	log.Printf("  Events: %d", totalEvents)
-	log.Println("  Bookings: Created with realistic distribution")
-	log.Println("========================================")
+	slog.Info("Database seeding completed", "users", totalUsers, "events", totalEvents)
 
 	return nil
 }
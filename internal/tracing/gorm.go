@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// querySpanKey stores a statement's span on its gorm.DB instance
+const querySpanKey = "tracing:query_span"
+
+// gormPlugin records a client span for every GORM statement, as a child of the
+// span in the statement's context, so queries made with db.WithContext(ctx)
+// show up under the request that made them
+type gormPlugin struct {
+	tracer trace.Tracer
+}
+
+// GormPlugin returns a plugin tracing queries; install it with db.Use
+func GormPlugin() gorm.Plugin {
+	return &gormPlugin{tracer: otel.Tracer(instrumentationName)}
+}
+
+func (p *gormPlugin) Name() string {
+	return "tracing"
+}
+
+// Initialize wraps each of GORM's statement callbacks in a span
+func (p *gormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("tracing:before_create", p.start("INSERT")),
+		callbacks.Create().After("gorm:create").Register("tracing:after_create", p.end),
+		callbacks.Query().Before("gorm:query").Register("tracing:before_query", p.start("SELECT")),
+		callbacks.Query().After("gorm:query").Register("tracing:after_query", p.end),
+		callbacks.Update().Before("gorm:update").Register("tracing:before_update", p.start("UPDATE")),
+		callbacks.Update().After("gorm:update").Register("tracing:after_update", p.end),
+		callbacks.Delete().Before("gorm:delete").Register("tracing:before_delete", p.start("DELETE")),
+		callbacks.Delete().After("gorm:delete").Register("tracing:after_delete", p.end),
+		callbacks.Row().Before("gorm:row").Register("tracing:before_row", p.start("SELECT")),
+		callbacks.Row().After("gorm:row").Register("tracing:after_row", p.end),
+		callbacks.Raw().Before("gorm:raw").Register("tracing:before_raw", p.start("RAW")),
+		callbacks.Raw().After("gorm:raw").Register("tracing:after_raw", p.end),
+	)
+}
+
+// start opens the statement's span, named after its operation and table
+func (p *gormPlugin) start(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		name := operation
+		if db.Statement.Table != "" {
+			name += " " + db.Statement.Table
+		}
+
+		_, span := p.tracer.Start(db.Statement.Context, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemNamePostgreSQL,
+				semconv.DBOperationName(operation),
+				semconv.DBCollectionName(db.Statement.Table),
+			),
+		)
+		db.InstanceSet(querySpanKey, span)
+	}
+}
+
+// end records the statement's SQL, rows and error, and closes its span
+func (p *gormPlugin) end(db *gorm.DB) {
+	value, ok := db.InstanceGet(querySpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		semconv.DBResponseReturnedRows(int(db.RowsAffected)),
+	)
+
+	// Lookups of missing rows are answered with 404s, not failures
+	if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
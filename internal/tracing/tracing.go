@@ -0,0 +1,66 @@
+// Package tracing sets up OpenTelemetry tracing and records spans for HTTP
+// routes and database queries. Other packages start their own spans with
+// otel.Tracer, which uses the provider installed by Setup.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/alexs/golang_test/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+)
+
+// instrumentationName names the tracer of this package's spans
+const instrumentationName = "github.com/alexs/golang_test/internal/tracing"
+
+// Setup installs the tracer provider for cfg as the global one, along with the
+// W3C trace context propagator, and returns a function that flushes pending
+// spans and stops the provider. The stdout exporter writes to w. With the none
+// exporter nothing is recorded.
+func Setup(ctx context.Context, cfg config.TracingConfig, w io.Writer) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		err = fmt.Errorf("unknown exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe trace resource: %w", err)
+	}
+
+	provider := NewProvider(exporter, cfg.SampleRatio, sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// NewProvider creates a tracer provider batching spans to exporter. New traces
+// are sampled at sampleRatio; a trace started upstream keeps its decision.
+// Tests pass an in-memory exporter from the sdk's tracetest package.
+func NewProvider(exporter sdktrace.SpanExporter, sampleRatio float64, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	opts = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	}, opts...)
+	return sdktrace.NewTracerProvider(opts...)
+}
@@ -0,0 +1,157 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexs/golang_test/internal/config"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// record installs a provider exporting spans to memory for the rest of the test
+func record(t *testing.T) *tracetest.InMemoryExporter {
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return exporter
+}
+
+// attributes returns the attributes of span by key
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	values := make(map[attribute.Key]attribute.Value, len(span.Attributes))
+	for _, attr := range span.Attributes {
+		values[attr.Key] = attr.Value
+	}
+	return values
+}
+
+// TestMiddleware tests that server spans are named by route pattern and continue incoming traces
+func TestMiddleware(t *testing.T) {
+	exporter := record(t)
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Post("/events/{id}/book", func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, trace.SpanContextFromContext(r.Context()).IsValid(), "Handlers see the request's span")
+		w.WriteHeader(http.StatusConflict)
+	})
+	r.Get("/events", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/events/5/book", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+
+	book := spans[0]
+	assert.Equal(t, "POST /events/{id}/book", book.Name)
+	assert.Equal(t, trace.SpanKindServer, book.SpanKind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", book.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", book.Parent.SpanID().String())
+	attrs := attributes(book)
+	assert.Equal(t, "/events/{id}/book", attrs["http.route"].AsString())
+	assert.Equal(t, "/events/5/book", attrs["url.path"].AsString())
+	assert.EqualValues(t, http.StatusConflict, attrs["http.response.status_code"].AsInt64())
+	assert.Equal(t, codes.Unset, book.Status.Code, "Client errors are not span errors")
+
+	list := spans[1]
+	assert.Equal(t, "GET /events", list.Name)
+	assert.False(t, list.Parent.IsValid(), "Requests without a traceparent start a trace")
+	assert.Equal(t, codes.Error, list.Status.Code)
+}
+
+// TestGormPlugin tests that statements are recorded as children of their context's span
+func TestGormPlugin(t *testing.T) {
+	exporter := record(t)
+
+	type Event struct {
+		ID    uint
+		Title string
+	}
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=test"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(GormPlugin()))
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	var event Event
+	db.WithContext(ctx).Where("id = ?", 3).Find(&event)
+	db.WithContext(ctx).Create(&Event{Title: "Concert"})
+	parent.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+
+	tests := []struct {
+		name      string
+		operation string
+	}{
+		{name: "SELECT events", operation: "SELECT"},
+		{name: "INSERT events", operation: "INSERT"},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := spans[i]
+			assert.Equal(t, tt.name, span.Name)
+			assert.Equal(t, trace.SpanKindClient, span.SpanKind)
+			assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+			attrs := attributes(span)
+			assert.Equal(t, tt.operation, attrs["db.operation.name"].AsString())
+			assert.Equal(t, "events", attrs["db.collection.name"].AsString())
+			assert.Contains(t, attrs["db.query.text"].AsString(), `"events"`)
+		})
+	}
+}
+
+// TestSetup tests that the stdout exporter writes spans once tracing shuts down
+func TestSetup(t *testing.T) {
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+
+	var buf bytes.Buffer
+	shutdown, err := Setup(context.Background(), config.TracingConfig{Exporter: "stdout", ServiceName: "ticket-api", SampleRatio: 1}, &buf)
+	require.NoError(t, err)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "work")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	assert.Contains(t, buf.String(), `"Name":"work"`)
+	assert.Contains(t, buf.String(), "ticket-api")
+
+	shutdown, err = Setup(context.Background(), config.TracingConfig{Exporter: "none"}, &buf)
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}
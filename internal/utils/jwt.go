@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -9,27 +10,45 @@ import (
 )
 
 type Claims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
+	UserID    uint   `json:"user_id"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	SessionID uint   `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT creates a new JWT token for a user
-func GenerateJWT(userID uint, username, email string) (string, error) {
+// jwtConfig holds the signing secret and access token lifetime; it is set
+// from the configuration at startup with ConfigureJWT
+var jwtConfig config.JWTConfig
+
+// ConfigureJWT sets the secret and lifetimes used to sign and validate access
+// tokens and ticket codes
+func ConfigureJWT(cfg config.JWTConfig) {
+	jwtConfig = cfg
+}
+
+// SessionChecker reports whether a login session is still active. It is wired to
+// the session store at startup so access tokens of revoked sessions are rejected.
+var SessionChecker func(ctx context.Context, sessionID uint) (bool, error)
+
+// GenerateJWT creates a new short-lived access token for a user's session
+func GenerateJWT(userID uint, username, email, role string, sessionID uint) (string, error) {
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		Email:    email,
+		UserID:    userID,
+		Username:  username,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.AppConfig.JWTExpiration)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(jwtConfig.AccessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(config.AppConfig.JWTSecret))
+	return token.SignedString([]byte(jwtConfig.Secret))
 }
 
 // ValidateJWT parses and validates a JWT token
@@ -39,7 +58,7 @@ func ValidateJWT(tokenString string) (*Claims, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("invalid signing method")
 		}
-		return []byte(config.AppConfig.JWTSecret), nil
+		return []byte(jwtConfig.Secret), nil
 	})
 
 	if err != nil {
@@ -52,3 +71,25 @@ func ValidateJWT(tokenString string) (*Claims, error) {
 
 	return nil, errors.New("invalid token")
 }
+
+// ValidateAccessToken validates a JWT and checks that its session has not been revoked
+func ValidateAccessToken(ctx context.Context, tokenString string) (*Claims, error) {
+	claims, err := ValidateJWT(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.SessionID == 0 || SessionChecker == nil {
+		return nil, errors.New("token has no active session")
+	}
+
+	active, err := SessionChecker(ctx, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, errors.New("session has been revoked")
+	}
+
+	return claims, nil
+}
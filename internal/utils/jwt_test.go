@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -10,13 +11,8 @@ import (
 
 // TestGenerateJWT tests JWT token generation
 func TestGenerateJWT(t *testing.T) {
-	// Ensure config is loaded for tests
-	if config.AppConfig == nil {
-		config.AppConfig = &config.Config{
-			JWTSecret:     "test-secret-key-for-testing",
-			JWTExpiration: 24 * time.Hour,
-		}
-	}
+	// Configure token signing for tests
+	ConfigureJWT(config.JWTConfig{Secret: "test-secret-key-for-testing", AccessTokenTTL: 24 * time.Hour})
 
 	tests := []struct {
 		name     string
@@ -46,7 +42,7 @@ func TestGenerateJWT(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			token, err := GenerateJWT(tc.userID, tc.username, tc.email)
+			token, err := GenerateJWT(tc.userID, tc.username, tc.email, "user", 1)
 
 			assert.NoError(t, err, "Should generate token without error")
 			assert.NotEmpty(t, token, "Token should not be empty")
@@ -57,13 +53,8 @@ func TestGenerateJWT(t *testing.T) {
 
 // TestValidateJWT tests JWT token validation
 func TestValidateJWT(t *testing.T) {
-	// Ensure config is loaded for tests
-	if config.AppConfig == nil {
-		config.AppConfig = &config.Config{
-			JWTSecret:     "test-secret-key-for-testing",
-			JWTExpiration: 24 * time.Hour,
-		}
-	}
+	// Configure token signing for tests
+	ConfigureJWT(config.JWTConfig{Secret: "test-secret-key-for-testing", AccessTokenTTL: 24 * time.Hour})
 
 	tests := []struct {
 		name        string
@@ -91,7 +82,7 @@ func TestValidateJWT(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			// Generate a token
-			token, err := GenerateJWT(tc.userID, tc.username, tc.email)
+			token, err := GenerateJWT(tc.userID, tc.username, tc.email, "user", 1)
 			assert.NoError(t, err)
 
 			// Validate the token
@@ -112,13 +103,8 @@ func TestValidateJWT(t *testing.T) {
 
 // TestValidateJWT_InvalidToken tests validation of invalid tokens
 func TestValidateJWT_InvalidToken(t *testing.T) {
-	// Ensure config is loaded for tests
-	if config.AppConfig == nil {
-		config.AppConfig = &config.Config{
-			JWTSecret:     "test-secret-key-for-testing",
-			JWTExpiration: 24 * time.Hour,
-		}
-	}
+	// Configure token signing for tests
+	ConfigureJWT(config.JWTConfig{Secret: "test-secret-key-for-testing", AccessTokenTTL: 24 * time.Hour})
 
 	tests := []struct {
 		name  string
@@ -154,22 +140,16 @@ func TestValidateJWT_InvalidToken(t *testing.T) {
 
 // TestValidateJWT_WrongSecret tests validation with wrong secret
 func TestValidateJWT_WrongSecret(t *testing.T) {
-	// Ensure config is initialized
-	if config.AppConfig == nil {
-		config.AppConfig = &config.Config{}
-	}
-
-	originalSecret := config.AppConfig.JWTSecret
-	defer func() { config.AppConfig.JWTSecret = originalSecret }()
+	originalConfig := jwtConfig
+	defer ConfigureJWT(originalConfig)
 
 	// Generate token with one secret
-	config.AppConfig.JWTSecret = "secret1"
-	config.AppConfig.JWTExpiration = 24 * time.Hour
-	token, err := GenerateJWT(1, "testuser", "test@example.com")
+	ConfigureJWT(config.JWTConfig{Secret: "secret1", AccessTokenTTL: 24 * time.Hour})
+	token, err := GenerateJWT(1, "testuser", "test@example.com", "user", 1)
 	assert.NoError(t, err)
 
 	// Try to validate with different secret
-	config.AppConfig.JWTSecret = "secret2"
+	ConfigureJWT(config.JWTConfig{Secret: "secret2", AccessTokenTTL: 24 * time.Hour})
 	claims, err := ValidateJWT(token)
 
 	assert.Error(t, err, "Should error when validating with wrong secret")
@@ -178,20 +158,16 @@ func TestValidateJWT_WrongSecret(t *testing.T) {
 
 // TestJWT_RoundTrip tests full generate and validate cycle
 func TestJWT_RoundTrip(t *testing.T) {
-	// Ensure config is loaded for tests
-	if config.AppConfig == nil {
-		config.AppConfig = &config.Config{
-			JWTSecret:     "test-secret-key-for-testing",
-			JWTExpiration: 24 * time.Hour,
-		}
-	}
+	// Configure token signing for tests
+	ConfigureJWT(config.JWTConfig{Secret: "test-secret-key-for-testing", AccessTokenTTL: 24 * time.Hour})
 
 	userID := uint(42)
 	username := "roundtripuser"
 	email := "roundtrip@example.com"
+	role := "organizer"
 
 	// Generate token
-	token, err := GenerateJWT(userID, username, email)
+	token, err := GenerateJWT(userID, username, email, role, 5)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, token)
 
@@ -204,8 +180,63 @@ func TestJWT_RoundTrip(t *testing.T) {
 	assert.Equal(t, userID, claims.UserID)
 	assert.Equal(t, username, claims.Username)
 	assert.Equal(t, email, claims.Email)
+	assert.Equal(t, role, claims.Role)
+	assert.Equal(t, uint(5), claims.SessionID)
 
 	// Verify timestamps
 	assert.True(t, claims.ExpiresAt.After(time.Now()), "Token should not be expired")
 	assert.True(t, claims.IssuedAt.Before(time.Now().Add(time.Second)), "IssuedAt should be in the past")
 }
+
+// TestValidateAccessToken tests that access tokens are only accepted for active sessions
+func TestValidateAccessToken(t *testing.T) {
+	// Configure token signing for tests
+	ConfigureJWT(config.JWTConfig{Secret: "test-secret-key-for-testing", AccessTokenTTL: 24 * time.Hour})
+
+	originalChecker := SessionChecker
+	defer func() { SessionChecker = originalChecker }()
+
+	// Session 1 is active, session 2 has been revoked
+	SessionChecker = func(ctx context.Context, sessionID uint) (bool, error) {
+		return sessionID == 1, nil
+	}
+
+	tests := []struct {
+		name        string
+		sessionID   uint
+		expectValid bool
+	}{
+		{
+			name:        "Active Session",
+			sessionID:   1,
+			expectValid: true,
+		},
+		{
+			name:        "Revoked Session",
+			sessionID:   2,
+			expectValid: false,
+		},
+		{
+			name:        "No Session",
+			sessionID:   0,
+			expectValid: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := GenerateJWT(1, "testuser", "test@example.com", "user", tc.sessionID)
+			assert.NoError(t, err)
+
+			claims, err := ValidateAccessToken(context.Background(), token)
+
+			if tc.expectValid {
+				assert.NoError(t, err, "Should accept token of an active session")
+				assert.Equal(t, tc.sessionID, claims.SessionID)
+			} else {
+				assert.Error(t, err, "Should reject token without an active session")
+				assert.Nil(t, claims)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexs/golang_test/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTicketCode_RoundTrip tests signing and verifying a ticket code
+func TestTicketCode_RoundTrip(t *testing.T) {
+	// Configure token signing for tests
+	ConfigureJWT(config.JWTConfig{Secret: "test-secret-key-for-testing", AccessTokenTTL: 24 * time.Hour})
+
+	code, err := GenerateTicketCode(7, 42)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(code, "7.42."), "Code should embed event and booking IDs")
+
+	eventID, bookingID, err := VerifyTicketCode(code)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), eventID)
+	assert.Equal(t, uint(42), bookingID)
+
+	// Each ticket gets a unique code even for the same booking
+	other, err := GenerateTicketCode(7, 42)
+	assert.NoError(t, err)
+	assert.NotEqual(t, code, other, "Ticket codes should be unique")
+}
+
+// TestVerifyTicketCode_Invalid tests rejection of forged or malformed codes
+func TestVerifyTicketCode_Invalid(t *testing.T) {
+	// Configure token signing for tests
+	ConfigureJWT(config.JWTConfig{Secret: "test-secret-key-for-testing", AccessTokenTTL: 24 * time.Hour})
+
+	valid, err := GenerateTicketCode(1, 2)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name string
+		code string
+	}{
+		{
+			name: "Empty Code",
+			code: "",
+		},
+		{
+			name: "No Signature",
+			code: "1.2.abcdef",
+		},
+		{
+			name: "Tampered Event ID",
+			code: "9" + valid[1:],
+		},
+		{
+			name: "Tampered Signature",
+			code: valid[:len(valid)-2] + "xx",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := VerifyTicketCode(tc.code)
+			assert.Error(t, err, "Should reject invalid ticket code")
+		})
+	}
+}
+
+// TestVerifyTicketCode_WrongSecret tests that codes signed with another secret are rejected
+func TestVerifyTicketCode_WrongSecret(t *testing.T) {
+	originalConfig := jwtConfig
+	defer ConfigureJWT(originalConfig)
+
+	ConfigureJWT(config.JWTConfig{Secret: "secret1"})
+	code, err := GenerateTicketCode(1, 1)
+	assert.NoError(t, err)
+
+	ConfigureJWT(config.JWTConfig{Secret: "secret2"})
+	_, _, err = VerifyTicketCode(code)
+	assert.Error(t, err, "Should reject code signed with a different secret")
+}
+
+// TestRenderQRCode tests PNG and SVG QR rendering
+func TestRenderQRCode(t *testing.T) {
+	png, err := RenderQRCodePNG("1.2.abcdef.signature", 256)
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(png, []byte("\x89PNG")), "Should produce a PNG image")
+
+	svg, err := RenderQRCodeSVG("1.2.abcdef.signature")
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(svg, []byte("<svg")), "Should produce an SVG document")
+	assert.True(t, bytes.HasSuffix(svg, []byte("</svg>")))
+}
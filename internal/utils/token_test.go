@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGenerateOpaqueToken tests that opaque tokens are random and URL-safe
+func TestGenerateOpaqueToken(t *testing.T) {
+	token, err := GenerateOpaqueToken()
+	assert.NoError(t, err)
+	assert.Len(t, token, 43, "32 random bytes should encode to 43 base64url characters")
+	assert.NotContains(t, token, "+")
+	assert.NotContains(t, token, "/")
+	assert.NotContains(t, token, "=")
+
+	other, err := GenerateOpaqueToken()
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, other, "Tokens should be unique")
+}
+
+// TestHashToken tests that token hashes are stable and do not leak the token
+func TestHashToken(t *testing.T) {
+	hash := HashToken("my-refresh-token")
+
+	assert.Len(t, hash, 64, "SHA-256 hex digest should be 64 characters")
+	assert.Equal(t, hash, HashToken("my-refresh-token"), "Hash should be deterministic")
+	assert.NotEqual(t, hash, HashToken("my-refresh-token2"), "Different tokens should hash differently")
+	assert.NotContains(t, hash, "my-refresh-token")
+}
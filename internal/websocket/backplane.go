@@ -0,0 +1,121 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Backplane fans hub messages out to every API replica. A hub with a backplane
+// publishes broadcasts to it instead of delivering them directly, and delivers
+// whatever the backplane hands back to its own clients - including the messages
+// it published itself.
+type Backplane interface {
+	// Publish sends a message to every subscribed hub
+	Publish(ctx context.Context, msg *Message) error
+
+	// Subscribe calls deliver for each published message until ctx is done
+	Subscribe(ctx context.Context, deliver func(*Message)) error
+
+	// Close releases the backplane's resources
+	Close() error
+}
+
+// envelope carries a message between replicas along with the routing fields
+// that are hidden from clients, and the trace context it was published in
+type envelope struct {
+	Message *Message               `json:"message"`
+	UserID  *uint                  `json:"user_id,omitempty"`
+	Event   *EventInfo             `json:"event,omitempty"`
+	Trace   propagation.MapCarrier `json:"trace,omitempty"`
+}
+
+// encodeEnvelope serializes a message for the backplane
+func encodeEnvelope(msg *Message) ([]byte, error) {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(trace.ContextWithSpanContext(context.Background(), msg.spanContext), carrier)
+
+	return json.Marshal(envelope{Message: msg, UserID: msg.UserID, Event: msg.Event, Trace: carrier})
+}
+
+// decodeEnvelope restores a message received from the backplane. The payload is
+// kept as raw JSON so it is forwarded to clients exactly as it was published.
+func decodeEnvelope(payload []byte) (*Message, error) {
+	var raw struct {
+		Message struct {
+			Message
+			Data json.RawMessage `json:"data,omitempty"`
+		} `json:"message"`
+		UserID *uint                  `json:"user_id,omitempty"`
+		Event  *EventInfo             `json:"event,omitempty"`
+		Trace  propagation.MapCarrier `json:"trace,omitempty"`
+	}
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, err
+	}
+
+	msg := raw.Message.Message
+	if len(raw.Message.Data) > 0 {
+		msg.Data = raw.Message.Data
+	}
+	msg.UserID = raw.UserID
+	msg.Event = raw.Event
+	msg.spanContext = trace.SpanContextFromContext(propagation.TraceContext{}.Extract(context.Background(), raw.Trace))
+	return &msg, nil
+}
+
+// MemoryBackplane connects hubs within a single process. It is meant for tests
+// and single-replica deployments.
+type MemoryBackplane struct {
+	subscribers map[int]func(*Message)
+	nextID      int
+	mutex       sync.RWMutex
+}
+
+// NewMemoryBackplane creates an in-process backplane
+func NewMemoryBackplane() *MemoryBackplane {
+	return &MemoryBackplane{subscribers: make(map[int]func(*Message))}
+}
+
+// Publish delivers the message to every subscriber. Messages go through the same
+// encoding as other backplanes so routing fields survive the trip.
+func (b *MemoryBackplane) Publish(ctx context.Context, msg *Message) error {
+	payload, err := encodeEnvelope(msg)
+	if err != nil {
+		return err
+	}
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for _, deliver := range b.subscribers {
+		decoded, err := decodeEnvelope(payload)
+		if err != nil {
+			return err
+		}
+		deliver(decoded)
+	}
+	return nil
+}
+
+func (b *MemoryBackplane) Subscribe(ctx context.Context, deliver func(*Message)) error {
+	b.mutex.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = deliver
+	b.mutex.Unlock()
+
+	<-ctx.Done()
+
+	b.mutex.Lock()
+	delete(b.subscribers, id)
+	b.mutex.Unlock()
+	return nil
+}
+
+func (b *MemoryBackplane) Close() error {
+	return nil
+}
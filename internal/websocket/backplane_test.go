@@ -0,0 +1,238 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTestClient registers a client without a network connection and drains its connection ack
+func newTestClient(t *testing.T, hub *Hub, id string, userID uint) *Client {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &Client{
+		ID:       id,
+		hub:      hub,
+		send:     make(chan []byte, 16),
+		eventIDs: make(map[uint]bool),
+		filters:  make(map[SubscriptionFilter]bool),
+		userID:   userID,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	require.NoError(t, hub.add(client))
+
+	msg := receive(t, client)
+	require.Equal(t, MessageTypeConnectionAck, msg.Type)
+	return client
+}
+
+// receive waits for the next message sent to a client
+func receive(t *testing.T, client *Client) *Message {
+	select {
+	case data := <-client.send:
+		var msg Message
+		require.NoError(t, json.Unmarshal(data, &msg))
+		return &msg
+	case <-time.After(time.Second):
+		t.Fatalf("client %s received no message", client.ID)
+		return nil
+	}
+}
+
+// assertNoMessage checks that nothing was sent to a client
+func assertNoMessage(t *testing.T, client *Client) {
+	select {
+	case data := <-client.send:
+		t.Fatalf("client %s received unexpected message: %s", client.ID, data)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// waitForSubscribers blocks until n hubs are subscribed to the backplane
+func waitForSubscribers(t *testing.T, backplane *MemoryBackplane, n int) {
+	require.Eventually(t, func() bool {
+		backplane.mutex.RLock()
+		defer backplane.mutex.RUnlock()
+		return len(backplane.subscribers) == n
+	}, time.Second, 5*time.Millisecond)
+}
+
+// TestMemoryBackplane_FansOutAcrossHubs tests that broadcasts reach clients on every hub
+func TestMemoryBackplane_FansOutAcrossHubs(t *testing.T) {
+	backplane := NewMemoryBackplane()
+	hubA := NewHubWithBackplane(backplane)
+	hubB := NewHubWithBackplane(backplane)
+	go hubA.Run()
+	go hubB.Run()
+	waitForSubscribers(t, backplane, 2)
+
+	clientA := newTestClient(t, hubA, "client-a", 1)
+	clientB := newTestClient(t, hubB, "client-b", 2)
+	other := newTestClient(t, hubB, "client-other", 3)
+	hubA.SubscribeToEvent(clientA, 42)
+	hubB.SubscribeToEvent(clientB, 42)
+	hubB.SubscribeToEvent(other, 7)
+
+	hubA.BroadcastAvailabilityUpdate(context.Background(), EventInfo{ID: 42}, 10, 100, nil)
+
+	for _, client := range []*Client{clientA, clientB} {
+		msg := receive(t, client)
+		assert.Equal(t, MessageTypeAvailabilityUpdate, msg.Type)
+		assert.Equal(t, uint(42), *msg.EventID)
+
+		data := msg.Data.(map[string]interface{})
+		assert.Equal(t, float64(10), data["available_tickets"])
+		assert.Equal(t, float64(100), data["capacity"])
+	}
+	assertNoMessage(t, other)
+}
+
+// TestMemoryBackplane_UserMessages tests that user-targeted messages keep their routing across hubs
+func TestMemoryBackplane_UserMessages(t *testing.T) {
+	backplane := NewMemoryBackplane()
+	hubA := NewHubWithBackplane(backplane)
+	hubB := NewHubWithBackplane(backplane)
+	go hubA.Run()
+	go hubB.Run()
+	waitForSubscribers(t, backplane, 2)
+
+	target := newTestClient(t, hubB, "target", 5)
+	bystander := newTestClient(t, hubA, "bystander", 6)
+
+	hubA.SendToUser(context.Background(), 5, &Message{
+		Type: MessageTypeWaitlistOffer,
+		Data: WaitlistOffer{EventID: 1, HoldID: 9, Quantity: 2},
+	})
+
+	msg := receive(t, target)
+	assert.Equal(t, MessageTypeWaitlistOffer, msg.Type)
+	assert.Equal(t, float64(9), msg.Data.(map[string]interface{})["hold_id"])
+	assertNoMessage(t, bystander)
+}
+
+// TestMemoryBackplane_ContinuesTrace tests that broadcasts on every hub join the trace of the publishing request
+func TestMemoryBackplane_ContinuesTrace(t *testing.T) {
+	provider := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(provider) })
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	backplane := NewMemoryBackplane()
+	hubA := NewHubWithBackplane(backplane)
+	hubB := NewHubWithBackplane(backplane)
+	go hubA.Run()
+	go hubB.Run()
+	waitForSubscribers(t, backplane, 2)
+
+	clientB := newTestClient(t, hubB, "client-b", 2)
+	hubB.SubscribeToEvent(clientB, 42)
+
+	ctx, request := otel.Tracer("test").Start(context.Background(), "POST /events/{id}/book")
+	hubA.BroadcastAvailabilityUpdate(ctx, EventInfo{ID: 42}, 10, 100, nil)
+	request.End()
+	receive(t, clientB)
+
+	spansNamed := func(name string) tracetest.SpanStubs {
+		var spans tracetest.SpanStubs
+		for _, span := range exporter.GetSpans() {
+			if span.Name == name {
+				spans = append(spans, span)
+			}
+		}
+		return spans
+	}
+	require.Eventually(t, func() bool { return len(spansNamed("hub.broadcast")) == 2 }, time.Second, 10*time.Millisecond)
+
+	publish := spansNamed("hub.publish")
+	require.Len(t, publish, 1)
+	assert.Equal(t, request.SpanContext().SpanID(), publish[0].Parent.SpanID())
+
+	for _, broadcast := range spansNamed("hub.broadcast") {
+		assert.Equal(t, request.SpanContext().TraceID(), broadcast.SpanContext.TraceID())
+		assert.Equal(t, publish[0].SpanContext.SpanID(), broadcast.Parent.SpanID())
+	}
+}
+
+// TestEnvelope_RoundTrip tests that routing fields survive encoding while staying hidden from clients
+func TestEnvelope_RoundTrip(t *testing.T) {
+	eventID := uint(3)
+	userID := uint(8)
+	original := &Message{
+		Type:      MessageTypeSeatUpdate,
+		EventID:   &eventID,
+		Timestamp: time.Now().UTC().Truncate(time.Millisecond),
+		Data:      SeatUpdate{EventID: 3, SeatIDs: []uint{1, 2}, Status: "booked"},
+		UserID:    &userID,
+		Event:     &EventInfo{ID: 3, City: "Aarhus", EventType: "concert", OrganizerID: 4},
+	}
+
+	payload, err := encodeEnvelope(original)
+	require.NoError(t, err)
+
+	decoded, err := decodeEnvelope(payload)
+	require.NoError(t, err)
+	assert.Equal(t, original.Type, decoded.Type)
+	assert.Equal(t, eventID, *decoded.EventID)
+	assert.Equal(t, userID, *decoded.UserID)
+	assert.Equal(t, *original.Event, *decoded.Event)
+	assert.True(t, original.Timestamp.Equal(decoded.Timestamp))
+
+	// The client-facing JSON matches what would have been sent without the backplane
+	want, err := json.Marshal(original)
+	require.NoError(t, err)
+	got, err := json.Marshal(decoded)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(want), string(got))
+	assert.NotContains(t, string(got), "user_id")
+	assert.NotContains(t, string(got), "organizer_id")
+}
+
+// TestPostgresBackplane tests LISTEN/NOTIFY delivery between two backplanes.
+// It needs a real Postgres and is skipped unless TEST_DATABASE_DSN is set.
+func TestPostgresBackplane(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN not set; skipping Postgres backplane test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	channel := "ticket_hub_test"
+	publisher, err := NewPostgresBackplane(ctx, dsn, channel)
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	listener, err := NewPostgresBackplane(ctx, dsn, channel)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	received := make(chan *Message, 1)
+	listenCtx, stopListening := context.WithCancel(ctx)
+	defer stopListening()
+	go listener.Subscribe(listenCtx, func(msg *Message) { received <- msg })
+
+	// LISTEN is asynchronous, so keep publishing until the first delivery
+	eventID := uint(11)
+	msg := &Message{Type: MessageTypeAvailabilityUpdate, EventID: &eventID, Timestamp: time.Now()}
+	for {
+		require.NoError(t, publisher.Publish(ctx, msg))
+		select {
+		case got := <-received:
+			assert.Equal(t, MessageTypeAvailabilityUpdate, got.Type)
+			assert.Equal(t, eventID, *got.EventID)
+			return
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatal("no notification received")
+		}
+	}
+}
@@ -1,12 +1,17 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+
+	"github.com/alexs/golang_test/internal/models"
 )
 
 const (
@@ -40,29 +45,126 @@ type Client struct {
 	// Map of event IDs this client is subscribed to
 	eventIDs map[uint]bool
 
+	// Set of filter subscriptions matched against every event broadcast
+	filters map[SubscriptionFilter]bool
+
 	// User ID from JWT authentication
 	userID uint
 
+	// Role from JWT authentication
+	role string
+
+	// Whether this connection shows the organizer sales dashboard
+	salesDashboard bool
+
+	// Limits the rate of inbound messages
+	limiter *tokenBucket
+
+	// Close frame writePump sends once the send channel is closed; set by closeSend
+	closeFrame []byte
+
+	// Context of the request that opened the connection, carrying its request
+	// ID and trace but not its cancellation; cancelled once the client is closed
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Whether the send channel is closed. Producers check it under mutex, so the
+	// channel can be closed while the read pump is still replying to messages.
+	closed bool
+
+	// Closed when writePump has returned
+	written chan struct{}
+
 	// Mutex for thread-safe access
 	mutex sync.RWMutex
 }
 
-// NewClient creates a new Client instance
-func NewClient(id string, hub *Hub, conn *websocket.Conn, userID uint) *Client {
+// logger returns the default logger with the client and user IDs attached,
+// so every line about a connection can be traced back to it
+func (c *Client) logger() *slog.Logger {
+	return slog.With("client_id", c.ID, "user_id", c.userID)
+}
+
+// NewClient creates a new Client instance for the request whose context is ctx
+func NewClient(ctx context.Context, id string, hub *Hub, conn *websocket.Conn, userID uint) *Client {
+	limits := hub.currentLimits()
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
 	return &Client{
 		ID:       id,
 		hub:      hub,
 		conn:     conn,
 		send:     make(chan []byte, 256),
 		eventIDs: make(map[uint]bool),
+		filters:  make(map[SubscriptionFilter]bool),
 		userID:   userID,
+		limiter:  newTokenBucket(limits.MessageRate, limits.MessageBurst),
+		written:  make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// closeWith sends a close frame with the given code and reason and closes the
+// connection. It is safe to call while the pumps are running.
+func (c *Client) closeWith(code int, reason string) {
+	if c.conn == nil {
+		return
 	}
+
+	message := websocket.FormatCloseMessage(code, reason)
+	if err := c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(writeWait)); err != nil {
+		c.logger().Warn("Failed to send close frame", "error", err)
+	}
+	c.conn.Close()
+}
+
+// closeSend closes the send channel, after which nothing more is queued.
+// writePump sends closeFrame once it has written the queued messages; nil sends
+// an empty close frame. Closing an already closed client does nothing.
+func (c *Client) closeSend(closeFrame []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.closeFrame = closeFrame
+	close(c.send)
+	c.cancel()
+}
+
+// Messages returns the channel of serialized messages queued for this client.
+// It is closed when the client is unregistered.
+func (c *Client) Messages() <-chan []byte {
+	return c.send
+}
+
+// matchesFilter reports whether any of the client's filter subscriptions matches the event
+func (c *Client) matchesFilter(event *EventInfo) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	for filter := range c.filters {
+		if filter.Matches(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchingSales reports whether the client subscribed to the sales dashboard
+func (c *Client) watchingSales() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.salesDashboard
 }
 
 // readPump pumps messages from the WebSocket connection to the hub
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.unregister <- c
+		c.hub.Unregister(c)
 		c.conn.Close()
 	}()
 
@@ -77,15 +179,21 @@ func (c *Client) readPump() {
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket error for client %s: %v", c.ID, err)
+				c.logger().Warn("WebSocket closed unexpectedly", "error", err)
 			}
 			break
 		}
 
+		if !c.limiter.allow(time.Now()) {
+			c.logger().Warn("Client exceeded the message rate limit")
+			c.closeWith(websocket.ClosePolicyViolation, "Rate limit exceeded")
+			break
+		}
+
 		// Parse the message
 		var clientMsg ClientMessage
 		if err := json.Unmarshal(message, &clientMsg); err != nil {
-			log.Printf("Error parsing message from client %s: %v", c.ID, err)
+			c.logger().Warn("Failed to parse client message", "error", err)
 			c.sendError("Invalid message format")
 			continue
 		}
@@ -101,6 +209,7 @@ func (c *Client) writePump() {
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
+		close(c.written)
 	}()
 
 	for {
@@ -109,7 +218,11 @@ func (c *Client) writePump() {
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
 				// The hub closed the channel
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				closeFrame := c.closeFrame
+				if closeFrame == nil {
+					closeFrame = []byte{}
+				}
+				c.conn.WriteMessage(websocket.CloseMessage, closeFrame)
 				return
 			}
 
@@ -143,19 +256,94 @@ func (c *Client) writePump() {
 func (c *Client) handleMessage(msg *ClientMessage) {
 	switch msg.Type {
 	case MessageTypeSubscribe:
-		if msg.EventID != nil {
-			c.hub.SubscribeToEvent(c, *msg.EventID)
-		} else {
-			c.sendError("Event ID required for subscription")
+		eventIDs := msg.eventIDs()
+		if len(eventIDs) == 0 && msg.Filter == nil {
+			c.sendError("Event ID, event IDs or filter required for subscription")
+			return
+		}
+
+		if len(eventIDs) > 0 {
+			if err := c.hub.SubscribeToEvents(c, eventIDs); err != nil {
+				c.sendError(fmt.Sprintf("Cannot subscribe to more than %d events", maxEventSubscriptions))
+				return
+			}
+		}
+
+		if msg.Filter != nil {
+			if err := c.hub.SubscribeToFilter(c, *msg.Filter); err != nil {
+				if errors.Is(err, errFilterSubscriptionLimit) {
+					c.sendError(fmt.Sprintf("Cannot subscribe to more than %d filters", maxFilterSubscriptions))
+				} else {
+					c.sendError("Filter needs a city, event type or organizer")
+				}
+			}
 		}
 
 	case MessageTypeUnsubscribe:
-		if msg.EventID != nil {
-			c.hub.UnsubscribeFromEvent(c, *msg.EventID)
-		} else {
-			c.sendError("Event ID required for unsubscription")
+		eventIDs := msg.eventIDs()
+		if len(eventIDs) == 0 && msg.Filter == nil {
+			c.sendError("Event ID, event IDs or filter required for unsubscription")
+			return
+		}
+
+		for _, eventID := range eventIDs {
+			c.hub.UnsubscribeFromEvent(c, eventID)
+		}
+		if msg.Filter != nil {
+			c.hub.UnsubscribeFromFilter(c, *msg.Filter)
+		}
+
+	case MessageTypeResume:
+		if msg.EventID == nil || msg.LastSeq == nil {
+			c.sendError("Event ID and last sequence required to resume")
+			return
+		}
+
+		if c.hub.Resume(c, *msg.EventID, msg.Epoch, *msg.LastSeq) {
+			return
+		}
+
+		// Subscribe before asking for a resync, so the state the client fetches
+		// is followed by every later broadcast
+		if err := c.hub.SubscribeToEvents(c, []uint{*msg.EventID}); err != nil {
+			c.sendError(fmt.Sprintf("Cannot subscribe to more than %d events", maxEventSubscriptions))
+			return
+		}
+
+		c.sendMessage(&Message{
+			Type:      MessageTypeResyncRequired,
+			EventID:   msg.EventID,
+			Timestamp: time.Now(),
+			Data:      ResyncRequired{EventID: *msg.EventID},
+		})
+
+	case MessageTypeSubscribeSales:
+		if c.role != models.RoleOrganizer && c.role != models.RoleAdmin {
+			c.sendError("Only organizers can subscribe to sales")
+			return
+		}
+
+		updates, err := c.hub.SubscribeToSales(c)
+		if err != nil {
+			c.logger().Error("Failed to load sales", "error", err)
+			c.sendError("Failed to load current sales")
+			return
 		}
 
+		// Start the dashboard with the current figures of every owned event
+		for _, update := range updates {
+			eventID := update.EventID
+			c.sendMessage(&Message{
+				Type:      MessageTypeSalesUpdate,
+				EventID:   &eventID,
+				Timestamp: time.Now(),
+				Data:      update,
+			})
+		}
+
+	case MessageTypeUnsubscribeSales:
+		c.hub.UnsubscribeFromSales(c)
+
 	case MessageTypePing:
 		// Respond with pong
 		pongMsg := &Message{
@@ -164,38 +352,73 @@ func (c *Client) handleMessage(msg *ClientMessage) {
 		}
 		data, err := json.Marshal(pongMsg)
 		if err == nil {
-			select {
-			case c.send <- data:
-			default:
-				log.Printf("Failed to send pong to client %s", c.ID)
-			}
+			c.trySend(data, pongMsg.Type)
 		}
 
 	default:
-		log.Printf("Unknown message type from client %s: %s", c.ID, msg.Type)
+		c.logger().Warn("Unknown client message type", "type", msg.Type)
 		c.sendError("Unknown message type")
 	}
 }
 
 // sendError sends an error message to the client
 func (c *Client) sendError(errorMsg string) {
-	msg := &Message{
+	c.sendMessage(&Message{
 		Type:      MessageTypeError,
 		Timestamp: time.Now(),
 		Data: ErrorMessage{
 			Message: errorMsg,
 		},
-	}
+	})
+}
 
+// sendMessage queues a message for this client only
+func (c *Client) sendMessage(msg *Message) {
 	data, err := json.Marshal(msg)
 	if err != nil {
-		log.Printf("Error marshaling error message: %v", err)
+		c.logger().Error("Failed to marshal message", "type", msg.Type, "error", err)
 		return
 	}
 
+	c.trySend(data, msg.Type)
+}
+
+// trySendAll queues every message without blocking, or none of them when they
+// don't all fit in the client's buffer or the client is closed
+func (c *Client) trySendAll(messages [][]byte) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if c.closed || len(messages) > cap(c.send)-len(c.send) {
+		return false
+	}
+	for _, data := range messages {
+		select {
+		case c.send <- data:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// trySend queues serialized data without blocking. When the client's buffer is
+// full the message is dropped and counted; once the client is closed it is
+// dropped silently.
+func (c *Client) trySend(data []byte, msgType MessageType) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if c.closed {
+		return false
+	}
+
 	select {
 	case c.send <- data:
+		return true
 	default:
-		log.Printf("Failed to send error message to client %s (buffer full)", c.ID)
+		c.logger().Warn("Dropped message, client buffer full", "type", msgType)
+		c.hub.recordDrop(msgType)
+		return false
 	}
 }
@@ -0,0 +1,81 @@
+package websocket
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultCoalesceWindow is how long availability requests for an event are
+// collected before its availability is computed and broadcast once
+const defaultCoalesceWindow = 250 * time.Millisecond
+
+// coalescer runs flush at most once per window for each event, no matter how
+// many times the event is requested within that window
+type coalescer struct {
+	window time.Duration
+	flush  func(eventID uint)
+
+	// pending holds the events with a flush scheduled
+	pending map[uint]*time.Timer
+	stopped bool
+	mutex   sync.Mutex
+
+	// flushing tracks scheduled and running flushes so stop can wait for them
+	flushing sync.WaitGroup
+
+	// coalesced counts requests absorbed by an already scheduled flush
+	coalesced atomic.Uint64
+}
+
+// newCoalescer creates a coalescer calling flush after window
+func newCoalescer(window time.Duration, flush func(eventID uint)) *coalescer {
+	return &coalescer{
+		window:  window,
+		flush:   flush,
+		pending: make(map[uint]*time.Timer),
+	}
+}
+
+// request schedules a flush for the event unless one is already pending. The
+// flush happens after the window, so it sees every change requested until then.
+func (c *coalescer) request(eventID uint) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.stopped {
+		return
+	}
+	if _, scheduled := c.pending[eventID]; scheduled {
+		c.coalesced.Add(1)
+		return
+	}
+
+	c.flushing.Add(1)
+	c.pending[eventID] = time.AfterFunc(c.window, func() {
+		defer c.flushing.Done()
+
+		// Requests arriving from here on schedule the next flush
+		c.mutex.Lock()
+		delete(c.pending, eventID)
+		c.mutex.Unlock()
+
+		c.flush(eventID)
+	})
+}
+
+// stop cancels the scheduled flushes, ignores later requests and waits for
+// flushes that already started to finish
+func (c *coalescer) stop() {
+	c.mutex.Lock()
+	c.stopped = true
+	for eventID, timer := range c.pending {
+		if timer.Stop() {
+			c.flushing.Done()
+		}
+		delete(c.pending, eventID)
+	}
+	c.mutex.Unlock()
+
+	c.flushing.Wait()
+}
@@ -0,0 +1,58 @@
+package websocket
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCoalescer_OneFlushPerWindow tests that a burst of requests refreshes each event once
+func TestCoalescer_OneFlushPerWindow(t *testing.T) {
+	var mutex sync.Mutex
+	flushes := make(map[uint]int)
+	c := newCoalescer(20*time.Millisecond, func(eventID uint) {
+		mutex.Lock()
+		flushes[eventID]++
+		mutex.Unlock()
+	})
+	flushCount := func(eventID uint) int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return flushes[eventID]
+	}
+
+	for i := 0; i < 100; i++ {
+		c.request(1)
+	}
+	c.request(2)
+
+	require.Eventually(t, func() bool { return flushCount(1) == 1 && flushCount(2) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, uint64(99), c.coalesced.Load())
+
+	// A request after the flush schedules the next one
+	c.request(1)
+	require.Eventually(t, func() bool { return flushCount(1) == 2 }, time.Second, 5*time.Millisecond)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 2, flushCount(1))
+	assert.Equal(t, 1, flushCount(2))
+}
+
+// TestCoalescer_Stop tests that stopping cancels scheduled flushes and ignores new requests
+func TestCoalescer_Stop(t *testing.T) {
+	var flushes atomic.Int32
+	c := newCoalescer(20*time.Millisecond, func(eventID uint) {
+		flushes.Add(1)
+	})
+
+	c.request(1)
+	c.stop()
+	c.request(2)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(0), flushes.Load())
+}
@@ -0,0 +1,51 @@
+package websocket
+
+import (
+	"errors"
+	"strings"
+)
+
+// EventInfo describes the event a broadcast is about so it can be matched
+// against filter subscriptions. It is never sent to clients.
+type EventInfo struct {
+	ID          uint   `json:"id"`
+	City        string `json:"city,omitempty"`
+	EventType   string `json:"event_type,omitempty"`
+	OrganizerID uint   `json:"organizer_id,omitempty"`
+}
+
+// SubscriptionFilter follows every event matching all of its non-empty fields,
+// such as all concerts or all events in one city
+type SubscriptionFilter struct {
+	City        string `json:"city,omitempty"`
+	EventType   string `json:"event_type,omitempty"`
+	OrganizerID uint   `json:"organizer_id,omitempty"`
+}
+
+// normalize trims and lower-cases the text fields so equivalent filters compare equal
+func (f SubscriptionFilter) normalize() (SubscriptionFilter, error) {
+	f.City = strings.ToLower(strings.TrimSpace(f.City))
+	f.EventType = strings.ToLower(strings.TrimSpace(f.EventType))
+
+	if f.City == "" && f.EventType == "" && f.OrganizerID == 0 {
+		return f, errors.New("filter needs a city, event type or organizer")
+	}
+	return f, nil
+}
+
+// Matches reports whether an event satisfies every field set on the filter
+func (f SubscriptionFilter) Matches(event *EventInfo) bool {
+	if event == nil {
+		return false
+	}
+	if f.City != "" && !strings.EqualFold(f.City, strings.TrimSpace(event.City)) {
+		return false
+	}
+	if f.EventType != "" && !strings.EqualFold(f.EventType, strings.TrimSpace(event.EventType)) {
+		return false
+	}
+	if f.OrganizerID != 0 && f.OrganizerID != event.OrganizerID {
+		return false
+	}
+	return true
+}
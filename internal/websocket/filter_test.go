@@ -0,0 +1,45 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSubscriptionFilter_Matches tests matching filters against broadcast events
+func TestSubscriptionFilter_Matches(t *testing.T) {
+	event := &EventInfo{ID: 1, City: "Copenhagen", EventType: "concert", OrganizerID: 7}
+
+	tests := []struct {
+		name     string
+		filter   SubscriptionFilter
+		expected bool
+	}{
+		{name: "City", filter: SubscriptionFilter{City: "copenhagen"}, expected: true},
+		{name: "Other City", filter: SubscriptionFilter{City: "Aarhus"}, expected: false},
+		{name: "Event Type", filter: SubscriptionFilter{EventType: "Concert"}, expected: true},
+		{name: "Organizer", filter: SubscriptionFilter{OrganizerID: 7}, expected: true},
+		{name: "All Fields", filter: SubscriptionFilter{City: "Copenhagen", EventType: "concert", OrganizerID: 7}, expected: true},
+		{name: "One Field Differs", filter: SubscriptionFilter{City: "Copenhagen", EventType: "standup"}, expected: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.filter.Matches(event))
+		})
+	}
+
+	assert.False(t, SubscriptionFilter{City: "Copenhagen"}.Matches(nil), "Broadcasts without event info match no filter")
+}
+
+// TestSubscriptionFilter_Normalize tests that equivalent filters compare equal and empty ones are rejected
+func TestSubscriptionFilter_Normalize(t *testing.T) {
+	a, err := SubscriptionFilter{City: " Copenhagen "}.normalize()
+	assert.NoError(t, err)
+	b, err := SubscriptionFilter{City: "copenhagen"}.normalize()
+	assert.NoError(t, err)
+	assert.Equal(t, a, b)
+
+	_, err = SubscriptionFilter{City: "  "}.normalize()
+	assert.Error(t, err)
+}
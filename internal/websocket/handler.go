@@ -1,27 +1,25 @@
 package websocket
 
 import (
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 
+	"github.com/alexs/golang_test/internal/logging"
 	"github.com/alexs/golang_test/internal/utils"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// Allow connections from the frontend
-		// In production, you should check the origin more strictly
-		return true
-	},
-}
+// HandleWebSocket handles WebSocket upgrade requests from the allowed origins
+func HandleWebSocket(hub *Hub, allowedOrigins []string) http.HandlerFunc {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     originChecker(allowedOrigins),
+	}
 
-// HandleWebSocket handles WebSocket upgrade requests
-func HandleWebSocket(hub *Hub) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Extract JWT token from query parameter
 		token := r.URL.Query().Get("token")
@@ -30,32 +28,43 @@ func HandleWebSocket(hub *Hub) http.HandlerFunc {
 			return
 		}
 
-		// Validate JWT token
-		claims, err := utils.ValidateJWT(token)
+		// Validate JWT token and make sure its session hasn't been revoked
+		claims, err := utils.ValidateAccessToken(r.Context(), token)
 		if err != nil {
-			log.Printf("WebSocket authentication failed: %v", err)
+			slog.WarnContext(r.Context(), "WebSocket authentication failed", "error", err)
 			http.Error(w, "Invalid authentication token", http.StatusUnauthorized)
 			return
 		}
 
+		logging.SetUserID(r.Context(), claims.UserID)
+
 		// Upgrade HTTP connection to WebSocket
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
-			log.Printf("WebSocket upgrade failed: %v", err)
+			slog.WarnContext(r.Context(), "WebSocket upgrade failed", "error", err)
 			return
 		}
 
 		// Create a new client
 		clientID := uuid.New().String()
-		client := NewClient(clientID, hub, conn, claims.UserID)
+		client := NewClient(r.Context(), clientID, hub, conn, claims.UserID)
+		client.role = claims.Role
 
-		// Register the client with the hub
-		hub.register <- client
+		// Register the client with the hub before its pumps start, so a rejected
+		// client never reads or queues a message
+		if err := hub.add(client); err != nil {
+			if errors.Is(err, errTooManyConnections) {
+				client.closeWith(websocket.CloseTryAgainLater, "Too many connections")
+			} else {
+				client.closeWith(websocket.CloseGoingAway, "Server shutting down")
+			}
+			return
+		}
 
 		// Start the client's read and write pumps in separate goroutines
 		go client.writePump()
 		go client.readPump()
 
-		log.Printf("WebSocket connection established for user %d (client %s)", claims.UserID, clientID)
+		slog.InfoContext(r.Context(), "WebSocket connection established", "client_id", clientID)
 	}
 }
@@ -1,18 +1,59 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"sync"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const (
+	// Maximum number of events a single client can subscribe to
+	maxEventSubscriptions = 200
+
+	// Maximum number of filter subscriptions a single client can hold
+	maxFilterSubscriptions = 10
+)
+
+var (
+	errEventSubscriptionLimit  = errors.New("event subscription limit reached")
+	errFilterSubscriptionLimit = errors.New("filter subscription limit reached")
+	errTooManyConnections      = errors.New("too many connections")
+	errHubStopped              = errors.New("hub is shutting down")
+)
+
+// registration asks Run to admit a client; the verdict is sent on admitted
+type registration struct {
+	client   *Client
+	admitted chan error
+}
+
+// tracer records publish and broadcast spans with the global tracer provider
+var tracer = otel.Tracer("github.com/alexs/golang_test/internal/websocket")
+
 // Hub maintains the set of active clients and broadcasts messages to the clients
 type Hub struct {
 	// eventClients maps eventID to a map of clientID to Client
 	// This allows efficient broadcasting to event-specific subscribers
 	eventClients map[uint]map[string]*Client
 
+	// userClients maps userID to a map of clientID to Client, so a user's
+	// connections can be reached without scanning every client
+	userClients map[uint]map[string]*Client
+
+	// filterClients maps clientID to Client for clients holding filter
+	// subscriptions, which are matched against every event broadcast
+	filterClients map[string]*Client
+
 	// clients is a map of all connected clients
 	clients map[*Client]bool
 
@@ -20,50 +61,279 @@ type Hub struct {
 	broadcast chan *Message
 
 	// register channel for registering new clients
-	register chan *Client
+	register chan registration
 
 	// unregister channel for unregistering clients
 	unregister chan *Client
 
+	// backplane fans broadcasts out to other replicas; nil delivers locally only
+	backplane Backplane
+
+	// epoch identifies this hub's sequence numbering, which restarts with the process
+	epoch string
+
+	// seq is the sequence number of the last delivered message
+	seq uint64
+
+	// history keeps each event's recent broadcasts so reconnecting clients can resume
+	history map[uint]*replayBuffer
+
+	// prunedSeq is the newest sequence number of any history dropped by pruneHistory
+	prunedSeq uint64
+
+	// limits bound message rates and connections per user
+	limits Limits
+
+	// availability coalesces availability updates per event; nil until a refresher is set
+	availability *coalescer
+
+	// dropped counts messages per type that didn't fit in a client's send buffer
+	dropped      map[MessageType]uint64
+	droppedMutex sync.Mutex
+
+	// quit is closed to stop Run; done is closed once Run has closed every client
+	quit     chan struct{}
+	done     chan struct{}
+	quitOnce sync.Once
+
+	// closing holds the WebSocket clients closed at shutdown, whose writes Shutdown waits for
+	closing []*Client
+
+	// salesSource loads an organizer's current sales figures for new dashboard subscribers
+	salesSource func(ctx context.Context, organizerID uint) ([]SalesUpdate, error)
+
 	// mutex for thread-safe access to maps
 	mutex sync.RWMutex
 }
 
-// NewHub creates a new Hub instance
+// NewHub creates a new Hub instance that only serves its own clients
 func NewHub() *Hub {
+	return NewHubWithBackplane(nil)
+}
+
+// NewHubWithBackplane creates a Hub that shares broadcasts with other replicas through backplane
+func NewHubWithBackplane(backplane Backplane) *Hub {
 	return &Hub{
-		eventClients: make(map[uint]map[string]*Client),
-		clients:      make(map[*Client]bool),
-		broadcast:    make(chan *Message, 256),
-		register:     make(chan *Client),
-		unregister:   make(chan *Client),
+		eventClients:  make(map[uint]map[string]*Client),
+		userClients:   make(map[uint]map[string]*Client),
+		filterClients: make(map[string]*Client),
+		clients:       make(map[*Client]bool),
+		broadcast:     make(chan *Message, 256),
+		register:      make(chan registration),
+		unregister:    make(chan *Client),
+		backplane:     backplane,
+		epoch:         uuid.New().String(),
+		history:       make(map[uint]*replayBuffer),
+		limits:        DefaultLimits(),
+		dropped:       make(map[MessageType]uint64),
+		quit:          make(chan struct{}),
+		done:          make(chan struct{}),
 	}
 }
 
-// Run starts the hub's main loop
+// SetLimits replaces the per-user and per-connection limits. Connections that
+// are already open keep their message rate.
+func (h *Hub) SetLimits(limits Limits) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.limits = limits
+}
+
+// currentLimits returns the configured limits
+func (h *Hub) currentLimits() Limits {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return h.limits
+}
+
+// Epoch returns the identifier of this hub's sequence numbering
+func (h *Hub) Epoch() string {
+	return h.epoch
+}
+
+// SetSalesSource sets how the hub loads an organizer's current sales figures
+// when they open the sales dashboard
+func (h *Hub) SetSalesSource(source func(ctx context.Context, organizerID uint) ([]SalesUpdate, error)) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.salesSource = source
+}
+
+// Run starts the hub's main loop. It returns once Shutdown has closed every client.
 func (h *Hub) Run() {
-	log.Println("WebSocket Hub started")
+	slog.Info("WebSocket hub started")
+
+	if h.backplane != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go func() {
+			err := h.backplane.Subscribe(ctx, func(message *Message) {
+				select {
+				case h.broadcast <- message:
+				case <-h.quit:
+				}
+			})
+			if err != nil && ctx.Err() == nil {
+				slog.Error("Backplane subscription ended", "error", err)
+			}
+		}()
+	}
+
+	prune := time.NewTicker(historyPruneInterval)
+	defer prune.Stop()
+
 	for {
 		select {
-		case client := <-h.register:
-			h.registerClient(client)
+		case now := <-prune.C:
+			h.pruneHistory(now)
+
+		case r := <-h.register:
+			r.admitted <- h.registerClient(r.client)
 
 		case client := <-h.unregister:
 			h.unregisterClient(client)
 
 		case message := <-h.broadcast:
 			h.broadcastMessage(message)
+
+		case <-h.quit:
+			// Deliver what is already queued before saying goodbye
+			for len(h.broadcast) > 0 {
+				h.broadcastMessage(<-h.broadcast)
+			}
+			h.closeClients()
+			close(h.done)
+			slog.Info("WebSocket hub stopped")
+			return
 		}
 	}
 }
 
-// registerClient adds a client to the hub
-func (h *Hub) registerClient(client *Client) {
+// Shutdown stops the hub: pending availability updates are dropped, every
+// client is told the server is going away and closed with a going-away close
+// frame. It waits until Run has returned and the close frames are written, or
+// until ctx is done. Clients connecting after Shutdown are turned away.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mutex.RLock()
+	availability := h.availability
+	h.mutex.RUnlock()
+
+	if availability != nil {
+		if err := waitContext(ctx, availability.stop); err != nil {
+			return err
+		}
+	}
+
+	h.quitOnce.Do(func() { close(h.quit) })
+
+	select {
+	case <-h.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return waitContext(ctx, func() {
+		for _, client := range h.closing {
+			<-client.written
+		}
+	})
+}
+
+// waitContext runs wait and returns when it does or when ctx is done, whichever comes first
+func waitContext(ctx context.Context, wait func()) error {
+	finished := make(chan struct{})
+	go func() {
+		wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// closeClients sends the shutdown notice to every client and closes them
+func (h *Hub) closeClients() {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 
+	notice := &Message{
+		Type:      MessageTypeServerShutdown,
+		Timestamp: time.Now(),
+		Data: ServerShutdown{
+			Message: "Server is shutting down, please reconnect",
+		},
+	}
+	data, err := json.Marshal(notice)
+	if err != nil {
+		slog.Error("Failed to marshal shutdown message", "error", err)
+	}
+
+	for client := range h.clients {
+		if data != nil {
+			client.trySend(data, notice.Type)
+		}
+
+		// writePump sends the close frame after the queued messages. The read
+		// pump may still be running; its replies are dropped from here on.
+		client.closeSend(websocket.FormatCloseMessage(websocket.CloseGoingAway, "Server shutting down"))
+
+		if client.conn != nil {
+			h.closing = append(h.closing, client)
+		}
+	}
+
+	slog.Info("Closed clients for shutdown", "clients", len(h.clients))
+
+	h.clients = make(map[*Client]bool)
+	h.userClients = make(map[uint]map[string]*Client)
+	h.eventClients = make(map[uint]map[string]*Client)
+	h.filterClients = make(map[string]*Client)
+}
+
+// add hands a new client to Run and waits for it to be admitted, so a rejected
+// client never gets its pumps started. It returns errTooManyConnections when the
+// client's user is at the connection limit and errHubStopped once the hub is
+// shutting down.
+func (h *Hub) add(client *Client) error {
+	admitted := make(chan error, 1)
+	select {
+	case h.register <- registration{client: client, admitted: admitted}:
+		return <-admitted
+	case <-h.quit:
+		return errHubStopped
+	}
+}
+
+// registerClient adds a client to the hub, turning it away if its user already
+// has as many connections as allowed
+func (h *Hub) registerClient(client *Client) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if client.conn != nil {
+		if connections := h.countConnections(client.userID); connections >= h.limits.MaxConnectionsPerUser {
+			client.logger().Warn("Client rejected, too many connections", "connections", connections)
+			return errTooManyConnections
+		}
+	}
+
 	h.clients[client] = true
-	log.Printf("Client %s connected. Total clients: %d", client.ID, len(h.clients))
+
+	// Index the connection under its user
+	if _, exists := h.userClients[client.userID]; !exists {
+		h.userClients[client.userID] = make(map[string]*Client)
+	}
+	h.userClients[client.userID][client.ID] = client
+
+	client.logger().Info("Client connected", "total_clients", len(h.clients))
 
 	// Send connection acknowledgment
 	ack := &Message{
@@ -72,17 +342,26 @@ func (h *Hub) registerClient(client *Client) {
 		Data: ConnectionAck{
 			ClientID: client.ID,
 			Message:  "Connected successfully",
+			Epoch:    h.epoch,
 		},
 	}
 
 	data, err := json.Marshal(ack)
 	if err == nil {
-		select {
-		case client.send <- data:
-		default:
-			log.Printf("Failed to send connection ack to client %s", client.ID)
+		client.trySend(data, ack.Type)
+	}
+	return nil
+}
+
+// countConnections counts a user's WebSocket connections; the caller holds the hub lock
+func (h *Hub) countConnections(userID uint) int {
+	count := 0
+	for _, client := range h.userClients[userID] {
+		if client.conn != nil {
+			count++
 		}
 	}
+	return count
 }
 
 // unregisterClient removes a client from the hub
@@ -94,6 +373,14 @@ func (h *Hub) unregisterClient(client *Client) {
 		// Remove from global clients map
 		delete(h.clients, client)
 
+		// Remove from the user index
+		if userClients, exists := h.userClients[client.userID]; exists {
+			delete(userClients, client.ID)
+			if len(userClients) == 0 {
+				delete(h.userClients, client.userID)
+			}
+		}
+
 		// Remove from all event subscriptions
 		for eventID := range client.eventIDs {
 			if eventClients, exists := h.eventClients[eventID]; exists {
@@ -105,48 +392,232 @@ func (h *Hub) unregisterClient(client *Client) {
 				}
 			}
 		}
+		delete(h.filterClients, client.ID)
 
 		// Close the client's send channel
-		close(client.send)
+		client.closeSend(nil)
 
-		log.Printf("Client %s disconnected. Total clients: %d", client.ID, len(h.clients))
+		client.logger().Info("Client disconnected", "total_clients", len(h.clients))
 	}
 }
 
-// broadcastMessage sends a message to all relevant subscribers
+// NewStreamClient registers a client that isn't backed by a WebSocket connection,
+// such as a Server-Sent Events stream. The caller reads from Messages and must
+// call Unregister when the stream ends.
+func (h *Hub) NewStreamClient(id string, userID uint) *Client {
+	client := NewClient(context.Background(), id, h, nil, userID)
+	if err := h.add(client); err != nil {
+		// The hub is shutting down, so the stream ends right away
+		client.closeSend(nil)
+	}
+	return client
+}
+
+// Unregister removes a client from the hub and closes its message channel
+func (h *Hub) Unregister(client *Client) {
+	select {
+	case h.unregister <- client:
+	case <-h.done:
+		// Shutdown already closed every client
+	}
+}
+
+// broadcastMessage numbers a message and sends it to all relevant subscribers.
+// Its span continues the trace the message was published in.
 func (h *Hub) broadcastMessage(message *Message) {
-	h.mutex.RLock()
-	defer h.mutex.RUnlock()
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.seq++
+	message.Seq = h.seq
+
+	_, span := tracer.Start(trace.ContextWithSpanContext(context.Background(), message.spanContext), "hub.broadcast",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("message.type", string(message.Type)),
+			attribute.Int64("message.seq", int64(message.Seq)),
+		),
+	)
+	defer span.End()
 
 	data, err := json.Marshal(message)
 	if err != nil {
-		log.Printf("Error marshaling message: %v", err)
+		slog.Error("Failed to marshal message", "type", message.Type, "error", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to marshal message")
 		return
 	}
 
-	// If message has an event ID, broadcast only to subscribers of that event
-	if message.EventID != nil {
+	// Keep event broadcasts for replay; user messages are private and never replayed
+	if message.UserID == nil && message.EventID != nil {
+		buffer, exists := h.history[*message.EventID]
+		if !exists {
+			buffer = newReplayBuffer(replayBufferSize)
+			h.history[*message.EventID] = buffer
+		}
+		buffer.add(message.Seq, data)
+	}
+
+	// If message targets a user, deliver only to that user's connections
+	if message.UserID != nil {
+		userID := *message.UserID
+		sent := 0
+		for _, client := range h.userClients[userID] {
+			// Sales figures only go to connections showing the dashboard
+			if message.Type == MessageTypeSalesUpdate && !client.watchingSales() {
+				continue
+			}
+			if client.trySend(data, message.Type) {
+				sent++
+			}
+		}
+		span.SetAttributes(attribute.Int64("user.id", int64(userID)), attribute.Int("message.recipients", sent))
+		slog.Info("Sent message to user", "type", message.Type, "seq", message.Seq, "user_id", userID, "connections", sent)
+	} else if message.EventID != nil {
+		// If message has an event ID, broadcast only to subscribers of that event
+		// and to clients whose filters match it
 		eventID := *message.EventID
-		if eventClients, exists := h.eventClients[eventID]; exists {
-			for _, client := range eventClients {
-				select {
-				case client.send <- data:
-				default:
-					log.Printf("Failed to send message to client %s (buffer full)", client.ID)
-				}
+		eventClients := h.eventClients[eventID]
+		for _, client := range eventClients {
+			client.trySend(data, message.Type)
+		}
+
+		matched := 0
+		for clientID, client := range h.filterClients {
+			if _, subscribed := eventClients[clientID]; subscribed || !client.matchesFilter(message.Event) {
+				continue
 			}
-			log.Printf("Broadcast message to %d clients subscribed to event %d", len(eventClients), eventID)
+			matched++
+			client.trySend(data, message.Type)
+		}
+
+		span.SetAttributes(attribute.Int64("event.id", int64(eventID)), attribute.Int("message.recipients", len(eventClients)+matched))
+		if len(eventClients) > 0 || matched > 0 {
+			slog.Info("Broadcast message to event", "type", message.Type, "seq", message.Seq, "event_id", eventID, "subscribers", len(eventClients), "filter_matches", matched)
 		}
 	} else {
 		// Broadcast to all clients
 		for client := range h.clients {
-			select {
-			case client.send <- data:
-			default:
-				log.Printf("Failed to send message to client %s (buffer full)", client.ID)
-			}
+			client.trySend(data, message.Type)
+		}
+		span.SetAttributes(attribute.Int("message.recipients", len(h.clients)))
+		slog.Info("Broadcast message to all clients", "type", message.Type, "seq", message.Seq, "clients", len(h.clients))
+	}
+}
+
+// recordDrop counts a message that didn't fit in a client's send buffer
+func (h *Hub) recordDrop(msgType MessageType) {
+	h.droppedMutex.Lock()
+	defer h.droppedMutex.Unlock()
+
+	h.dropped[msgType]++
+}
+
+// HubStats is a snapshot of the hub's connections and delivery counters
+type HubStats struct {
+	Clients          int                    `json:"clients"`
+	SubscribedEvents int                    `json:"subscribed_events"`
+	EventSubscribers map[uint]int           `json:"event_subscribers"` // clients subscribed to each event by ID
+	DroppedMessages  map[MessageType]uint64 `json:"dropped_messages"`
+	CoalescedUpdates uint64                 `json:"coalesced_updates"`
+}
+
+// Stats returns the hub's current connection counts and delivery counters
+func (h *Hub) Stats() HubStats {
+	h.mutex.RLock()
+	stats := HubStats{
+		Clients:          len(h.clients),
+		SubscribedEvents: len(h.eventClients),
+		EventSubscribers: make(map[uint]int, len(h.eventClients)),
+		DroppedMessages:  make(map[MessageType]uint64),
+	}
+	for eventID, clients := range h.eventClients {
+		stats.EventSubscribers[eventID] = len(clients)
+	}
+	if h.availability != nil {
+		stats.CoalescedUpdates = h.availability.coalesced.Load()
+	}
+	h.mutex.RUnlock()
+
+	h.droppedMutex.Lock()
+	for msgType, count := range h.dropped {
+		stats.DroppedMessages[msgType] = count
+	}
+	h.droppedMutex.Unlock()
+
+	return stats
+}
+
+// SetAvailabilityRefresher sets how the hub recomputes and broadcasts an event's
+// availability. Requests made with RequestAvailabilityUpdate within window of
+// each other are coalesced into a single refresh. Each refresh gets a context
+// with a trace of its own, since it serves many requests.
+func (h *Hub) SetAvailabilityRefresher(window time.Duration, refresh func(ctx context.Context, eventID uint)) {
+	if window <= 0 {
+		window = defaultCoalesceWindow
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.availability = newCoalescer(window, func(eventID uint) {
+		ctx, span := tracer.Start(context.Background(), "hub.refresh_availability",
+			trace.WithAttributes(attribute.Int64("event.id", int64(eventID))),
+		)
+		defer span.End()
+
+		refresh(ctx, eventID)
+	})
+}
+
+// RequestAvailabilityUpdate asks for an event's availability to be broadcast.
+// Under heavy booking load many requests collapse into one refresh per window.
+func (h *Hub) RequestAvailabilityUpdate(ctx context.Context, eventID uint) {
+	ctx, span := tracer.Start(ctx, "hub.request_availability_update",
+		trace.WithAttributes(attribute.Int64("event.id", int64(eventID))),
+	)
+	defer span.End()
+
+	h.mutex.RLock()
+	availability := h.availability
+	h.mutex.RUnlock()
+
+	if availability == nil {
+		slog.WarnContext(ctx, "No availability refresher set, dropping update", "event_id", eventID)
+		return
+	}
+	availability.request(eventID)
+}
+
+// publish hands a message to the backplane so every replica delivers it, or
+// queues it for local delivery when there is no backplane. The message carries
+// the publishing span, so its broadcast joins the caller's trace.
+func (h *Hub) publish(ctx context.Context, message *Message) {
+	ctx, span := tracer.Start(ctx, "hub.publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("message.type", string(message.Type))),
+	)
+	defer span.End()
+	message.spanContext = span.SpanContext()
+
+	if h.backplane != nil {
+		// The request that published the message may finish before the backplane does
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+
+		err := h.backplane.Publish(ctx, message)
+		if err == nil {
+			return
 		}
-		log.Printf("Broadcast message to all %d clients", len(h.clients))
+		// Local clients still get the message even if other replicas miss it
+		slog.ErrorContext(ctx, "Backplane publish failed, delivering locally only", "type", message.Type, "error", err)
+		span.RecordError(err)
+	}
+
+	select {
+	case h.broadcast <- message:
+	case <-h.done:
+		slog.WarnContext(ctx, "Hub stopped, dropping message", "type", message.Type)
 	}
 }
 
@@ -155,6 +626,55 @@ func (h *Hub) SubscribeToEvent(client *Client, eventID uint) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 
+	h.subscribeLocked(client, eventID)
+}
+
+// SubscribeToEvents subscribes a client to several events at once. Nothing is
+// subscribed if that would take the client past its subscription limit.
+func (h *Hub) SubscribeToEvents(client *Client, eventIDs []uint) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if exceedsEventLimit(client, eventIDs) {
+		return errEventSubscriptionLimit
+	}
+
+	for _, eventID := range eventIDs {
+		h.subscribeLocked(client, eventID)
+	}
+	return nil
+}
+
+// exceedsEventLimit reports whether subscribing to eventIDs would take a
+// client over maxEventSubscriptions
+func exceedsEventLimit(client *Client, eventIDs []uint) bool {
+	client.mutex.RLock()
+	defer client.mutex.RUnlock()
+
+	total := len(client.eventIDs)
+	for _, eventID := range uniqueEventIDs(eventIDs) {
+		if !client.eventIDs[eventID] {
+			total++
+		}
+	}
+	return total > maxEventSubscriptions
+}
+
+// uniqueEventIDs drops repeated IDs from a bulk (un)subscription
+func uniqueEventIDs(eventIDs []uint) []uint {
+	seen := make(map[uint]bool, len(eventIDs))
+	unique := eventIDs[:0:0]
+	for _, eventID := range eventIDs {
+		if !seen[eventID] {
+			seen[eventID] = true
+			unique = append(unique, eventID)
+		}
+	}
+	return unique
+}
+
+// subscribeLocked adds a client to an event's subscribers; the caller holds the hub lock
+func (h *Hub) subscribeLocked(client *Client, eventID uint) {
 	// Initialize the event's client map if it doesn't exist
 	if _, exists := h.eventClients[eventID]; !exists {
 		h.eventClients[eventID] = make(map[string]*Client)
@@ -168,7 +688,61 @@ func (h *Hub) SubscribeToEvent(client *Client, eventID uint) {
 	client.eventIDs[eventID] = true
 	client.mutex.Unlock()
 
-	log.Printf("Client %s subscribed to event %d", client.ID, eventID)
+	client.logger().Info("Client subscribed to event", "event_id", eventID)
+}
+
+// Resume replays the broadcasts a reconnecting client missed after lastSeq and
+// subscribes it to the event, with no broadcast slipping in between the replay
+// and live delivery. It returns false, leaving the client unsubscribed, when
+// that isn't possible - the sequence belongs to another epoch, the messages
+// were already evicted, the subscription would exceed the client's limit or
+// the client was closed by shutdown - and the client has to fetch the event's
+// state again.
+func (h *Hub) Resume(client *Client, eventID uint, epoch string, lastSeq uint64) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if epoch != h.epoch || lastSeq > h.seq || exceedsEventLimit(client, []uint{eventID}) {
+		return false
+	}
+
+	// Without history nothing has been broadcast for the event in this epoch,
+	// unless its history was pruned after the client last heard from it
+	var missed [][]byte
+	if buffer, exists := h.history[eventID]; exists {
+		var ok bool
+		if missed, ok = buffer.since(lastSeq); !ok {
+			return false
+		}
+	} else if lastSeq < h.prunedSeq {
+		return false
+	}
+
+	// A client closed by shutdown can't take the replay
+	if !client.trySendAll(missed) {
+		return false
+	}
+
+	h.subscribeLocked(client, eventID)
+
+	client.logger().Info("Client resumed event", "event_id", eventID, "replayed", len(missed))
+	return true
+}
+
+// pruneHistory drops the broadcasts of events that have had no subscribers for
+// longer than replayRetention, so history doesn't grow with every event ever
+// broadcast. Clients resuming such an event from before the drop must resync.
+func (h *Hub) pruneHistory(now time.Time) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for eventID, buffer := range h.history {
+		if len(h.eventClients[eventID]) > 0 || now.Sub(buffer.updated) < replayRetention {
+			continue
+		}
+		delete(h.history, eventID)
+		h.prunedSeq = max(h.prunedSeq, buffer.lastSeq)
+	}
 }
 
 // UnsubscribeFromEvent removes a client from the subscribers list for a specific event
@@ -190,22 +764,181 @@ func (h *Hub) UnsubscribeFromEvent(client *Client, eventID uint) {
 	delete(client.eventIDs, eventID)
 	client.mutex.Unlock()
 
-	log.Printf("Client %s unsubscribed from event %d", client.ID, eventID)
+	client.logger().Info("Client unsubscribed from event", "event_id", eventID)
+}
+
+// SubscribeToFilter adds a filter subscription, so the client receives
+// broadcasts for every event matching it
+func (h *Hub) SubscribeToFilter(client *Client, filter SubscriptionFilter) error {
+	filter, err := filter.normalize()
+	if err != nil {
+		return err
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	client.mutex.Lock()
+	if !client.filters[filter] && len(client.filters) >= maxFilterSubscriptions {
+		client.mutex.Unlock()
+		return errFilterSubscriptionLimit
+	}
+	client.filters[filter] = true
+	client.mutex.Unlock()
+
+	h.filterClients[client.ID] = client
+
+	client.logger().Info("Client subscribed to filter", "filter", filter)
+	return nil
+}
+
+// UnsubscribeFromFilter removes a filter subscription
+func (h *Hub) UnsubscribeFromFilter(client *Client, filter SubscriptionFilter) {
+	filter, err := filter.normalize()
+	if err != nil {
+		return
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	client.mutex.Lock()
+	delete(client.filters, filter)
+	remaining := len(client.filters)
+	client.mutex.Unlock()
+
+	if remaining == 0 {
+		delete(h.filterClients, client.ID)
+	}
+
+	client.logger().Info("Client unsubscribed from filter", "filter", filter)
 }
 
 // BroadcastAvailabilityUpdate broadcasts a ticket availability update to all subscribers
-func (h *Hub) BroadcastAvailabilityUpdate(eventID uint, availableTickets int, capacity int) {
+func (h *Hub) BroadcastAvailabilityUpdate(ctx context.Context, event EventInfo, availableTickets int, capacity int, tiers []TierAvailability) {
 	message := &Message{
 		Type:      MessageTypeAvailabilityUpdate,
-		EventID:   &eventID,
+		EventID:   &event.ID,
 		Timestamp: time.Now(),
+		Event:     &event,
 		Data: AvailabilityUpdate{
-			EventID:          eventID,
+			EventID:          event.ID,
 			AvailableTickets: availableTickets,
 			Capacity:         capacity,
+			Tiers:            tiers,
 			LastUpdated:      time.Now().Format(time.RFC3339),
 		},
 	}
 
-	h.broadcast <- message
+	h.publish(ctx, message)
+}
+
+// BroadcastSeatUpdate broadcasts a reserved seat state change to all subscribers of the event
+func (h *Hub) BroadcastSeatUpdate(ctx context.Context, event EventInfo, seatIDs []uint, status string) {
+	message := &Message{
+		Type:      MessageTypeSeatUpdate,
+		EventID:   &event.ID,
+		Timestamp: time.Now(),
+		Event:     &event,
+		Data: SeatUpdate{
+			EventID:     event.ID,
+			SeatIDs:     seatIDs,
+			Status:      status,
+			LastUpdated: time.Now().Format(time.RFC3339),
+		},
+	}
+
+	h.publish(ctx, message)
+}
+
+// SubscribeToSales starts streaming sales figures for the events the client's
+// user organizes and returns their current figures. Updates are only ever sent
+// to an event's organizer, so a client never sees another organizer's sales.
+func (h *Hub) SubscribeToSales(client *Client) ([]SalesUpdate, error) {
+	client.mutex.Lock()
+	client.salesDashboard = true
+	client.mutex.Unlock()
+
+	client.logger().Info("Client subscribed to the sales dashboard")
+
+	h.mutex.RLock()
+	source := h.salesSource
+	h.mutex.RUnlock()
+
+	if source == nil {
+		return nil, nil
+	}
+	return source(client.ctx, client.userID)
+}
+
+// UnsubscribeFromSales stops streaming sales figures to the client
+func (h *Hub) UnsubscribeFromSales(client *Client) {
+	client.mutex.Lock()
+	client.salesDashboard = false
+	client.mutex.Unlock()
+}
+
+// SendSalesUpdate delivers an event's sales figures to its organizer's dashboards, on any replica
+func (h *Hub) SendSalesUpdate(ctx context.Context, organizerID uint, update SalesUpdate) {
+	h.SendToUser(ctx, organizerID, &Message{
+		Type:    MessageTypeSalesUpdate,
+		EventID: &update.EventID,
+		Data:    update,
+	})
+}
+
+// RefreshSales sends fresh figures to every sales dashboard open on this hub,
+// so rates like bookings per minute fall back when nothing happens. Every
+// replica refreshes its own dashboards, so the figures aren't published
+// through the backplane.
+func (h *Hub) RefreshSales(ctx context.Context) {
+	h.mutex.RLock()
+	source := h.salesSource
+	var organizerIDs []uint
+	for userID, clients := range h.userClients {
+		for _, client := range clients {
+			if client.watchingSales() {
+				organizerIDs = append(organizerIDs, userID)
+				break
+			}
+		}
+	}
+	h.mutex.RUnlock()
+
+	if source == nil {
+		return
+	}
+
+	for _, organizerID := range organizerIDs {
+		updates, err := source(ctx, organizerID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to refresh sales", "organizer_id", organizerID, "error", err)
+			continue
+		}
+
+		for _, update := range updates {
+			message := &Message{
+				Type:      MessageTypeSalesUpdate,
+				EventID:   &update.EventID,
+				UserID:    &organizerID,
+				Timestamp: time.Now(),
+				Data:      update,
+			}
+			select {
+			case h.broadcast <- message:
+			case <-h.done:
+				return
+			}
+		}
+	}
+}
+
+// SendToUser delivers a message to every connection belonging to a user, on any replica
+func (h *Hub) SendToUser(ctx context.Context, userID uint, message *Message) {
+	message.UserID = &userID
+	if message.Timestamp.IsZero() {
+		message.Timestamp = time.Now()
+	}
+
+	h.publish(ctx, message)
 }
@@ -0,0 +1,513 @@
+package websocket
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alexs/golang_test/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSendToUser_AllConnections tests that a user message reaches every connection of that user only
+func TestSendToUser_AllConnections(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	laptop := newTestClient(t, hub, "laptop", 1)
+	phone := newTestClient(t, hub, "phone", 1)
+	stranger := newTestClient(t, hub, "stranger", 2)
+
+	eventID := uint(4)
+	hub.SendToUser(context.Background(), 1, &Message{
+		Type:    MessageTypeBookingConfirmed,
+		EventID: &eventID,
+		Data:    BookingNotification{BookingID: 12, EventID: 4, Quantity: 2, Status: "confirmed"},
+	})
+
+	for _, client := range []*Client{laptop, phone} {
+		msg := receive(t, client)
+		assert.Equal(t, MessageTypeBookingConfirmed, msg.Type)
+		assert.False(t, msg.Timestamp.IsZero(), "Timestamp should be filled in")
+
+		data := msg.Data.(map[string]interface{})
+		assert.Equal(t, float64(12), data["booking_id"])
+		assert.Equal(t, "confirmed", data["status"])
+	}
+	assertNoMessage(t, stranger)
+}
+
+// TestSendToUser_IgnoresEventSubscriptions tests that user messages skip other subscribers of the same event
+func TestSendToUser_IgnoresEventSubscriptions(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	holder := newTestClient(t, hub, "holder", 1)
+	watcher := newTestClient(t, hub, "watcher", 2)
+	hub.SubscribeToEvent(watcher, 9)
+
+	eventID := uint(9)
+	hub.SendToUser(context.Background(), 1, &Message{
+		Type:    MessageTypeEventCancelled,
+		EventID: &eventID,
+		Data:    EventCancelled{EventID: 9, EventName: "Show"},
+	})
+
+	msg := receive(t, holder)
+	assert.Equal(t, MessageTypeEventCancelled, msg.Type)
+	assertNoMessage(t, watcher)
+}
+
+// TestUserIndex_Unregister tests that disconnecting removes the connection from the user index
+func TestUserIndex_Unregister(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	first := newTestClient(t, hub, "first", 3)
+	second := newTestClient(t, hub, "second", 3)
+
+	hub.unregister <- first
+	require.Eventually(t, func() bool {
+		hub.mutex.RLock()
+		defer hub.mutex.RUnlock()
+		return len(hub.userClients[3]) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	hub.unregister <- second
+	require.Eventually(t, func() bool {
+		hub.mutex.RLock()
+		defer hub.mutex.RUnlock()
+		_, exists := hub.userClients[3]
+		return !exists
+	}, time.Second, 5*time.Millisecond)
+}
+
+// TestStreamClient_ReceivesEventUpdates tests connectionless clients used by Server-Sent Events
+func TestStreamClient_ReceivesEventUpdates(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	client := hub.NewStreamClient("stream", 0)
+	select {
+	case <-client.Messages(): // connection ack
+	case <-time.After(time.Second):
+		t.Fatal("stream client received no connection ack")
+	}
+
+	hub.SubscribeToEvent(client, 5)
+	hub.BroadcastAvailabilityUpdate(context.Background(), EventInfo{ID: 5}, 3, 10, nil)
+
+	msg := receive(t, client)
+	assert.Equal(t, MessageTypeAvailabilityUpdate, msg.Type)
+	assert.Equal(t, uint(5), *msg.EventID)
+
+	hub.Unregister(client)
+	select {
+	case _, ok := <-client.Messages():
+		assert.False(t, ok, "Messages should be closed after Unregister")
+	case <-time.After(time.Second):
+		t.Fatal("Messages was not closed after Unregister")
+	}
+
+	hub.mutex.RLock()
+	defer hub.mutex.RUnlock()
+	assert.Empty(t, hub.eventClients[5])
+}
+
+// TestResume_ReplaysMissedBroadcasts tests that a reconnecting client receives what it missed
+func TestResume_ReplaysMissedBroadcasts(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	first := newTestClient(t, hub, "first", 1)
+	hub.SubscribeToEvent(first, 3)
+	hub.BroadcastAvailabilityUpdate(context.Background(), EventInfo{ID: 3}, 9, 10, nil)
+	lastSeen := receive(t, first)
+	require.NotZero(t, lastSeen.Seq)
+
+	// The connection drops, then other events and this one keep changing
+	hub.Unregister(first)
+	hub.BroadcastAvailabilityUpdate(context.Background(), EventInfo{ID: 4}, 1, 10, nil)
+	hub.BroadcastAvailabilityUpdate(context.Background(), EventInfo{ID: 3}, 8, 10, nil)
+	hub.BroadcastSeatUpdate(context.Background(), EventInfo{ID: 3}, []uint{1}, "booked")
+
+	second := newTestClient(t, hub, "second", 1)
+	require.Eventually(t, func() bool {
+		hub.mutex.RLock()
+		defer hub.mutex.RUnlock()
+		return hub.seq == lastSeen.Seq+3
+	}, time.Second, 5*time.Millisecond)
+
+	assert.True(t, hub.Resume(second, 3, hub.Epoch(), lastSeen.Seq))
+
+	availability := receive(t, second)
+	assert.Equal(t, MessageTypeAvailabilityUpdate, availability.Type)
+	assert.Equal(t, float64(8), availability.Data.(map[string]interface{})["available_tickets"])
+	seat := receive(t, second)
+	assert.Equal(t, MessageTypeSeatUpdate, seat.Type)
+	assert.Greater(t, seat.Seq, availability.Seq)
+	assertNoMessage(t, second)
+
+	// Resuming also subscribes to live updates
+	hub.BroadcastAvailabilityUpdate(context.Background(), EventInfo{ID: 3}, 7, 10, nil)
+	live := receive(t, second)
+	assert.Equal(t, float64(7), live.Data.(map[string]interface{})["available_tickets"])
+}
+
+// TestResume_RequiresResync tests the cases where missed messages can't be replayed
+func TestResume_RequiresResync(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	watcher := newTestClient(t, hub, "watcher", 2)
+	hub.SubscribeToEvent(watcher, 6)
+	for i := 0; i < replayBufferSize+1; i++ {
+		hub.BroadcastAvailabilityUpdate(context.Background(), EventInfo{ID: 6}, i, 1000, nil)
+		receive(t, watcher)
+	}
+
+	tests := []struct {
+		name    string
+		epoch   string
+		lastSeq uint64
+	}{
+		{name: "Other Epoch", epoch: "previous-process", lastSeq: 50},
+		{name: "Sequence From The Future", epoch: hub.Epoch(), lastSeq: 10_000},
+		{name: "Evicted Messages", epoch: hub.Epoch(), lastSeq: 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, hub, "client-"+tc.name, 2)
+			assert.False(t, hub.Resume(client, 6, tc.epoch, tc.lastSeq))
+			assertNoMessage(t, client)
+
+			client.mutex.RLock()
+			assert.False(t, client.eventIDs[6], "Resuming should only subscribe after a replay")
+			client.mutex.RUnlock()
+		})
+	}
+}
+
+// TestPruneHistory tests that abandoned events lose their history and resuming them requires a resync
+func TestPruneHistory(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	watcher := newTestClient(t, hub, "watcher", 1)
+	hub.SubscribeToEvent(watcher, 5)
+	hub.BroadcastAvailabilityUpdate(context.Background(), EventInfo{ID: 5}, 9, 10, nil)
+	hub.BroadcastAvailabilityUpdate(context.Background(), EventInfo{ID: 6}, 9, 10, nil)
+	receive(t, watcher)
+
+	historyLen := func() int {
+		hub.mutex.RLock()
+		defer hub.mutex.RUnlock()
+		return len(hub.history)
+	}
+	require.Eventually(t, func() bool { return historyLen() == 2 }, time.Second, 5*time.Millisecond)
+
+	hub.pruneHistory(time.Now())
+	assert.Equal(t, 2, historyLen(), "Recent history is kept")
+
+	hub.pruneHistory(time.Now().Add(replayRetention))
+	hub.mutex.RLock()
+	_, kept := hub.history[5]
+	_, pruned := hub.history[6]
+	seq := hub.seq
+	hub.mutex.RUnlock()
+	assert.True(t, kept, "Events with subscribers keep their history")
+	assert.False(t, pruned, "Events without subscribers lose their history")
+
+	client := newTestClient(t, hub, "resumer", 2)
+	assert.False(t, hub.Resume(client, 6, hub.Epoch(), 0), "Pruned broadcasts can't be replayed")
+	assert.True(t, hub.Resume(client, 6, hub.Epoch(), seq), "Clients that missed nothing resume")
+}
+
+// TestHandleMessage_Resume tests the client resume message and the resync reply
+func TestHandleMessage_Resume(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	client := newTestClient(t, hub, "resumer", 1)
+	eventID := uint(8)
+	lastSeq := uint64(3)
+
+	client.handleMessage(&ClientMessage{Type: MessageTypeResume, EventID: &eventID})
+	msg := receive(t, client)
+	assert.Equal(t, MessageTypeError, msg.Type)
+
+	client.handleMessage(&ClientMessage{Type: MessageTypeResume, EventID: &eventID, Epoch: "stale", LastSeq: &lastSeq})
+	msg = receive(t, client)
+	assert.Equal(t, MessageTypeResyncRequired, msg.Type)
+	assert.Equal(t, float64(8), msg.Data.(map[string]interface{})["event_id"])
+
+	// Clients asked to resync still get live updates
+	hub.BroadcastAvailabilityUpdate(context.Background(), EventInfo{ID: eventID}, 4, 10, nil)
+	assert.Equal(t, MessageTypeAvailabilityUpdate, receive(t, client).Type)
+
+	// A replayed client gets the broadcast once and stays subscribed
+	resumer := newTestClient(t, hub, "replayed", 2)
+	fromStart := uint64(0)
+	resumer.handleMessage(&ClientMessage{Type: MessageTypeResume, EventID: &eventID, Epoch: hub.Epoch(), LastSeq: &fromStart})
+	assert.Equal(t, MessageTypeAvailabilityUpdate, receive(t, resumer).Type)
+	assertNoMessage(t, resumer)
+
+	resumer.mutex.RLock()
+	assert.True(t, resumer.eventIDs[eventID])
+	resumer.mutex.RUnlock()
+}
+
+// TestFilterSubscriptions tests that filter subscribers get matching broadcasts exactly once
+func TestFilterSubscriptions(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	copenhagen := newTestClient(t, hub, "copenhagen", 1)
+	concerts := newTestClient(t, hub, "concerts", 2)
+	require.NoError(t, hub.SubscribeToFilter(copenhagen, SubscriptionFilter{City: "Copenhagen"}))
+	require.NoError(t, hub.SubscribeToFilter(concerts, SubscriptionFilter{EventType: "concert"}))
+
+	// Subscribed directly as well as through the filter
+	hub.SubscribeToEvent(concerts, 1)
+
+	hub.BroadcastAvailabilityUpdate(context.Background(), EventInfo{ID: 1, City: "Copenhagen", EventType: "concert"}, 5, 10, nil)
+	assert.Equal(t, uint(1), *receive(t, copenhagen).EventID)
+	assert.Equal(t, uint(1), *receive(t, concerts).EventID)
+	assertNoMessage(t, concerts)
+
+	hub.BroadcastAvailabilityUpdate(context.Background(), EventInfo{ID: 2, City: "Aarhus", EventType: "standup"}, 5, 10, nil)
+	assertNoMessage(t, copenhagen)
+	assertNoMessage(t, concerts)
+
+	hub.UnsubscribeFromFilter(copenhagen, SubscriptionFilter{City: "copenhagen"})
+	hub.BroadcastAvailabilityUpdate(context.Background(), EventInfo{ID: 3, City: "Copenhagen"}, 5, 10, nil)
+	assertNoMessage(t, copenhagen)
+
+	hub.mutex.RLock()
+	defer hub.mutex.RUnlock()
+	assert.NotContains(t, hub.filterClients, copenhagen.ID)
+}
+
+// TestSubscriptionLimits tests the per-client limits on event and filter subscriptions
+func TestSubscriptionLimits(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	client := newTestClient(t, hub, "greedy", 1)
+
+	eventIDs := make([]uint, maxEventSubscriptions)
+	for i := range eventIDs {
+		eventIDs[i] = uint(i + 1)
+	}
+	require.NoError(t, hub.SubscribeToEvents(client, eventIDs))
+
+	// Already subscribed events don't count twice
+	require.NoError(t, hub.SubscribeToEvents(client, []uint{1, 2}))
+
+	// Going over the limit subscribes nothing
+	err := hub.SubscribeToEvents(client, []uint{1, 1000})
+	assert.ErrorIs(t, err, errEventSubscriptionLimit)
+	client.mutex.RLock()
+	assert.False(t, client.eventIDs[1000])
+	client.mutex.RUnlock()
+
+	// Resuming subscribes too, so it counts against the same limit
+	lastSeq := uint64(0)
+	assert.False(t, hub.Resume(client, 1000, hub.Epoch(), lastSeq))
+	client.handleMessage(&ClientMessage{Type: MessageTypeResume, EventID: &eventIDs[0], Epoch: hub.Epoch(), LastSeq: &lastSeq})
+	assertNoMessage(t, client)
+	eventID := uint(1000)
+	client.handleMessage(&ClientMessage{Type: MessageTypeResume, EventID: &eventID, Epoch: hub.Epoch(), LastSeq: &lastSeq})
+	assert.Equal(t, MessageTypeError, receive(t, client).Type)
+	client.mutex.RLock()
+	assert.False(t, client.eventIDs[1000])
+	client.mutex.RUnlock()
+
+	for i := 0; i < maxFilterSubscriptions; i++ {
+		require.NoError(t, hub.SubscribeToFilter(client, SubscriptionFilter{OrganizerID: uint(i + 1)}))
+	}
+	err = hub.SubscribeToFilter(client, SubscriptionFilter{City: "Odense"})
+	assert.ErrorIs(t, err, errFilterSubscriptionLimit)
+}
+
+// TestHandleMessage_BulkSubscribe tests subscribing to a list of events and a filter in one message
+func TestHandleMessage_BulkSubscribe(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	client := newTestClient(t, hub, "bulk", 1)
+	eventID := uint(1)
+
+	client.handleMessage(&ClientMessage{
+		Type:     MessageTypeSubscribe,
+		EventID:  &eventID,
+		EventIDs: []uint{2, 3},
+		Filter:   &SubscriptionFilter{City: "Copenhagen"},
+	})
+	assertNoMessage(t, client)
+
+	client.mutex.RLock()
+	assert.Len(t, client.eventIDs, 3)
+	assert.Len(t, client.filters, 1)
+	client.mutex.RUnlock()
+
+	client.handleMessage(&ClientMessage{Type: MessageTypeUnsubscribe, EventIDs: []uint{1, 2}})
+	client.mutex.RLock()
+	assert.Equal(t, map[uint]bool{3: true}, client.eventIDs)
+	client.mutex.RUnlock()
+
+	client.handleMessage(&ClientMessage{Type: MessageTypeSubscribe})
+	assert.Equal(t, MessageTypeError, receive(t, client).Type)
+
+	client.handleMessage(&ClientMessage{Type: MessageTypeSubscribe, Filter: &SubscriptionFilter{}})
+	assert.Equal(t, MessageTypeError, receive(t, client).Type)
+}
+
+// TestSalesDashboard tests that sales figures reach only the organizer's dashboard connections
+func TestSalesDashboard(t *testing.T) {
+	hub := NewHub()
+	hub.SetSalesSource(func(ctx context.Context, organizerID uint) ([]SalesUpdate, error) {
+		return []SalesUpdate{{EventID: 1, EventName: "Owned", TicketsSold: 3}}, nil
+	})
+	go hub.Run()
+
+	dashboard := newTestClient(t, hub, "dashboard", 7)
+	dashboard.role = models.RoleOrganizer
+	otherTab := newTestClient(t, hub, "other-tab", 7)
+	otherOrganizer := newTestClient(t, hub, "other-organizer", 8)
+	otherOrganizer.role = models.RoleOrganizer
+	customer := newTestClient(t, hub, "customer", 9)
+	customer.role = models.RoleUser
+
+	// Customers can't open the dashboard
+	customer.handleMessage(&ClientMessage{Type: MessageTypeSubscribeSales})
+	assert.Equal(t, MessageTypeError, receive(t, customer).Type)
+
+	// Subscribing starts with the current figures
+	dashboard.handleMessage(&ClientMessage{Type: MessageTypeSubscribeSales})
+	snapshot := receive(t, dashboard)
+	assert.Equal(t, MessageTypeSalesUpdate, snapshot.Type)
+	assert.Equal(t, "Owned", snapshot.Data.(map[string]interface{})["event_name"])
+
+	otherOrganizer.handleMessage(&ClientMessage{Type: MessageTypeSubscribeSales})
+	receive(t, otherOrganizer)
+
+	hub.SendSalesUpdate(context.Background(), 7, SalesUpdate{EventID: 1, TicketsSold: 5, Revenue: 250})
+	update := receive(t, dashboard)
+	assert.Equal(t, float64(250), update.Data.(map[string]interface{})["revenue"])
+	assertNoMessage(t, otherTab)
+	assertNoMessage(t, otherOrganizer)
+
+	dashboard.handleMessage(&ClientMessage{Type: MessageTypeUnsubscribeSales})
+	hub.SendSalesUpdate(context.Background(), 7, SalesUpdate{EventID: 1, TicketsSold: 6})
+	assertNoMessage(t, dashboard)
+}
+
+// TestRefreshSales tests that open sales dashboards get fresh figures without any booking activity
+func TestRefreshSales(t *testing.T) {
+	hub := NewHub()
+	var loads atomic.Int32
+	hub.SetSalesSource(func(ctx context.Context, organizerID uint) ([]SalesUpdate, error) {
+		loads.Add(1)
+		return []SalesUpdate{{EventID: organizerID * 10, BookingsPerMinute: 0}}, nil
+	})
+	go hub.Run()
+
+	dashboard := newTestClient(t, hub, "dashboard", 7)
+	dashboard.role = models.RoleOrganizer
+	otherTab := newTestClient(t, hub, "other-tab", 7)
+	idle := newTestClient(t, hub, "idle", 8)
+	idle.role = models.RoleOrganizer
+
+	dashboard.handleMessage(&ClientMessage{Type: MessageTypeSubscribeSales})
+	receive(t, dashboard)
+
+	hub.RefreshSales(context.Background())
+	update := receive(t, dashboard)
+	assert.Equal(t, MessageTypeSalesUpdate, update.Type)
+	assert.Equal(t, float64(70), update.Data.(map[string]interface{})["event_id"])
+	assertNoMessage(t, otherTab)
+	assertNoMessage(t, idle)
+	assert.Equal(t, int32(2), loads.Load(), "Only organizers with an open dashboard are loaded")
+}
+
+// TestNewClient_Context tests that a client keeps its request's values past the request and loses them on close
+func TestNewClient_Context(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	type key struct{}
+	request, cancelRequest := context.WithCancel(context.WithValue(context.Background(), key{}, "req-1"))
+	client := NewClient(request, "client", hub, nil, 1)
+	require.NoError(t, hub.add(client))
+	cancelRequest()
+
+	assert.NoError(t, client.ctx.Err(), "The upgrade request ends long before the connection")
+	assert.Equal(t, "req-1", client.ctx.Value(key{}))
+
+	hub.Unregister(client)
+	select {
+	case <-client.ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Closing the client didn't cancel its context")
+	}
+}
+
+// TestRequestAvailabilityUpdate_Coalesces tests that bursts of availability requests trigger one refresh
+func TestRequestAvailabilityUpdate_Coalesces(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	refreshed := make(chan uint, 10)
+	hub.SetAvailabilityRefresher(20*time.Millisecond, func(ctx context.Context, eventID uint) {
+		refreshed <- eventID
+	})
+
+	for i := 0; i < 50; i++ {
+		hub.RequestAvailabilityUpdate(context.Background(), 9)
+	}
+
+	select {
+	case eventID := <-refreshed:
+		assert.Equal(t, uint(9), eventID)
+	case <-time.After(time.Second):
+		t.Fatal("availability was never refreshed")
+	}
+	select {
+	case <-refreshed:
+		t.Fatal("availability was refreshed more than once")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.Equal(t, uint64(49), hub.Stats().CoalescedUpdates)
+}
+
+// TestStats_CountsDroppedMessages tests that messages to clients with full buffers are counted
+func TestStats_CountsDroppedMessages(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	slow := newTestClient(t, hub, "slow", 1)
+	hub.SubscribeToEvent(slow, 2)
+
+	// Fill the buffer so nothing else fits
+	for len(slow.send) < cap(slow.send) {
+		slow.send <- []byte(`{}`)
+	}
+
+	hub.BroadcastAvailabilityUpdate(context.Background(), EventInfo{ID: 2}, 1, 10, nil)
+	hub.BroadcastSeatUpdate(context.Background(), EventInfo{ID: 2}, []uint{4}, "booked")
+
+	require.Eventually(t, func() bool {
+		stats := hub.Stats()
+		return stats.DroppedMessages[MessageTypeAvailabilityUpdate] == 1 && stats.DroppedMessages[MessageTypeSeatUpdate] == 1
+	}, time.Second, 5*time.Millisecond)
+
+	stats := hub.Stats()
+	assert.Equal(t, 1, stats.Clients)
+	assert.Equal(t, 1, stats.SubscribedEvents)
+	assert.Equal(t, map[uint]int{2: 1}, stats.EventSubscribers)
+}
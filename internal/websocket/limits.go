@@ -0,0 +1,85 @@
+package websocket
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits bounds what a single user and connection may do
+type Limits struct {
+	// MessageRate is the number of inbound messages per second a connection may sustain
+	MessageRate float64
+
+	// MessageBurst is the number of inbound messages a connection may send at once
+	MessageBurst int
+
+	// MaxConnectionsPerUser caps a user's concurrent WebSocket connections; Server-Sent Event streams aren't counted
+	MaxConnectionsPerUser int
+}
+
+// DefaultLimits returns the limits used when none are configured
+func DefaultLimits() Limits {
+	return Limits{
+		MessageRate:           10,
+		MessageBurst:          20,
+		MaxConnectionsPerUser: 5,
+	}
+}
+
+// tokenBucket allows bursts of up to capacity events, refilled at rate per second
+type tokenBucket struct {
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+	mutex    sync.Mutex
+}
+
+// newTokenBucket creates a full bucket
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:     rate,
+		capacity: float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+// allow takes a token if one is available at now
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// originChecker allows handshakes from the given origins, the same list the
+// router's CORS options use. Requests without an Origin header don't come from
+// a browser and are allowed; "*" allows every origin.
+func originChecker(allowedOrigins []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+
+		for _, allowed := range allowedOrigins {
+			if allowed == "*" || strings.EqualFold(allowed, origin) {
+				return true
+			}
+		}
+		return false
+	}
+}
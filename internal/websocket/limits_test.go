@@ -0,0 +1,150 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexs/golang_test/internal/config"
+	"github.com/alexs/golang_test/internal/models"
+	"github.com/alexs/golang_test/internal/utils"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTokenBucket tests bursts and refilling of the inbound message limiter
+func TestTokenBucket(t *testing.T) {
+	start := time.Now()
+	bucket := newTokenBucket(2, 3)
+	bucket.last = start
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, bucket.allow(start), "Burst message %d should be allowed", i+1)
+	}
+	assert.False(t, bucket.allow(start), "Burst is used up")
+
+	// Two tokens per second refill one token every half second
+	assert.True(t, bucket.allow(start.Add(500*time.Millisecond)))
+	assert.False(t, bucket.allow(start.Add(500*time.Millisecond)))
+
+	// Refilling stops at the burst size
+	later := start.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		assert.True(t, bucket.allow(later))
+	}
+	assert.False(t, bucket.allow(later))
+}
+
+// TestOriginChecker tests the WebSocket origin allow-list
+func TestOriginChecker(t *testing.T) {
+	check := originChecker([]string{"http://localhost:3000", "https://tickets.example.com"})
+
+	tests := []struct {
+		name     string
+		origin   string
+		expected bool
+	}{
+		{name: "Allowed Origin", origin: "http://localhost:3000", expected: true},
+		{name: "Allowed Origin Different Case", origin: "https://Tickets.Example.com", expected: true},
+		{name: "Unknown Origin", origin: "https://evil.example.com", expected: false},
+		{name: "Non-browser Client", origin: "", expected: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+			assert.Equal(t, tc.expected, check(req))
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	req.Header.Set("Origin", "https://anything.example.com")
+	assert.True(t, originChecker([]string{"*"})(req))
+}
+
+// dialTestServer opens a WebSocket to server as the given user
+func dialTestServer(t *testing.T, server *httptest.Server, userID uint, origin string) (*websocket.Conn, *http.Response, error) {
+	token, err := utils.GenerateJWT(userID, "wsuser", "ws@example.com", models.RoleUser, 1)
+	require.NoError(t, err)
+
+	header := http.Header{}
+	if origin != "" {
+		header.Set("Origin", origin)
+	}
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?token=" + token
+	return websocket.DefaultDialer.Dial(url, header)
+}
+
+// readCloseCode reads from conn until it is closed and returns the close code
+func readCloseCode(t *testing.T, conn *websocket.Conn) int {
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			closeErr, ok := err.(*websocket.CloseError)
+			require.True(t, ok, "expected a close frame, got %v", err)
+			return closeErr.Code
+		}
+	}
+}
+
+// TestHandleWebSocket_Limits tests the origin check, connection cap and rate limit end to end
+func TestHandleWebSocket_Limits(t *testing.T) {
+	// Configure token signing for signing test tokens
+	utils.ConfigureJWT(config.JWTConfig{Secret: "test-secret-key-for-testing", AccessTokenTTL: 24 * time.Hour})
+
+	// Every token in this test belongs to an active session
+	originalChecker := utils.SessionChecker
+	defer func() { utils.SessionChecker = originalChecker }()
+	utils.SessionChecker = func(ctx context.Context, sessionID uint) (bool, error) { return true, nil }
+
+	hub := NewHub()
+	hub.SetLimits(Limits{MessageRate: 1, MessageBurst: 2, MaxConnectionsPerUser: 1})
+	go hub.Run()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", HandleWebSocket(hub, []string{"http://localhost:3000"}))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Run("Disallowed Origin", func(t *testing.T) {
+		_, resp, err := dialTestServer(t, server, 1, "https://evil.example.com")
+		require.Error(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Connection Cap", func(t *testing.T) {
+		first, _, err := dialTestServer(t, server, 2, "http://localhost:3000")
+		require.NoError(t, err)
+		defer first.Close()
+		_, _, err = first.ReadMessage() // connection ack
+		require.NoError(t, err)
+
+		second, _, err := dialTestServer(t, server, 2, "http://localhost:3000")
+		require.NoError(t, err)
+		defer second.Close()
+		// The frontend resumes its subscriptions as soon as the socket opens
+		second.WriteMessage(websocket.TextMessage, []byte(`{"type":"resume","event_id":1,"last_seq":0}`))
+		assert.Equal(t, websocket.CloseTryAgainLater, readCloseCode(t, second))
+		assert.Equal(t, 1, hub.Stats().Clients, "The rejected connection is never registered")
+	})
+
+	t.Run("Message Rate", func(t *testing.T) {
+		conn, _, err := dialTestServer(t, server, 3, "")
+		require.NoError(t, err)
+		defer conn.Close()
+
+		for i := 0; i < 5; i++ {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"ping"}`)); err != nil {
+				break
+			}
+		}
+		assert.Equal(t, websocket.ClosePolicyViolation, readCloseCode(t, conn))
+	})
+}
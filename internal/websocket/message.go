@@ -1,16 +1,31 @@
 package websocket
 
-import "time"
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
 
 // MessageType represents the type of WebSocket message
 type MessageType string
 
 const (
 	MessageTypeAvailabilityUpdate MessageType = "availability_update"
+	MessageTypeWaitlistOffer      MessageType = "waitlist_offer"
+	MessageTypeSeatUpdate         MessageType = "seat_update"
+	MessageTypeBookingConfirmed   MessageType = "booking_confirmed"
+	MessageTypeBookingCancelled   MessageType = "booking_cancelled"
+	MessageTypeEventCancelled     MessageType = "event_cancelled"
+	MessageTypeResyncRequired     MessageType = "resync_required"
+	MessageTypeSalesUpdate        MessageType = "sales_update"
+	MessageTypeServerShutdown     MessageType = "server_shutdown"
 	MessageTypeConnectionAck      MessageType = "connection_ack"
 	MessageTypeError              MessageType = "error"
 	MessageTypeSubscribe          MessageType = "subscribe"
 	MessageTypeUnsubscribe        MessageType = "unsubscribe"
+	MessageTypeResume             MessageType = "resume"
+	MessageTypeSubscribeSales     MessageType = "subscribe_sales"
+	MessageTypeUnsubscribeSales   MessageType = "unsubscribe_sales"
 	MessageTypePing               MessageType = "ping"
 	MessageTypePong               MessageType = "pong"
 )
@@ -18,23 +33,103 @@ const (
 // Message represents a WebSocket message
 type Message struct {
 	Type      MessageType `json:"type"`
+	Seq       uint64      `json:"seq,omitempty"` // increases with every message the hub delivers
 	EventID   *uint       `json:"event_id,omitempty"`
 	Timestamp time.Time   `json:"timestamp"`
 	Data      interface{} `json:"data,omitempty"`
+
+	// UserID restricts delivery to a single user's connections; it is never sent to clients
+	UserID *uint `json:"-"`
+
+	// Event describes the event for matching filter subscriptions; it is never sent to clients
+	Event *EventInfo `json:"-"`
+
+	// spanContext is the span that published the message, which its broadcast continues
+	spanContext trace.SpanContext
 }
 
 // AvailabilityUpdate represents ticket availability data
 type AvailabilityUpdate struct {
-	EventID          uint   `json:"event_id"`
+	EventID          uint               `json:"event_id"`
+	AvailableTickets int                `json:"available_tickets"`
+	Capacity         int                `json:"capacity"`
+	Tiers            []TierAvailability `json:"tiers,omitempty"`
+	LastUpdated      string             `json:"last_updated"`
+}
+
+// TierAvailability represents ticket availability for a single ticket tier
+type TierAvailability struct {
+	TierID           uint   `json:"tier_id"`
+	Name             string `json:"name"`
 	AvailableTickets int    `json:"available_tickets"`
 	Capacity         int    `json:"capacity"`
-	LastUpdated      string `json:"last_updated"`
+}
+
+// SeatUpdate represents a change in state for reserved seats of an event
+type SeatUpdate struct {
+	EventID     uint   `json:"event_id"`
+	SeatIDs     []uint `json:"seat_ids"`
+	Status      string `json:"status"` // available, booked
+	LastUpdated string `json:"last_updated"`
+}
+
+// WaitlistOffer tells a waitlisted user that tickets are being held for them
+type WaitlistOffer struct {
+	EventID   uint   `json:"event_id"`
+	HoldID    uint   `json:"hold_id"`
+	Quantity  int    `json:"quantity"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// BookingNotification tells a user that one of their bookings was confirmed or cancelled
+type BookingNotification struct {
+	BookingID  uint    `json:"booking_id"`
+	EventID    uint    `json:"event_id"`
+	EventName  string  `json:"event_name"`
+	Quantity   int     `json:"quantity"`
+	TotalPrice float64 `json:"total_price"`
+	Status     string  `json:"status"`
+}
+
+// EventCancelled tells a ticket holder that an event they booked was cancelled or removed
+type EventCancelled struct {
+	EventID   uint   `json:"event_id"`
+	EventName string `json:"event_name"`
+	Date      string `json:"date"`
+}
+
+// SalesUpdate is one event's live sales figures on an organizer's dashboard
+type SalesUpdate struct {
+	EventID                uint    `json:"event_id"`
+	EventName              string  `json:"event_name"`
+	TicketsSold            int64   `json:"tickets_sold"`
+	Revenue                float64 `json:"revenue"`
+	RemainingCapacity      int     `json:"remaining_capacity"`
+	Capacity               int     `json:"capacity"`
+	BookingsPerMinute      int64   `json:"bookings_per_minute"`
+	CancellationsPerMinute int64   `json:"cancellations_per_minute"`
+	LastUpdated            string  `json:"last_updated"`
+}
+
+// ResyncRequired tells a resuming client that missed messages can't be replayed,
+// so it has to fetch the event's current state again
+type ResyncRequired struct {
+	EventID uint `json:"event_id"`
+}
+
+// ServerShutdown tells clients the server is going away, so they should
+// reconnect and resume their subscriptions
+type ServerShutdown struct {
+	Message string `json:"message"`
 }
 
 // ConnectionAck represents a connection acknowledgment
 type ConnectionAck struct {
 	ClientID string `json:"client_id"`
 	Message  string `json:"message"`
+
+	// Epoch identifies the hub's sequence numbering; sequence numbers from another epoch can't be resumed
+	Epoch string `json:"epoch"`
 }
 
 // ErrorMessage represents an error message
@@ -44,6 +139,21 @@ type ErrorMessage struct {
 
 // ClientMessage represents a message from client to server
 type ClientMessage struct {
-	Type    MessageType `json:"type"`
-	EventID *uint       `json:"event_id,omitempty"`
+	Type     MessageType         `json:"type"`
+	EventID  *uint               `json:"event_id,omitempty"`
+	EventIDs []uint              `json:"event_ids,omitempty"` // (un)subscribe several events at once
+	Filter   *SubscriptionFilter `json:"filter,omitempty"`    // (un)subscribe every event matching a filter
+
+	// Epoch and LastSeq identify the last message seen before reconnecting (resume only)
+	Epoch   string  `json:"epoch,omitempty"`
+	LastSeq *uint64 `json:"last_seq,omitempty"`
+}
+
+// eventIDs collects the single and bulk event IDs of a (un)subscribe message
+func (m *ClientMessage) eventIDs() []uint {
+	eventIDs := m.EventIDs
+	if m.EventID != nil {
+		eventIDs = append([]uint{*m.EventID}, eventIDs...)
+	}
+	return eventIDs
 }
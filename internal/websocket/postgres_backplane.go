@@ -0,0 +1,112 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// BackplaneChannel is the NOTIFY channel shared by all replicas
+	BackplaneChannel = "ticket_hub"
+
+	// Postgres rejects NOTIFY payloads of 8000 bytes or more
+	maxNotifyPayload = 7999
+
+	// Delay before re-establishing a dropped LISTEN connection
+	listenRetryDelay = 2 * time.Second
+)
+
+// PostgresBackplane fans hub messages out across replicas with LISTEN/NOTIFY
+type PostgresBackplane struct {
+	pool    *pgxpool.Pool
+	channel string
+}
+
+// NewPostgresBackplane connects to the database at dsn and uses channel for notifications
+func NewPostgresBackplane(ctx context.Context, dsn, channel string) (*PostgresBackplane, error) {
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backplane pool: %w", err)
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect backplane: %w", err)
+	}
+
+	return &PostgresBackplane{pool: pool, channel: channel}, nil
+}
+
+// Publish sends the message to every replica listening on the channel
+func (b *PostgresBackplane) Publish(ctx context.Context, msg *Message) error {
+	payload, err := encodeEnvelope(msg)
+	if err != nil {
+		return err
+	}
+
+	if len(payload) > maxNotifyPayload {
+		return fmt.Errorf("message of %d bytes exceeds the NOTIFY payload limit", len(payload))
+	}
+
+	_, err = b.pool.Exec(ctx, "SELECT pg_notify($1, $2)", b.channel, string(payload))
+	return err
+}
+
+// Subscribe listens on the channel until ctx is done, reconnecting when the
+// connection drops. Messages published while reconnecting are lost.
+func (b *PostgresBackplane) Subscribe(ctx context.Context, deliver func(*Message)) error {
+	for {
+		err := b.listen(ctx, deliver)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		slog.Error("Backplane listener stopped, reconnecting", "error", err, "retry_in", listenRetryDelay.String())
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+// listen holds one pooled connection in LISTEN mode and delivers notifications until it fails
+func (b *PostgresBackplane) listen(ctx context.Context, deliver func(*Message)) error {
+	pooled, err := b.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	// A connection that was listening must not go back to the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{b.channel}.Sanitize()); err != nil {
+		return err
+	}
+	slog.Info("Backplane listening", "channel", b.channel)
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		msg, err := decodeEnvelope([]byte(notification.Payload))
+		if err != nil {
+			slog.Warn("Backplane dropped malformed notification", "error", err)
+			continue
+		}
+		deliver(msg)
+	}
+}
+
+func (b *PostgresBackplane) Close() error {
+	b.pool.Close()
+	return nil
+}
@@ -0,0 +1,77 @@
+package websocket
+
+import "time"
+
+const (
+	// replayBufferSize is how many recent broadcasts are kept per event for resuming clients
+	replayBufferSize = 100
+
+	// replayRetention is how long an event without subscribers keeps its broadcasts
+	replayRetention = 10 * time.Minute
+
+	// historyPruneInterval is how often the hub drops the broadcasts of abandoned events
+	historyPruneInterval = time.Minute
+)
+
+// bufferedMessage is a serialized broadcast along with its sequence number
+type bufferedMessage struct {
+	seq  uint64
+	data []byte
+}
+
+// replayBuffer is a fixed-size ring holding an event's most recent broadcasts
+type replayBuffer struct {
+	messages []bufferedMessage
+
+	// next is the index the next message is written to
+	next int
+
+	// count is the number of messages currently held
+	count int
+
+	// evictedSeq is the sequence number of the newest message pushed out of the ring
+	evictedSeq uint64
+
+	// lastSeq is the sequence number of the newest message
+	lastSeq uint64
+
+	// updated is when the newest message was added
+	updated time.Time
+}
+
+// newReplayBuffer creates an empty ring holding up to size messages
+func newReplayBuffer(size int) *replayBuffer {
+	return &replayBuffer{messages: make([]bufferedMessage, size)}
+}
+
+// add stores a message, evicting the oldest one when the ring is full
+func (b *replayBuffer) add(seq uint64, data []byte) {
+	if b.count == len(b.messages) {
+		b.evictedSeq = b.messages[b.next].seq
+	} else {
+		b.count++
+	}
+
+	b.messages[b.next] = bufferedMessage{seq: seq, data: data}
+	b.next = (b.next + 1) % len(b.messages)
+	b.lastSeq = seq
+	b.updated = time.Now()
+}
+
+// since returns the messages newer than seq, oldest first. It reports false when
+// some of them were already evicted and the caller can't be brought up to date.
+func (b *replayBuffer) since(seq uint64) ([][]byte, bool) {
+	if seq < b.evictedSeq {
+		return nil, false
+	}
+
+	var missed [][]byte
+	start := (b.next - b.count + len(b.messages)) % len(b.messages)
+	for i := 0; i < b.count; i++ {
+		msg := b.messages[(start+i)%len(b.messages)]
+		if msg.seq > seq {
+			missed = append(missed, msg.data)
+		}
+	}
+	return missed, true
+}
@@ -0,0 +1,33 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReplayBuffer_Since tests replaying from the ring before and after it wraps
+func TestReplayBuffer_Since(t *testing.T) {
+	buffer := newReplayBuffer(3)
+	buffer.add(2, []byte("two"))
+	buffer.add(5, []byte("five"))
+
+	missed, ok := buffer.since(2)
+	assert.True(t, ok)
+	assert.Equal(t, [][]byte{[]byte("five")}, missed)
+
+	missed, ok = buffer.since(5)
+	assert.True(t, ok)
+	assert.Empty(t, missed)
+
+	// Wrapping evicts the oldest message
+	buffer.add(6, []byte("six"))
+	buffer.add(9, []byte("nine"))
+
+	missed, ok = buffer.since(2)
+	assert.True(t, ok, "Nothing after seq 2 was evicted")
+	assert.Equal(t, [][]byte{[]byte("five"), []byte("six"), []byte("nine")}, missed)
+
+	_, ok = buffer.since(1)
+	assert.False(t, ok, "Seq 2 was evicted, so resuming after 1 must resync")
+}
@@ -0,0 +1,137 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexs/golang_test/internal/config"
+	"github.com/alexs/golang_test/internal/utils"
+)
+
+// TestHub_Shutdown tests that shutdown notifies and closes every client and stops the hub
+func TestHub_Shutdown(t *testing.T) {
+	// Configure token signing for signing test tokens
+	utils.ConfigureJWT(config.JWTConfig{Secret: "test-secret-key-for-testing", AccessTokenTTL: 24 * time.Hour})
+
+	// Every token in this test belongs to an active session
+	originalChecker := utils.SessionChecker
+	defer func() { utils.SessionChecker = originalChecker }()
+	utils.SessionChecker = func(ctx context.Context, sessionID uint) (bool, error) { return true, nil }
+
+	hub := NewHub()
+	stopped := make(chan struct{})
+	go func() {
+		hub.Run()
+		close(stopped)
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", HandleWebSocket(hub, nil))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	conn, _, err := dialTestServer(t, server, 1, "")
+	require.NoError(t, err)
+	defer conn.Close()
+	_, _, err = conn.ReadMessage() // connection ack
+	require.NoError(t, err)
+
+	stream := hub.NewStreamClient("stream", 2)
+	msg := receive(t, stream)
+	require.Equal(t, MessageTypeConnectionAck, msg.Type)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, hub.Shutdown(ctx))
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Run didn't return after Shutdown")
+	}
+
+	// The WebSocket gets the notice, then a going-away close frame
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	var notice Message
+	require.NoError(t, json.Unmarshal(data, &notice))
+	assert.Equal(t, MessageTypeServerShutdown, notice.Type)
+	assert.Equal(t, websocket.CloseGoingAway, readCloseCode(t, conn))
+
+	// The stream gets the notice and its channel is closed
+	msg = receive(t, stream)
+	assert.Equal(t, MessageTypeServerShutdown, msg.Type)
+	_, open := <-stream.Messages()
+	assert.False(t, open)
+	stream.hub.Unregister(stream) // must not block once the hub is stopped
+
+	// Late clients are turned away and broadcasts are dropped without blocking
+	late := hub.NewStreamClient("late", 3)
+	_, open = <-late.Messages()
+	assert.False(t, open)
+
+	lateConn, _, err := dialTestServer(t, server, 4, "")
+	require.NoError(t, err)
+	defer lateConn.Close()
+	assert.Equal(t, websocket.CloseGoingAway, readCloseCode(t, lateConn))
+
+	hub.BroadcastAvailabilityUpdate(context.Background(), EventInfo{ID: 1}, 10, 100, nil)
+	assert.Equal(t, 0, hub.Stats().Clients)
+}
+
+// TestHub_ShutdownWhileClientReplies tests that replies to messages read after shutdown are dropped instead of panicking
+func TestHub_ShutdownWhileClientReplies(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	client := hub.NewStreamClient("reader", 1)
+	receive(t, client) // connection ack
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, hub.Shutdown(ctx))
+
+	// The read pump keeps handling messages until the connection goes away
+	assert.NotPanics(t, func() {
+		client.handleMessage(&ClientMessage{Type: MessageTypePing})
+		client.handleMessage(&ClientMessage{Type: "bogus"})
+	})
+
+	msg := receive(t, client)
+	assert.Equal(t, MessageTypeServerShutdown, msg.Type)
+	_, open := <-client.Messages()
+	assert.False(t, open)
+}
+
+// TestHub_ResumeAfterShutdown tests that resuming a client closed by shutdown asks for a resync instead of panicking
+func TestHub_ResumeAfterShutdown(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	// Leave a broadcast in the event's history to be replayed
+	hub.BroadcastAvailabilityUpdate(context.Background(), EventInfo{ID: 3}, 9, 10, nil)
+	require.Eventually(t, func() bool {
+		hub.mutex.RLock()
+		defer hub.mutex.RUnlock()
+		return hub.seq == 1
+	}, time.Second, 5*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, hub.Shutdown(ctx))
+
+	// Streams opened during shutdown are closed right away
+	stream := hub.NewStreamClient("late-sse", 1)
+	assert.False(t, hub.Resume(stream, 3, hub.Epoch(), 0))
+	_, open := <-stream.Messages()
+	assert.False(t, open)
+}
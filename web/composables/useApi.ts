@@ -10,22 +10,58 @@ export const useApi = () => {
     return null
   }
 
-  const setToken = (token: string) => {
+  const setSession = (auth: AuthResponse) => {
     if (import.meta.client) {
-      localStorage.setItem('token', token)
+      localStorage.setItem('token', auth.token)
+      localStorage.setItem('refresh_token', auth.refresh_token)
+      localStorage.setItem('user', JSON.stringify(auth.user))
     }
   }
 
   const removeToken = () => {
     if (import.meta.client) {
+      // Revoke the session server-side; local logout proceeds either way
+      const refreshToken = localStorage.getItem('refresh_token')
+      if (refreshToken) {
+        fetch(`${API_URL}/auth/logout`, {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/json' },
+          body: JSON.stringify({ refresh_token: refreshToken }),
+        }).catch(() => {})
+      }
       localStorage.removeItem('token')
+      localStorage.removeItem('refresh_token')
       localStorage.removeItem('user')
     }
   }
 
+  // Exchanges the stored refresh token for a new token pair (refresh tokens are single-use)
+  const refreshSession = async (): Promise<boolean> => {
+    if (!import.meta.client) return false
+    const refreshToken = localStorage.getItem('refresh_token')
+    if (!refreshToken) return false
+
+    const response = await fetch(`${API_URL}/auth/refresh`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ refresh_token: refreshToken }),
+    })
+    if (!response.ok) {
+      localStorage.removeItem('token')
+      localStorage.removeItem('refresh_token')
+      localStorage.removeItem('user')
+      return false
+    }
+
+    const body: ApiResponse<AuthResponse> = await response.json()
+    setSession(body.data)
+    return true
+  }
+
   const fetchWithAuth = async <T>(
     endpoint: string,
-    options: RequestInit = {}
+    options: RequestInit = {},
+    retried = false
   ): Promise<ApiResponse<T>> => {
     const token = getToken()
     const headers: HeadersInit = {
@@ -42,6 +78,11 @@ export const useApi = () => {
       headers,
     })
 
+    // Access tokens are short-lived: refresh once and retry
+    if (response.status === 401 && token && !retried && await refreshSession()) {
+      return fetchWithAuth<T>(endpoint, options, true)
+    }
+
     if (!response.ok) {
       const error = await response.json()
       throw new Error(error.error || 'Request failed')
@@ -57,10 +98,7 @@ export const useApi = () => {
       body: JSON.stringify({ username, email, password }),
     })
     if (response.data.token) {
-      setToken(response.data.token)
-      if (import.meta.client) {
-        localStorage.setItem('user', JSON.stringify(response.data.user))
-      }
+      setSession(response.data)
     }
     return response
   }
@@ -71,10 +109,7 @@ export const useApi = () => {
       body: JSON.stringify({ email, password }),
     })
     if (response.data.token) {
-      setToken(response.data.token)
-      if (import.meta.client) {
-        localStorage.setItem('user', JSON.stringify(response.data.user))
-      }
+      setSession(response.data)
     }
     return response
   }
@@ -143,6 +178,7 @@ export const useApi = () => {
     getMyBookings,
     cancelBooking,
     getToken,
+    refreshSession,
     removeToken,
   }
 }
\ No newline at end of file
@@ -1,5 +1,6 @@
 interface WebSocketMessage {
   type: string
+  seq?: number
   event_id?: number
   timestamp?: string
   data?: any
@@ -12,8 +13,22 @@ interface AvailabilityUpdate {
   last_updated: string
 }
 
+interface SubscriptionFilter {
+  city?: string
+  event_type?: string
+  organizer_id?: number
+}
+
+interface LastSeen {
+  epoch: string
+  seq: number
+}
+
 type EventHandler = (data: any) => void
 
+// Broadcasts the server keeps for replay after a reconnect
+const replayableTypes = new Set(['availability_update', 'seat_update'])
+
 export const useWebSocket = () => {
   const socket = ref<WebSocket | null>(null)
   const isConnected = ref(false)
@@ -25,24 +40,58 @@ export const useWebSocket = () => {
   // Event handlers map
   const eventHandlers = ref<Map<string, Set<EventHandler>>>(new Map())
 
-  // Subscribe to event updates
-  const subscribe = (eventId: number) => {
+  // Server epoch and last sequence seen per event, used to resume after reconnecting
+  const epoch = ref('')
+  const lastSeen = new Map<number, LastSeen>()
+
+  const send = (message: object) => {
     if (socket.value && isConnected.value) {
-      socket.value.send(JSON.stringify({
-        type: 'subscribe',
-        event_id: eventId
-      }))
+      socket.value.send(JSON.stringify(message))
     }
   }
 
+  // Subscribe to several events in one message, resuming those we were subscribed to before
+  const subscribeMany = (eventIds: number[]) => {
+    const fresh: number[] = []
+    for (const eventId of eventIds) {
+      const seen = lastSeen.get(eventId)
+      if (seen) {
+        send({ type: 'resume', event_id: eventId, epoch: seen.epoch, last_seq: seen.seq })
+      } else {
+        fresh.push(eventId)
+      }
+    }
+
+    if (fresh.length > 0) {
+      send({ type: 'subscribe', event_ids: fresh })
+    }
+  }
+
+  // Subscribe to event updates
+  const subscribe = (eventId: number) => subscribeMany([eventId])
+
+  // Follow every event matching a filter, e.g. { city: 'Copenhagen' } or { event_type: 'concert' }
+  const subscribeFilter = (filter: SubscriptionFilter) => {
+    send({ type: 'subscribe', filter })
+  }
+
+  const unsubscribeFilter = (filter: SubscriptionFilter) => {
+    send({ type: 'unsubscribe', filter })
+  }
+
+  // Stream live sales of the events the signed-in organizer owns as 'sales_update' events
+  const subscribeSales = () => {
+    send({ type: 'subscribe_sales' })
+  }
+
+  const unsubscribeSales = () => {
+    send({ type: 'unsubscribe_sales' })
+  }
+
   // Unsubscribe from event updates
   const unsubscribe = (eventId: number) => {
-    if (socket.value && isConnected.value) {
-      socket.value.send(JSON.stringify({
-        type: 'unsubscribe',
-        event_id: eventId
-      }))
-    }
+    lastSeen.delete(eventId)
+    send({ type: 'unsubscribe', event_id: eventId })
   }
 
   // Add event listener
@@ -63,6 +112,32 @@ export const useWebSocket = () => {
     eventHandlers.value.get(eventType)?.forEach(handler => handler(data))
   }
 
+  const handleMessage = (message: WebSocketMessage) => {
+    if (message.seq && message.event_id && replayableTypes.has(message.type)) {
+      lastSeen.set(message.event_id, { epoch: epoch.value, seq: message.seq })
+    }
+
+    if (message.type === 'availability_update') {
+      emit('availability_update', message.data as AvailabilityUpdate)
+    } else if (message.type === 'sales_update') {
+      emit('sales_update', message.data)
+    } else if (message.type === 'resync_required') {
+      // Missed updates couldn't be replayed, so the page has to refetch this event
+      lastSeen.delete(message.data.event_id)
+      emit('resync', message.data)
+    } else if (message.type === 'server_shutdown') {
+      // The going-away close that follows triggers a reconnect, and subscriptions resume from lastSeen
+      console.log('Server shutting down:', message.data?.message)
+      emit('server_shutdown', message.data)
+    } else if (message.type === 'connection_ack') {
+      epoch.value = message.data.epoch
+      console.log('WebSocket connection acknowledged:', message.data)
+    } else if (message.type === 'error') {
+      emit('error', message.data)
+      console.error('WebSocket error:', message.data)
+    }
+  }
+
   const connect = (token: string) => {
     if (socket.value) return
 
@@ -79,19 +154,13 @@ export const useWebSocket = () => {
     }
 
     socket.value.onmessage = (event) => {
-      try {
-        const message: WebSocketMessage = JSON.parse(event.data)
-
-        if (message.type === 'availability_update') {
-          emit('availability_update', message.data as AvailabilityUpdate)
-        } else if (message.type === 'connection_ack') {
-          console.log('WebSocket connection acknowledged:', message.data)
-        } else if (message.type === 'error') {
-          emit('error', message.data)
-          console.error('WebSocket error:', message.data)
+      // The server batches queued messages into one frame, separated by newlines
+      for (const line of String(event.data).split('\n')) {
+        try {
+          handleMessage(JSON.parse(line))
+        } catch (error) {
+          console.error('Failed to parse WebSocket message:', error)
         }
-      } catch (error) {
-        console.error('Failed to parse WebSocket message:', error)
       }
     }
 
@@ -100,11 +169,17 @@ export const useWebSocket = () => {
       console.error('WebSocket error:', error)
     }
 
-    socket.value.onclose = () => {
+    socket.value.onclose = (event) => {
       isConnected.value = false
       socket.value = null
       emit('disconnected', null)
-      console.log('WebSocket disconnected')
+      console.log('WebSocket disconnected', event.code, event.reason)
+
+      // The server closed us for breaking its rate limit; reconnecting would just repeat it
+      if (event.code === 1008) {
+        emit('error', { message: event.reason })
+        return
+      }
 
       // Attempt reconnection
       if (reconnectAttempts.value < maxReconnectAttempts) {
@@ -139,7 +214,12 @@ export const useWebSocket = () => {
     connect,
     disconnect,
     subscribe,
+    subscribeMany,
     unsubscribe,
+    subscribeFilter,
+    unsubscribeFilter,
+    subscribeSales,
+    unsubscribeSales,
     on,
     off
   }
@@ -2,11 +2,14 @@ export interface User {
   id: number
   username: string
   email: string
+  role?: 'user' | 'organizer' | 'admin'
   created_at: string
 }
 
 export interface AuthResponse {
   token: string
+  refresh_token: string
+  expires_in: number
   user: User
 }
 